@@ -15,6 +15,31 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/admin/explore/cache-stats": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "name": "reset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/admin.exploreCacheStatsResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/admin/projects": {
             "get": {
                 "produces": [
@@ -118,6 +143,12 @@ const docTemplate = `{
                         "name": "current",
                         "in": "query"
                     },
+                    {
+                        "maxLength": 512,
+                        "type": "string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
                     {
                         "type": "boolean",
                         "name": "is_active",
@@ -164,6 +195,11 @@ const docTemplate = `{
                         "type": "string",
                         "name": "username",
                         "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -220,6 +256,129 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/notifications": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "current",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 512,
+                        "type": "string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "unread_only",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/notification.ListNotificationsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications/read": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "parameters": [
+                    {
+                        "description": "通知ID列表",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/notification.MarkReadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/notification.NotificationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/notification.MarkReadResponseData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notifications/unread-count": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/notification.NotificationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/notification.UnreadCountResponseData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/oauth/callback": {
             "post": {
                 "produces": [
@@ -318,6 +477,12 @@ const docTemplate = `{
                         "name": "current",
                         "in": "query"
                     },
+                    {
+                        "maxLength": 512,
+                        "type": "string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
                     {
                         "maximum": 100,
                         "minimum": 1,
@@ -333,6 +498,11 @@ const docTemplate = `{
                         "collectionFormat": "csv",
                         "name": "tags",
                         "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -375,7 +545,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/projects/mine": {
+        "/api/v1/projects/favorites": {
             "get": {
                 "produces": [
                     "application/json"
@@ -390,6 +560,12 @@ const docTemplate = `{
                         "name": "current",
                         "in": "query"
                     },
+                    {
+                        "maxLength": 512,
+                        "type": "string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
                     {
                         "maximum": 100,
                         "minimum": 1,
@@ -405,19 +581,24 @@ const docTemplate = `{
                         "collectionFormat": "csv",
                         "name": "tags",
                         "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/project.ListProjectsResponse"
+                            "$ref": "#/definitions/project.ListFavoriteProjectsResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/projects/received": {
+        "/api/v1/projects/following": {
             "get": {
                 "produces": [
                     "application/json"
@@ -433,9 +614,9 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "maxLength": 255,
+                        "maxLength": 512,
                         "type": "string",
-                        "name": "search",
+                        "name": "cursor",
                         "in": "query"
                     },
                     {
@@ -444,19 +625,33 @@ const docTemplate = `{
                         "type": "integer",
                         "name": "size",
                         "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/project.ListReceiveHistoryResponse"
+                            "$ref": "#/definitions/project.ListProjectsResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/projects/received/chart": {
+        "/api/v1/projects/mine": {
             "get": {
                 "produces": [
                     "application/json"
@@ -466,10 +661,36 @@ const docTemplate = `{
                 ],
                 "parameters": [
                     {
-                        "maximum": 180,
                         "minimum": 1,
                         "type": "integer",
-                        "name": "day",
+                        "name": "current",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 512,
+                        "type": "string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
                         "in": "query"
                     }
                 ],
@@ -477,58 +698,218 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/project.ListReceiveHistoryChartResponse"
+                            "$ref": "#/definitions/project.ListProjectsResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/projects/{id}": {
+        "/api/v1/projects/received": {
             "get": {
-                "description": "获取指定项目所有信息以及领取情况 (Get all information and claim status for a specific project)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "project"
                 ],
-                "summary": "获取指定项目信息 (Get specific project information)",
                 "parameters": [
                     {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "current",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 512,
                         "type": "string",
-                        "description": "项目ID (Project ID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 255,
+                        "type": "string",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/project.ProjectResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "$ref": "#/definitions/project.GetProjectResponseData"
-                                        }
-                                    }
-                                }
-                            ]
+                            "$ref": "#/definitions/project.ListReceiveHistoryResponse"
                         }
                     }
                 }
-            },
-            "put": {
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
+            }
+        },
+        "/api/v1/projects/received/chart": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "maximum": 180,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "day",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ListReceiveHistoryChartResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/projects/search": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "current",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 64,
+                        "type": "string",
+                        "name": "keyword",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 16,
+                        "type": "string",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 16,
+                        "type": "string",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "free",
+                            "paid"
+                        ],
+                        "type": "string",
+                        "name": "pricing",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "relevance",
+                            "newest",
+                            "ending",
+                            "stock",
+                            "price_asc",
+                            "price_desc",
+                            "popular"
+                        ],
+                        "type": "string",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "name": "tags",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ListProjectsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/projects/{id}": {
+            "get": {
+                "description": "获取指定项目所有信息以及领取情况 (Get all information and claim status for a specific project)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "获取指定项目信息 (Get specific project information)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID (Project ID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/project.ProjectResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/project.GetProjectResponseData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
                 ],
                 "tags": [
                     "project"
@@ -589,11 +970,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/projects/{id}/receivers": {
-            "get": {
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/projects/{id}/favorite": {
+            "post": {
                 "produces": [
                     "application/json"
                 ],
@@ -603,29 +981,10 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "项目ID (Project ID)",
+                        "description": "项目ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "minimum": 1,
-                        "type": "integer",
-                        "name": "current",
-                        "in": "query"
-                    },
-                    {
-                        "maxLength": 1024,
-                        "type": "string",
-                        "name": "search",
-                        "in": "query"
-                    },
-                    {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "name": "size",
-                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -636,13 +995,8 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/projects/{id}/report": {
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
@@ -656,15 +1010,6 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "举报信息",
-                        "name": "project",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/project.ReportProjectRequestBody"
-                        }
                     }
                 ],
                 "responses": {
@@ -677,28 +1022,50 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/ready": {
+        "/api/v1/projects/{id}/items/export": {
             "get": {
                 "produces": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "health"
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "x-enum-varnames": [
+                            "ExportFormatCSV",
+                            "ExportFormatXLSX"
+                        ],
+                        "name": "format",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/health.ReadyResponse"
+                            "type": "file"
                         }
                     }
                 }
             }
         },
-        "/api/v1/tags": {
-            "get": {
+        "/api/v1/projects/{id}/items/import": {
+            "post": {
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
@@ -706,393 +1073,1310 @@ const docTemplate = `{
                 "tags": [
                     "project"
                 ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "物品文件 (.txt 每行一个, .csv 取第一列)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/project.ListTagsResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/project.ProjectResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/project.ImportItemsSummary"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "admin.ListProjectsResponse": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "$ref": "#/definitions/admin.ListProjectsResponseData"
-                },
-                "error_msg": {
-                    "type": "string"
-                }
-            }
         },
-        "admin.ListProjectsResponseData": {
-            "type": "object",
-            "properties": {
-                "results": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/admin.ListProjectsResponseDataResult"
+        "/api/v1/projects/{id}/items/revocations": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/project.ProjectResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/project.ProjectItemRevocation"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/projects/{id}/lottery": {
+            "get": {
+                "description": "开奖后公开种子、报名名单与中奖者，任何人可通过种子复现抽奖结果",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "获取内置抽奖的公开数据 (Get verifiable draw data)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.GetLotteryResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/projects/{id}/lottery/entries": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ProjectResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/projects/{id}/receivers": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID (Project ID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "current",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 512,
+                        "type": "string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 1024,
+                        "type": "string",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "with_count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ProjectResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/projects/{id}/report": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "举报信息",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/project.ReportProjectRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ProjectResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/projects/{id}/waitlist": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.GetWaitlistResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ProjectResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "项目ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ProjectResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/ready": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.ReadyResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tags": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ListTagsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/follow": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "创建者用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ProjectResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "创建者用户ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/project.ProjectResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "admin.ListProjectsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/admin.ListProjectsResponseData"
+                },
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "admin.ListProjectsResponseData": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/admin.ListProjectsResponseDataResult"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "admin.ListProjectsResponseDataResult": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/project.ProjectStatus"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "admin.ReviewProjectRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "enum": [
+                        0,
+                        1,
+                        2
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/project.ProjectStatus"
+                        }
+                    ]
+                }
+            }
+        },
+        "admin.ReviewProjectResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "admin.exploreCacheStatsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/project.ExploreCacheStats"
+                },
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "admin.listUsersResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/admin.listUsersResponseData"
+                },
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "admin.listUsersResponseData": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/oauth.User"
+                    }
+                }
+            }
+        },
+        "dashboard.DashboardDataResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.ReadyResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "notification.ListNotificationsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/notification.ListNotificationsResponseData"
+                },
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "notification.ListNotificationsResponseData": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/notification.Notification"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unread_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "notification.MarkReadRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "description": "IDs 为空时标记全部通知为已读",
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "type": "integer"
                     }
+                }
+            }
+        },
+        "notification.MarkReadResponseData": {
+            "type": "object",
+            "properties": {
+                "marked": {
+                    "type": "integer"
+                },
+                "unread_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "notification.Notification": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
                 },
-                "total": {
+                "out_trade_no": {
+                    "type": "string"
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/notification.NotificationType"
+                }
+            }
+        },
+        "notification.NotificationResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "notification.NotificationType": {
+            "type": "string",
+            "enum": [
+                "favorite_started",
+                "lottery_won",
+                "project_sold_out",
+                "item_sold",
+                "order_refunded",
+                "project_reviewed",
+                "dispute"
+            ],
+            "x-enum-comments": {
+                "NotificationTypeDispute": "退款争议状态变化",
+                "NotificationTypeFavoriteStarted": "收藏的项目已开始",
+                "NotificationTypeItemSold": "付费项目售出",
+                "NotificationTypeLotteryWon": "报名抽奖中奖",
+                "NotificationTypeOrderRefunded": "订单已退款",
+                "NotificationTypeProjectReviewed": "项目被审核或因举报隐藏",
+                "NotificationTypeProjectSoldOut": "创建的项目已领完"
+            },
+            "x-enum-descriptions": [
+                "收藏的项目已开始",
+                "报名抽奖中奖",
+                "创建的项目已领完",
+                "付费项目售出",
+                "订单已退款",
+                "项目被审核或因举报隐藏",
+                "退款争议状态变化"
+            ],
+            "x-enum-varnames": [
+                "NotificationTypeFavoriteStarted",
+                "NotificationTypeLotteryWon",
+                "NotificationTypeProjectSoldOut",
+                "NotificationTypeItemSold",
+                "NotificationTypeOrderRefunded",
+                "NotificationTypeProjectReviewed",
+                "NotificationTypeDispute"
+            ]
+        },
+        "notification.UnreadCountResponseData": {
+            "type": "object",
+            "properties": {
+                "unread_count": {
                     "type": "integer"
                 }
             }
         },
-        "admin.ListProjectsResponseDataResult": {
+        "oauth.BasicUserInfo": {
             "type": "object",
             "properties": {
-                "description": {
+                "avatar_url": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "trust_level": {
+                    "$ref": "#/definitions/oauth.TrustLevel"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "oauth.CallbackRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "oauth.CallbackResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "oauth.GetLoginURLResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "string"
+                },
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "oauth.LogoutResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "oauth.TrustLevel": {
+            "type": "integer",
+            "format": "int32",
+            "enum": [
+                0,
+                1,
+                2,
+                3,
+                4
+            ],
+            "x-enum-varnames": [
+                "TrustLevelNewUser",
+                "TrustLevelBasicUser",
+                "TrustLevelUser",
+                "TrustLevelActiveUser",
+                "TrustLevelLeader"
+            ]
+        },
+        "oauth.User": {
+            "type": "object",
+            "properties": {
+                "avatar_url": {
+                    "type": "string"
+                },
+                "created_at": {
                     "type": "string"
                 },
                 "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "is_admin": {
+                    "type": "boolean"
+                },
+                "last_login_at": {
                     "type": "string"
                 },
-                "nickname": {
+                "nickname": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "trust_level": {
+                    "$ref": "#/definitions/oauth.TrustLevel"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "violation_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "oauth.UserInfoResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/oauth.BasicUserInfo"
+                },
+                "error_msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "project.CreateProjectRequestBody": {
+            "type": "object",
+            "required": [
+                "end_time",
+                "name",
+                "project_items",
+                "start_time"
+            ],
+            "properties": {
+                "allow_same_ip": {
+                    "type": "boolean"
+                },
+                "claim_end_time": {
+                    "type": "string"
+                },
+                "claim_quota": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 1024
+                },
+                "distribution_type": {
+                    "enum": [
+                        0,
+                        1,
+                        2
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/project.DistributionType"
+                        }
+                    ]
+                },
+                "end_time": {
+                    "type": "string"
+                },
+                "hide_from_explore": {
+                    "type": "boolean"
+                },
+                "invite_user_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "invite_usernames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "lottery_candidates": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "minimum_trust_level": {
+                    "enum": [
+                        0,
+                        1,
+                        2,
+                        3,
+                        4
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/oauth.TrustLevel"
+                        }
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 1
+                },
+                "price": {
+                    "type": "number"
+                },
+                "project_items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "project_tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "release_schedule": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "$ref": "#/definitions/project.ReleaseBatch"
+                    }
+                },
+                "risk_level": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
+                },
+                "start_time": {
                     "type": "string"
                 },
-                "status": {
-                    "$ref": "#/definitions/project.ProjectStatus"
+                "topic_id": {
+                    "type": "integer"
                 },
-                "tags": {
+                "trust_level_quotas": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "type": "integer"
                     }
                 },
-                "username": {
-                    "type": "string"
-                }
-            }
-        },
-        "admin.ReviewProjectRequest": {
-            "type": "object",
-            "properties": {
-                "status": {
+                "winner_source": {
                     "enum": [
                         0,
                         1,
-                        2
+                        2,
+                        3
                     ],
                     "allOf": [
                         {
-                            "$ref": "#/definitions/project.ProjectStatus"
+                            "$ref": "#/definitions/project.WinnerSourceType"
                         }
                     ]
+                },
+                "winners": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "admin.ReviewProjectResponse": {
+        "project.DistributionType": {
+            "type": "integer",
+            "format": "int32",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "DistributionTypeOneForEach",
+                "DistributionTypeLottery",
+                "DistributionTypeInvite"
+            ]
+        },
+        "project.ExploreCacheStats": {
             "type": "object",
             "properties": {
-                "data": {},
-                "error_msg": {
-                    "type": "string"
+                "candidates_hit": {
+                    "type": "integer"
+                },
+                "candidates_hit_rate": {
+                    "type": "number"
+                },
+                "candidates_miss": {
+                    "type": "integer"
+                },
+                "received_hit": {
+                    "type": "integer"
+                },
+                "received_hit_rate": {
+                    "type": "number"
+                },
+                "received_miss": {
+                    "type": "integer"
                 }
             }
         },
-        "admin.listUsersResponse": {
+        "project.ExportFormat": {
+            "type": "string",
+            "enum": [
+                "csv",
+                "xlsx"
+            ],
+            "x-enum-varnames": [
+                "ExportFormatCSV",
+                "ExportFormatXLSX"
+            ]
+        },
+        "project.GetLotteryResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "type": "object",
-                    "properties": {
-                        "total": {
-                            "type": "integer"
-                        },
-                        "users": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/oauth.User"
-                            }
-                        }
-                    }
+                    "$ref": "#/definitions/project.GetLotteryResponseData"
                 },
                 "error_msg": {
                     "type": "string"
                 }
             }
         },
-        "dashboard.DashboardDataResponse": {
+        "project.GetLotteryResponseData": {
             "type": "object",
             "properties": {
-                "data": {},
-                "error_msg": {
+                "draw_seed": {
                     "type": "string"
-                }
-            }
-        },
-        "health.HealthResponse": {
-            "type": "object",
-            "properties": {
-                "data": {},
-                "error_msg": {
+                },
+                "draw_seed_hash": {
                     "type": "string"
-                }
-            }
-        },
-        "health.ReadyResponse": {
-            "type": "object",
-            "properties": {
-                "data": {},
-                "error_msg": {
+                },
+                "drawn_at": {
                     "type": "string"
+                },
+                "entrants": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "is_registered": {
+                    "type": "boolean"
+                },
+                "item_count": {
+                    "type": "integer"
+                },
+                "winner_source": {
+                    "$ref": "#/definitions/project.WinnerSourceType"
+                },
+                "winners": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "oauth.BasicUserInfo": {
+        "project.GetProjectResponseData": {
             "type": "object",
             "properties": {
-                "avatar_url": {
+                "allow_same_ip": {
+                    "type": "boolean"
+                },
+                "available_items_count": {
+                    "type": "integer"
+                },
+                "claim_end_time": {
                     "type": "string"
                 },
-                "id": {
+                "claim_quota": {
                     "type": "integer"
                 },
-                "nickname": {
+                "created_at": {
                     "type": "string"
                 },
-                "score": {
+                "creator_id": {
                     "type": "integer"
                 },
-                "trust_level": {
-                    "$ref": "#/definitions/oauth.TrustLevel"
+                "creator_nickname": {
+                    "type": "string"
                 },
-                "username": {
+                "creator_username": {
                     "type": "string"
-                }
-            }
-        },
-        "oauth.CallbackRequest": {
-            "type": "object",
-            "properties": {
-                "code": {
+                },
+                "description": {
                     "type": "string"
                 },
-                "state": {
+                "distribution_type": {
+                    "$ref": "#/definitions/project.DistributionType"
+                },
+                "draw_item_count": {
+                    "type": "integer"
+                },
+                "draw_seed_hash": {
                     "type": "string"
-                }
-            }
-        },
-        "oauth.CallbackResponse": {
-            "type": "object",
-            "properties": {
-                "data": {},
-                "error_msg": {
+                },
+                "drawn_at": {
                     "type": "string"
-                }
-            }
-        },
-        "oauth.GetLoginURLResponse": {
-            "type": "object",
-            "properties": {
-                "data": {
+                },
+                "end_time": {
                     "type": "string"
                 },
-                "error_msg": {
+                "hide_from_explore": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_completed": {
+                    "type": "boolean"
+                },
+                "is_favorited": {
+                    "type": "boolean"
+                },
+                "is_following_creator": {
+                    "type": "boolean"
+                },
+                "is_received": {
+                    "type": "boolean"
+                },
+                "minimum_trust_level": {
+                    "$ref": "#/definitions/oauth.TrustLevel"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "received_content": {
+                    "type": "string"
+                },
+                "received_contents": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "released_items_count": {
+                    "type": "integer"
+                },
+                "releases": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/project.ProjectRelease"
+                    }
+                },
+                "report_count": {
+                    "type": "integer"
+                },
+                "risk_level": {
+                    "type": "integer"
+                },
+                "start_time": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/project.ProjectStatus"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "total_items": {
+                    "type": "integer"
+                },
+                "trust_level_quotas": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "unreleased_items_count": {
+                    "type": "integer"
+                },
+                "updated_at": {
                     "type": "string"
+                },
+                "user_claim_quota": {
+                    "type": "integer"
+                },
+                "winner_source": {
+                    "$ref": "#/definitions/project.WinnerSourceType"
                 }
             }
         },
-        "oauth.LogoutResponse": {
+        "project.GetWaitlistResponse": {
             "type": "object",
             "properties": {
-                "data": {},
+                "data": {
+                    "$ref": "#/definitions/project.GetWaitlistResponseData"
+                },
                 "error_msg": {
                     "type": "string"
                 }
             }
         },
-        "oauth.TrustLevel": {
-            "type": "integer",
-            "format": "int32",
-            "enum": [
-                0,
-                1,
-                2,
-                3,
-                4
-            ],
-            "x-enum-varnames": [
-                "TrustLevelNewUser",
-                "TrustLevelBasicUser",
-                "TrustLevelUser",
-                "TrustLevelActiveUser",
-                "TrustLevelLeader"
-            ]
-        },
-        "oauth.User": {
+        "project.GetWaitlistResponseData": {
             "type": "object",
             "properties": {
-                "avatar_url": {
-                    "type": "string"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
+                "length": {
                     "type": "integer"
                 },
-                "is_active": {
-                    "type": "boolean"
+                "position": {
+                    "type": "integer"
                 },
-                "is_admin": {
+                "reservation_available": {
                     "type": "boolean"
                 },
-                "last_login_at": {
+                "reservation_expire_at": {
                     "type": "string"
+                }
+            }
+        },
+        "project.ImportIssueReason": {
+            "type": "string",
+            "enum": [
+                "duplicate",
+                "too_long",
+                "empty"
+            ],
+            "x-enum-varnames": [
+                "ImportIssueDuplicate",
+                "ImportIssueTooLong",
+                "ImportIssueEmpty"
+            ]
+        },
+        "project.ImportItemsSummary": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
                 },
-                "nickname": {
-                    "type": "string"
+                "duplicate": {
+                    "type": "integer"
                 },
-                "score": {
+                "empty": {
                     "type": "integer"
                 },
-                "trust_level": {
-                    "$ref": "#/definitions/oauth.TrustLevel"
+                "issues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/project.ImportLineIssue"
+                    }
                 },
-                "updated_at": {
-                    "type": "string"
+                "issues_truncated": {
+                    "type": "boolean"
                 },
-                "username": {
-                    "type": "string"
+                "too_long": {
+                    "type": "integer"
                 },
-                "violation_count": {
+                "total": {
                     "type": "integer"
                 }
             }
         },
-        "oauth.UserInfoResponse": {
+        "project.ImportLineIssue": {
+            "type": "object",
+            "properties": {
+                "line": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "$ref": "#/definitions/project.ImportIssueReason"
+                }
+            }
+        },
+        "project.ListFavoriteProjectsResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/oauth.BasicUserInfo"
+                    "$ref": "#/definitions/project.ListFavoriteProjectsResponseData"
                 },
                 "error_msg": {
                     "type": "string"
                 }
             }
         },
-        "project.CreateProjectRequestBody": {
+        "project.ListFavoriteProjectsResponseData": {
             "type": "object",
-            "required": [
-                "end_time",
-                "name",
-                "project_items",
-                "start_time"
-            ],
             "properties": {
-                "allow_same_ip": {
-                    "type": "boolean"
-                },
-                "description": {
-                    "type": "string",
-                    "maxLength": 1024
-                },
-                "distribution_type": {
-                    "enum": [
-                        0,
-                        1
-                    ],
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/project.DistributionType"
-                        }
-                    ]
-                },
-                "end_time": {
+                "next_cursor": {
                     "type": "string"
                 },
-                "hide_from_explore": {
-                    "type": "boolean"
-                },
-                "minimum_trust_level": {
-                    "enum": [
-                        0,
-                        1,
-                        2,
-                        3,
-                        4
-                    ],
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/oauth.TrustLevel"
-                        }
-                    ]
-                },
-                "name": {
-                    "type": "string",
-                    "maxLength": 32,
-                    "minLength": 1
-                },
-                "price": {
-                    "type": "number"
-                },
-                "project_items": {
-                    "type": "array",
-                    "minItems": 1,
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "project_tags": {
+                "results": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/project.ListFavoriteProjectsResult"
                     }
                 },
-                "risk_level": {
-                    "type": "integer",
-                    "maximum": 100,
-                    "minimum": 0
-                },
-                "start_time": {
-                    "type": "string"
-                },
-                "topic_id": {
+                "total": {
                     "type": "integer"
                 }
             }
         },
-        "project.DistributionType": {
-            "type": "integer",
-            "format": "int32",
-            "enum": [
-                0,
-                1,
-                2
-            ],
-            "x-enum-varnames": [
-                "DistributionTypeOneForEach",
-                "DistributionTypeLottery",
-                "DistributionTypeInvite"
-            ]
-        },
-        "project.GetProjectResponseData": {
+        "project.ListFavoriteProjectsResult": {
             "type": "object",
             "properties": {
                 "allow_same_ip": {
                     "type": "boolean"
                 },
-                "available_items_count": {
-                    "type": "integer"
-                },
                 "created_at": {
                     "type": "string"
                 },
-                "creator_id": {
-                    "type": "integer"
-                },
-                "creator_nickname": {
-                    "type": "string"
-                },
-                "creator_username": {
-                    "type": "string"
-                },
                 "description": {
                     "type": "string"
                 },
@@ -1102,18 +2386,15 @@ const docTemplate = `{
                 "end_time": {
                     "type": "string"
                 },
+                "favorited_at": {
+                    "type": "string"
+                },
                 "hide_from_explore": {
                     "type": "boolean"
                 },
                 "id": {
                     "type": "string"
                 },
-                "is_completed": {
-                    "type": "boolean"
-                },
-                "is_received": {
-                    "type": "boolean"
-                },
                 "minimum_trust_level": {
                     "$ref": "#/definitions/oauth.TrustLevel"
                 },
@@ -1123,21 +2404,12 @@ const docTemplate = `{
                 "price": {
                     "type": "number"
                 },
-                "received_content": {
-                    "type": "string"
-                },
-                "report_count": {
-                    "type": "integer"
-                },
                 "risk_level": {
                     "type": "integer"
                 },
                 "start_time": {
                     "type": "string"
                 },
-                "status": {
-                    "$ref": "#/definitions/project.ProjectStatus"
-                },
                 "tags": {
                     "type": "array",
                     "items": {
@@ -1146,9 +2418,6 @@ const docTemplate = `{
                 },
                 "total_items": {
                     "type": "integer"
-                },
-                "updated_at": {
-                    "type": "string"
                 }
             }
         },
@@ -1166,6 +2435,9 @@ const docTemplate = `{
         "project.ListProjectsResponseData": {
             "type": "object",
             "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
                 "results": {
                     "type": "array",
                     "items": {
@@ -1255,6 +2527,9 @@ const docTemplate = `{
         "project.ListReceiveHistoryResponseData": {
             "type": "object",
             "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
                 "results": {
                     "type": "array",
                     "items": {
@@ -1303,6 +2578,67 @@ const docTemplate = `{
                 }
             }
         },
+        "project.ProjectItemRevocation": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/project.RevokeAction"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "item_id": {
+                    "type": "integer"
+                },
+                "operator_id": {
+                    "type": "integer"
+                },
+                "out_trade_no": {
+                    "type": "string"
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "receiver_id": {
+                    "type": "integer"
+                },
+                "refunded": {
+                    "type": "boolean"
+                },
+                "replacement_item_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "project.ProjectRelease": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "item_count": {
+                    "type": "integer"
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "release_at": {
+                    "type": "string"
+                },
+                "released_at": {
+                    "type": "string"
+                }
+            }
+        },
         "project.ProjectResponse": {
             "type": "object",
             "properties": {
@@ -1340,6 +2676,22 @@ const docTemplate = `{
                 }
             }
         },
+        "project.ReleaseBatch": {
+            "type": "object",
+            "required": [
+                "item_count",
+                "release_at"
+            ],
+            "properties": {
+                "item_count": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "release_at": {
+                    "type": "string"
+                }
+            }
+        },
         "project.ReportProjectRequestBody": {
             "type": "object",
             "required": [
@@ -1353,6 +2705,17 @@ const docTemplate = `{
                 }
             }
         },
+        "project.RevokeAction": {
+            "type": "string",
+            "enum": [
+                "replace",
+                "release"
+            ],
+            "x-enum-varnames": [
+                "RevokeActionReplace",
+                "RevokeActionRelease"
+            ]
+        },
         "project.UpdateProjectRequestBody": {
             "type": "object",
             "required": [
@@ -1364,6 +2727,15 @@ const docTemplate = `{
                 "allow_same_ip": {
                     "type": "boolean"
                 },
+                "claim_end_time": {
+                    "description": "ClaimEndTime 报名抽奖的领取截止时间,为空时保留原值",
+                    "type": "string"
+                },
+                "claim_quota": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
                 "description": {
                     "type": "string",
                     "maxLength": 1024
@@ -1377,6 +2749,18 @@ const docTemplate = `{
                 "hide_from_explore": {
                     "type": "boolean"
                 },
+                "invite_user_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "invite_usernames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "minimum_trust_level": {
                     "enum": [
                         0,
@@ -1411,6 +2795,13 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "release_schedule": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "$ref": "#/definitions/project.ReleaseBatch"
+                    }
+                },
                 "risk_level": {
                     "type": "integer",
                     "maximum": 100,
@@ -1418,8 +2809,30 @@ const docTemplate = `{
                 },
                 "start_time": {
                     "type": "string"
+                },
+                "trust_level_quotas": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
+        },
+        "project.WinnerSourceType": {
+            "type": "integer",
+            "format": "int32",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "WinnerSourceDiscourseBot",
+                "WinnerSourceManual",
+                "WinnerSourceDraw",
+                "WinnerSourceScheduledDraw"
+            ]
         }
     }
 }`
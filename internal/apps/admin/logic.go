@@ -26,6 +26,8 @@ package admin
 
 import (
 	"context"
+	"time"
+
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/apps/project"
 	"github.com/linux-do/cdk/internal/db"
@@ -85,8 +87,8 @@ func QueryProjectsList(ctx context.Context, offset, limit int, status *project.P
 	}, nil
 }
 
-// QueryUsersList 获取用户列表
-func QueryUsersList(ctx context.Context, req *listUsersRequest) (int64, []oauth.User, error) {
+// QueryUsersList 获取用户列表,键集按 (created_at, id) 降序定位,返回总数、当前页与 next_cursor
+func QueryUsersList(ctx context.Context, req *listUsersRequest) (int64, []oauth.User, string, error) {
 	offset := (req.Current - 1) * req.Size
 
 	query := db.DB(ctx).Model(&oauth.User{})
@@ -106,15 +108,33 @@ func QueryUsersList(ctx context.Context, req *listUsersRequest) (int64, []oauth.
 		query = query.Where("violation_count >= ?", *req.MinViolations)
 	}
 
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		return 0, nil, err
+	total := utils.UncountedTotal
+	if req.NeedCount() {
+		if err := query.Count(&total).Error; err != nil {
+			return 0, nil, "", err
+		}
 	}
 
+	var (
+		cursorCreatedAt time.Time
+		cursorID        uint64
+	)
+	if ok, err := req.DecodeCursor(&cursorCreatedAt, &cursorID); err != nil {
+		return 0, nil, "", err
+	} else if ok {
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", cursorCreatedAt, cursorCreatedAt, cursorID)
+	}
+
+	limit, offset := req.Window(offset, req.Size)
 	var users []oauth.User
-	if err := query.Order("created_at DESC").Offset(offset).Limit(req.Size).Find(&users).Error; err != nil {
-		return 0, nil, err
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return 0, nil, "", err
+	}
+	users, nextCursor, err := utils.TrimCursorPage(&req.CursorPagination, users, req.Size,
+		func(u *oauth.User) []interface{} { return []interface{}{u.CreatedAt, u.ID} })
+	if err != nil {
+		return 0, nil, "", err
 	}
 
-	return total, users, nil
+	return total, users, nextCursor, nil
 }
@@ -25,11 +25,13 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/apps/project"
 	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/utils"
 	"gorm.io/gorm"
 
 	"github.com/gin-gonic/gin"
@@ -140,25 +142,74 @@ func ReviewProject(c *gin.Context) {
 		}
 	}
 
+	if p.Status != req.Status {
+		p.NotifyReviewed(c.Request.Context(), db.DB(c.Request.Context()), req.Status, "")
+		reviewed := *p
+		reviewed.Status = req.Status
+		project.InvalidateExploreCacheFor(c.Request.Context(), p, &reviewed)
+	}
 	c.JSON(http.StatusOK, ReviewProjectResponse{})
 }
 
+type exploreCacheStatsRequest struct {
+	Reset bool `json:"reset" form:"reset"`
+}
+
+type exploreCacheStatsResponse struct {
+	ErrorMsg string                     `json:"error_msg"`
+	Data     *project.ExploreCacheStats `json:"data"`
+}
+
+// GetExploreCacheStats 查看广场缓存命中统计
+// @Tags admin
+// @Param request query exploreCacheStatsRequest true "request query"
+// @Produce json
+// @Success 200 {object} exploreCacheStatsResponse
+// @Router /api/v1/admin/explore/cache-stats [get]
+func GetExploreCacheStats(c *gin.Context) {
+	req := &exploreCacheStatsRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, exploreCacheStatsResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	stats, err := project.GetExploreCacheStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, exploreCacheStatsResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	// 返回本次读取的数据后再清零,便于按时间窗口观察命中率
+	if req.Reset {
+		if err := project.ResetExploreCacheStats(c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, exploreCacheStatsResponse{ErrorMsg: err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, exploreCacheStatsResponse{Data: stats})
+}
+
 type listUsersRequest struct {
-	Current       int               `json:"current" form:"current" binding:"min=1"`
+	Current       int               `json:"current" form:"current" binding:"omitempty,min=1"`
 	Size          int               `json:"size" form:"size" binding:"min=1,max=100"`
 	Username      string            `json:"username" form:"username"`
 	IsActive      *bool             `json:"is_active" form:"is_active"`
 	TrustLevel    *oauth.TrustLevel `json:"trust_level" form:"trust_level" binding:"omitempty,oneof=0 1 2 3 4"`
 	IsAdmin       *bool             `json:"is_admin" form:"is_admin"`
 	MinViolations *uint8            `json:"min_violations" form:"min_violations"`
+	utils.CursorPagination
+}
+
+type listUsersResponseData struct {
+	Total      int64        `json:"total"`
+	Users      []oauth.User `json:"users"`
+	NextCursor string       `json:"next_cursor,omitempty"`
 }
 
 type listUsersResponse struct {
-	ErrorMsg string `json:"error_msg"`
-	Data     *struct {
-		Total int64        `json:"total"`
-		Users []oauth.User `json:"users"`
-	} `json:"data"`
+	ErrorMsg string                 `json:"error_msg"`
+	Data     *listUsersResponseData `json:"data"`
 }
 
 // ListUsers
@@ -173,20 +224,26 @@ func ListUsers(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, listUsersResponse{ErrorMsg: err.Error()})
 		return
 	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, listUsersResponse{ErrorMsg: err.Error()})
+		return
+	}
 
-	total, users, err := QueryUsersList(c.Request.Context(), req)
+	total, users, nextCursor, err := QueryUsersList(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, listUsersResponse{ErrorMsg: err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, listUsersResponse{ErrorMsg: err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, listUsersResponse{
-		Data: &struct {
-			Total int64        `json:"total"`
-			Users []oauth.User `json:"users"`
-		}{
-			Total: total,
-			Users: users,
+		Data: &listUsersResponseData{
+			Total:      total,
+			Users:      users,
+			NextCursor: nextCursor,
 		},
 	})
 }
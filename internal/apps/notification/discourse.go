@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/utils"
+)
+
+// defaultDiscourseBaseURL 未配置 linuxdo.base_url 时使用的 Discourse 站点
+const defaultDiscourseBaseURL = "https://linux.do"
+
+// DiscourseBaseURL 返回配置的 Discourse 站点地址,去除末尾的 /
+func DiscourseBaseURL() string {
+	if baseURL := strings.TrimRight(config.Config.LinuxDo.BaseURL, "/"); baseURL != "" {
+		return baseURL
+	}
+	return defaultDiscourseBaseURL
+}
+
+// markdownEscaper 转义 Markdown 与内联 HTML 的特殊字符,@ 后插入零宽空格使提及失效,换行折叠为空格
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"`", "\\`",
+	"*", "\\*",
+	"_", "\\_",
+	"~", "\\~",
+	"#", "\\#",
+	"[", "\\[",
+	"]", "\\]",
+	"(", "\\(",
+	")", "\\)",
+	"!", "\\!",
+	"|", "\\|",
+	":", "\\:",
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"@", "@\u200b",
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// EscapeMarkdown 转义拼接进通知正文的用户输入(项目名、争议原因等),
+// 避免其中的 Markdown、HTML 或 @提及在站内信中生效
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// sendDiscoursePM 通过 Discourse API 向多个用户发送一条站内信
+func sendDiscoursePM(ctx context.Context, recipients []string, title, raw string) error {
+	form := url.Values{}
+	form.Set("title", title)
+	form.Set("raw", raw)
+	form.Set("archetype", "private_message")
+	form.Set("target_recipients", strings.Join(recipients, ","))
+
+	resp, err := utils.Request(
+		ctx,
+		http.MethodPost,
+		DiscourseBaseURL()+"/posts.json",
+		strings.NewReader(form.Encode()),
+		map[string]string{
+			"Api-Key":      config.Config.LinuxDo.ApiKey,
+			"Api-Username": config.Config.LinuxDo.ApiUsername,
+			"Content-Type": "application/x-www-form-urlencoded",
+		},
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("discourse responded %d: %s", resp.StatusCode, strings.Join(e.Errors, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package notification
+
+import (
+	"time"
+)
+
+type NotificationType string
+
+const (
+	NotificationTypeFavoriteStarted NotificationType = "favorite_started" // 收藏的项目已开始
+	NotificationTypeLotteryWon      NotificationType = "lottery_won"      // 报名抽奖中奖
+	NotificationTypeProjectSoldOut  NotificationType = "project_sold_out" // 创建的项目已领完
+	NotificationTypeItemSold        NotificationType = "item_sold"        // 付费项目售出
+	NotificationTypeOrderRefunded   NotificationType = "order_refunded"   // 订单已退款
+	NotificationTypeProjectReviewed NotificationType = "project_reviewed" // 项目被审核或因举报隐藏
+	NotificationTypeDispute         NotificationType = "dispute"          // 退款争议状态变化
+)
+
+// PMStatus Discourse 站内信投递状态
+type PMStatus int8
+
+const (
+	PMStatusNone    PMStatus = iota // 不投递
+	PMStatusPending                 // 等待投递
+	PMStatusSent                    // 已投递
+	PMStatusFailed                  // 重试耗尽
+	PMStatusSending                 // 已被任务领取,租约过期后可被重新领取
+)
+
+// Notification 站内通知,每个接收者一条
+type Notification struct {
+	ID          uint64           `json:"id" gorm:"primaryKey,autoIncrement"`
+	UserID      uint64           `json:"-" gorm:"index:idx_user_read,priority:1"`
+	Type        NotificationType `json:"type" gorm:"size:32"`
+	Title       string           `json:"title" gorm:"size:255"`
+	Content     string           `json:"content" gorm:"type:text"`
+	ProjectID   string           `json:"project_id" gorm:"size:64"`
+	OutTradeNo  string           `json:"out_trade_no" gorm:"size:64"`
+	ReadAt      *time.Time       `json:"read_at" gorm:"index:idx_user_read,priority:2"`
+	PMStatus    PMStatus         `json:"-" gorm:"default:0;index:idx_pm_status"`
+	PMAttempts  int              `json:"-" gorm:"default:0"`
+	PMClaimedAt *time.Time       `json:"-"`
+	CreatedAt   time.Time        `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,143 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package notification
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/utils"
+)
+
+type NotificationResponse struct {
+	ErrorMsg string      `json:"error_msg"`
+	Data     interface{} `json:"data"`
+}
+
+type ListNotificationsRequest struct {
+	Current    int  `json:"current" form:"current" binding:"omitempty,min=1"`
+	Size       int  `json:"size" form:"size" binding:"min=1,max=100"`
+	UnreadOnly bool `json:"unread_only" form:"unread_only"`
+	utils.CursorPagination
+}
+
+type ListNotificationsResponse struct {
+	ErrorMsg string                         `json:"error_msg"`
+	Data     *ListNotificationsResponseData `json:"data"`
+}
+
+// ListNotificationsHTTP
+// @Tags notification
+// @Param request query ListNotificationsRequest true "request query"
+// @Produce json
+// @Success 200 {object} ListNotificationsResponse
+// @Router /api/v1/notifications [get]
+func ListNotificationsHTTP(c *gin.Context) {
+	userID := oauth.GetUserIDFromContext(c)
+
+	req := &ListNotificationsRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, ListNotificationsResponse{ErrorMsg: err.Error()})
+		return
+	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, ListNotificationsResponse{ErrorMsg: err.Error()})
+		return
+	}
+	offset := (req.Current - 1) * req.Size
+
+	pagedData, err := ListNotifications(c.Request.Context(), userID, offset, req.Size, req.UnreadOnly, &req.CursorPagination)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ListNotificationsResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListNotificationsResponse{Data: pagedData})
+}
+
+type UnreadCountResponseData struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// GetUnreadCountHTTP
+// @Tags notification
+// @Produce json
+// @Success 200 {object} NotificationResponse{data=UnreadCountResponseData}
+// @Router /api/v1/notifications/unread-count [get]
+func GetUnreadCountHTTP(c *gin.Context) {
+	count, err := UnreadCount(c.Request.Context(), oauth.GetUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NotificationResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, NotificationResponse{Data: UnreadCountResponseData{UnreadCount: count}})
+}
+
+type MarkReadRequest struct {
+	// IDs 为空时标记全部通知为已读
+	IDs []uint64 `json:"ids" binding:"omitempty,max=100,dive,gt=0"`
+}
+
+type MarkReadResponseData struct {
+	Marked      int64 `json:"marked"`
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// MarkReadHTTP
+// @Tags notification
+// @Accept json
+// @Produce json
+// @Param request body MarkReadRequest true "通知ID列表"
+// @Success 200 {object} NotificationResponse{data=MarkReadResponseData}
+// @Router /api/v1/notifications/read [post]
+func MarkReadHTTP(c *gin.Context) {
+	userID := oauth.GetUserIDFromContext(c)
+
+	var req MarkReadRequest
+	// 请求体可省略,等同于全部已读
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, NotificationResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	marked, err := MarkRead(c.Request.Context(), userID, req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NotificationResponse{ErrorMsg: err.Error()})
+		return
+	}
+	count, err := UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NotificationResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, NotificationResponse{Data: MarkReadResponseData{Marked: marked, UnreadCount: count}})
+}
@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"github.com/linux-do/cdk/internal/utils"
+	"gorm.io/gorm"
+)
+
+// pmTypes 需要同时以 Discourse 站内信送达的通知类型,其余仅在站内展示
+var pmTypes = map[NotificationType]bool{
+	NotificationTypeLotteryWon:      true,
+	NotificationTypeOrderRefunded:   true,
+	NotificationTypeProjectReviewed: true,
+	NotificationTypeDispute:         true,
+}
+
+// pmEnabled 配置了 LinuxDo.ApiKey 时启用站内信通道
+func pmEnabled() bool {
+	return config.Config.LinuxDo.ApiKey != ""
+}
+
+// Send 在调用方的事务中写入通知,随业务变更一同提交;站内信由 HandleDeliverPM 异步投递。
+// 写入失败仅记录日志,不影响业务流程。
+func Send(ctx context.Context, tx *gorm.DB, notifications ...*Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+	for _, n := range notifications {
+		if pmEnabled() && pmTypes[n.Type] {
+			n.PMStatus = PMStatusPending
+		}
+	}
+	if err := tx.Create(&notifications).Error; err != nil {
+		logger.WarnF(ctx, "[Notification] create %d notifications failed: %v", len(notifications), err)
+	}
+}
+
+// SendToUsers 以同一内容通知多个用户
+func SendToUsers(ctx context.Context, tx *gorm.DB, userIDs []uint64, template Notification) {
+	notifications := make([]*Notification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		n := template
+		n.UserID = userID
+		notifications = append(notifications, &n)
+	}
+	Send(ctx, tx, notifications...)
+}
+
+// UnreadCount 用户未读通知数
+func UnreadCount(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := db.DB(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将通知标记为已读,ids 为空时标记全部,返回本次标记的数量
+func MarkRead(ctx context.Context, userID uint64, ids []uint64) (int64, error) {
+	query := db.DB(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+type ListNotificationsResponseData struct {
+	Total       int64           `json:"total"`
+	UnreadCount int64           `json:"unread_count"`
+	Results     *[]Notification `json:"results"`
+	NextCursor  string          `json:"next_cursor,omitempty"`
+}
+
+// ListNotifications 按时间倒序查询用户通知,键集按 id 降序定位
+func ListNotifications(ctx context.Context, userID uint64, offset, limit int, unreadOnly bool, page *utils.CursorPagination) (*ListNotificationsResponseData, error) {
+	query := db.DB(ctx).Model(&Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	// 查询总数
+	total := utils.UncountedTotal
+	if page.NeedCount() {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+	}
+	unreadCount, err := UnreadCount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return &ListNotificationsResponseData{
+			Total:       0,
+			UnreadCount: unreadCount,
+			Results:     nil,
+		}, nil
+	}
+
+	// 游标定位
+	var cursorID uint64
+	if ok, err := page.DecodeCursor(&cursorID); err != nil {
+		return nil, err
+	} else if ok {
+		query = query.Where("id < ?", cursorID)
+	}
+
+	queryLimit, queryOffset := page.Window(offset, limit)
+	var notifications []Notification
+	if err := query.Order("id DESC").Limit(queryLimit).Offset(queryOffset).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	notifications, nextCursor, err := utils.TrimCursorPage(page, notifications, limit,
+		func(n *Notification) []interface{} { return []interface{}{n.ID} })
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListNotificationsResponseData{
+		Total:       total,
+		UnreadCount: unreadCount,
+		Results:     &notifications,
+		NextCursor:  nextCursor,
+	}, nil
+}
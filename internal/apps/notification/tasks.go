@@ -0,0 +1,153 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// deliverPMBatchSize 每次任务投递的站内信数量上限
+	deliverPMBatchSize = 100
+	// maxPMAttempts 站内信最多投递次数,超过后标记失败
+	maxPMAttempts = 5
+	// pmClaimLease 领取后的投递租约,任务中断时过期后由下次任务重新领取
+	pmClaimLease = 10 * time.Minute
+)
+
+// HandleDeliverPM 以 Discourse 站内信投递待发送的通知,失败的通知留待下次任务重试。
+// 每条通知发送前以 pm_status 比较并交换领取,重叠执行的任务不会重复发送。
+func HandleDeliverPM(ctx context.Context, _ *asynq.Task) error {
+	if !pmEnabled() {
+		return nil
+	}
+
+	now := time.Now()
+	var notifications []Notification
+	if err := db.DB(ctx).
+		Where("pm_status = ? OR (pm_status = ? AND pm_claimed_at < ?)", PMStatusPending, PMStatusSending, now.Add(-pmClaimLease)).
+		Order("id ASC").
+		Limit(deliverPMBatchSize).
+		Find(&notifications).Error; err != nil {
+		logger.ErrorF(ctx, "[Notification] query pending pms failed: %v", err)
+		return err
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	logger.InfoF(ctx, "[Notification] found %d pending pms", len(notifications))
+
+	userIDs := make([]uint64, 0, len(notifications))
+	for i := range notifications {
+		userIDs = append(userIDs, notifications[i].UserID)
+	}
+	var users []oauth.User
+	if err := db.DB(ctx).Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		logger.ErrorF(ctx, "[Notification] query pm recipients failed: %v", err)
+		return err
+	}
+	usernames := make(map[uint64]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+
+	for i := range notifications {
+		n := &notifications[i]
+		claimed, err := claimPM(ctx, n)
+		if err != nil {
+			logger.ErrorF(ctx, "[Notification] claim pm of notification %d failed: %v", n.ID, err)
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		username, ok := usernames[n.UserID]
+		if !ok {
+			if err := setPMStatus(ctx, n, PMStatusFailed); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := sendDiscoursePM(ctx, []string{username}, n.Title, n.Content); err != nil {
+			logger.WarnF(ctx, "[Notification] deliver pm of notification %d failed: %v", n.ID, err)
+			status := PMStatusPending
+			if n.PMAttempts >= maxPMAttempts {
+				status = PMStatusFailed
+			}
+			if err := setPMStatus(ctx, n, status); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setPMStatus(ctx, n, PMStatusSent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimPM 以读取时的状态与投递次数为条件领取通知并计入一次投递,返回是否领取成功。
+// 投递次数已耗尽的过期领取直接标记失败。
+func claimPM(ctx context.Context, n *Notification) (bool, error) {
+	updates := map[string]interface{}{
+		"pm_status":     PMStatusSending,
+		"pm_attempts":   gorm.Expr("pm_attempts + 1"),
+		"pm_claimed_at": time.Now(),
+	}
+	if n.PMAttempts >= maxPMAttempts {
+		updates = map[string]interface{}{"pm_status": PMStatusFailed}
+	}
+	result := db.DB(ctx).Model(&Notification{}).
+		Where("id = ? AND pm_status = ? AND pm_attempts = ?", n.ID, n.PMStatus, n.PMAttempts).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 || n.PMAttempts >= maxPMAttempts {
+		return false, nil
+	}
+	n.PMStatus = PMStatusSending
+	n.PMAttempts++
+	return true, nil
+}
+
+// setPMStatus 记录已领取通知的投递结果
+func setPMStatus(ctx context.Context, n *Notification, status PMStatus) error {
+	if err := db.DB(ctx).Model(&Notification{}).
+		Where("id = ? AND pm_status = ?", n.ID, PMStatusSending).
+		Update("pm_status", status).Error; err != nil {
+		logger.ErrorF(ctx, "[Notification] update pm status of notification %d failed: %v", n.ID, err)
+		return err
+	}
+	n.PMStatus = status
+	return nil
+}
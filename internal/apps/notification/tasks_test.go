@@ -0,0 +1,194 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package notification
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+)
+
+// setupPM 启用站内信并将 Discourse 指向测试服务器,返回每次投递的标题
+func setupPM(t *testing.T, status int) func() []string {
+	t.Helper()
+	dbtest.Setup(t, &oauth.User{}, &Notification{})
+	if err := db.DB(context.Background()).Create(&oauth.User{ID: 1, Username: "alice"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu     sync.Mutex
+		titles []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		titles = append(titles, r.FormValue("title"))
+		mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errors":["test"]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	prev := config.Config.LinuxDo
+	config.Config.LinuxDo.BaseURL = server.URL
+	config.Config.LinuxDo.ApiKey = "key"
+	t.Cleanup(func() { config.Config.LinuxDo = prev })
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), titles...)
+	}
+}
+
+func createPM(t *testing.T, n *Notification) *Notification {
+	t.Helper()
+	if n.UserID == 0 {
+		n.UserID = 1
+	}
+	if err := db.DB(context.Background()).Create(n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func loadPM(t *testing.T, id uint64) *Notification {
+	t.Helper()
+	var n Notification
+	if err := db.DB(context.Background()).First(&n, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &n
+}
+
+func TestHandleDeliverPM(t *testing.T) {
+	sent := setupPM(t, http.StatusOK)
+	ctx := context.Background()
+
+	stale := time.Now().Add(-2 * pmClaimLease)
+	fresh := time.Now()
+	pending := createPM(t, &Notification{Title: "pending", PMStatus: PMStatusPending})
+	abandoned := createPM(t, &Notification{Title: "abandoned", PMStatus: PMStatusSending, PMAttempts: 1, PMClaimedAt: &stale})
+	inFlight := createPM(t, &Notification{Title: "in-flight", PMStatus: PMStatusSending, PMAttempts: 1, PMClaimedAt: &fresh})
+	exhausted := createPM(t, &Notification{Title: "exhausted", PMStatus: PMStatusSending, PMAttempts: maxPMAttempts, PMClaimedAt: &stale})
+	orphan := createPM(t, &Notification{UserID: 99, Title: "orphan", PMStatus: PMStatusPending})
+
+	if err := HandleDeliverPM(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent(); strings.Join(got, ",") != "pending,abandoned" {
+		t.Errorf("sent %v, want [pending abandoned]", got)
+	}
+	for _, tc := range []struct {
+		n        *Notification
+		status   PMStatus
+		attempts int
+	}{
+		{pending, PMStatusSent, 1},
+		{abandoned, PMStatusSent, 2},
+		{inFlight, PMStatusSending, 1},
+		{exhausted, PMStatusFailed, maxPMAttempts},
+		{orphan, PMStatusFailed, 1},
+	} {
+		got := loadPM(t, tc.n.ID)
+		if got.PMStatus != tc.status || got.PMAttempts != tc.attempts {
+			t.Errorf("%s: status %d attempts %d, want %d %d", tc.n.Title, got.PMStatus, got.PMAttempts, tc.status, tc.attempts)
+		}
+	}
+
+	// 已投递的通知不会被再次发送
+	if err := HandleDeliverPM(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent(); len(got) != 2 {
+		t.Errorf("second run sent %v", got[2:])
+	}
+}
+
+func TestHandleDeliverPMRetries(t *testing.T) {
+	sent := setupPM(t, http.StatusUnprocessableEntity)
+	ctx := context.Background()
+
+	n := createPM(t, &Notification{Title: "retry", PMStatus: PMStatusPending, PMAttempts: maxPMAttempts - 2})
+	if err := HandleDeliverPM(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadPM(t, n.ID); got.PMStatus != PMStatusPending || got.PMAttempts != maxPMAttempts-1 {
+		t.Errorf("after first failure: status %d attempts %d", got.PMStatus, got.PMAttempts)
+	}
+	if err := HandleDeliverPM(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadPM(t, n.ID); got.PMStatus != PMStatusFailed || got.PMAttempts != maxPMAttempts {
+		t.Errorf("after last failure: status %d attempts %d", got.PMStatus, got.PMAttempts)
+	}
+	if got := sent(); len(got) != 2 {
+		t.Errorf("sent %d times, want 2", len(got))
+	}
+}
+
+func TestClaimPM(t *testing.T) {
+	setupPM(t, http.StatusOK)
+	ctx := context.Background()
+
+	n := createPM(t, &Notification{Title: "claim", PMStatus: PMStatusPending})
+	// 两个任务读到同一条待投递通知,只有先领取的一方可以发送
+	first, second := *n, *n
+	if ok, err := claimPM(ctx, &first); err != nil || !ok {
+		t.Fatalf("first claim: %v %v", ok, err)
+	}
+	if ok, err := claimPM(ctx, &second); err != nil || ok {
+		t.Fatalf("second claim: %v %v", ok, err)
+	}
+	if got := loadPM(t, n.ID); got.PMStatus != PMStatusSending || got.PMAttempts != 1 || got.PMClaimedAt == nil {
+		t.Errorf("claimed row: status %d attempts %d claimed_at %v", got.PMStatus, got.PMAttempts, got.PMClaimedAt)
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	cases := map[string]string{
+		"plain 项目":              "plain 项目",
+		"**bold** _x_":          `\*\*bold\*\* \_x\_`,
+		"[link](http://x)":      `\[link\]\(http\://x\)`,
+		"@admin hi":             "@\u200badmin hi",
+		"line1\n# title\r\nend": `line1 \# title end`,
+		"<img src=x>":           "&lt;img src=x&gt;",
+		"`code` \\":             "\\`code\\` \\\\",
+	}
+	for in, want := range cases {
+		if got := EscapeMarkdown(in); got != want {
+			t.Errorf("EscapeMarkdown(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -25,8 +25,6 @@
 package payment
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/subtle"
@@ -34,84 +32,171 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
 	"sort"
 	"strings"
+
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/utils"
 )
 
-// deriveAESKey 从配置的 key 派生出 32 字节 AES-256 密钥。
-// 优先尝试 base64 解码,失败则取原字节;不足 32 字节以 MD5 填充至稳定 32 字节。
-func deriveAESKey(raw string) ([]byte, error) {
-	if raw == "" {
-		return nil, errors.New(ErrEncryptionKeyMissing)
-	}
-	if b, err := base64.StdEncoding.DecodeString(raw); err == nil && len(b) == 32 {
-		return b, nil
-	}
-	if len(raw) == 32 {
-		return []byte(raw), nil
-	}
-	sum := md5.Sum([]byte(raw))
-	key := make([]byte, 32)
-	copy(key[:16], sum[:])
-	sum2 := md5.Sum(sum[:])
-	copy(key[16:], sum2[:])
-	return key, nil
-}
+const (
+	// legacyKeyID 旧版 config_encryption_key 对应的密钥 ID,不带前缀的历史密文也使用该密钥解密
+	legacyKeyID = "default"
+	// keyIDSeparator 密文各字段的分隔符,base64 字符集不含该字符
+	keyIDSeparator = ":"
+	// maxKeyIDLength 密钥 ID 的最大长度
+	maxKeyIDLength = 32
+	// kdfHKDF 密文中标记 HKDF 派生的版本字段
+	kdfHKDF = "hkdf"
+	// hkdfSaltSize 每条密文随机生成的 HKDF salt 长度
+	hkdfSaltSize = 16
+	// hkdfInfo HKDF 派生支付凭据加密密钥时使用的用途标识
+	hkdfInfo = "cdk:payment:client-secret"
+)
 
-// EncryptSecret 使用 AES-256-GCM 加密明文,输出 base64(nonce|ciphertext|tag)。
+// EncryptSecret 使用单个密钥加密明文,输出格式同 secretKeyring.Encrypt。
 func EncryptSecret(plaintext, key string) (string, error) {
-	k, err := deriveAESKey(key)
+	ring, err := newSecretKeyring("", nil, key)
 	if err != nil {
 		return "", err
 	}
-	block, err := aes.NewCipher(k)
+	return ring.Encrypt(plaintext)
+}
+
+// DecryptSecret 解密 EncryptSecret 的输出,兼容旧版密钥派生的历史密文。
+func DecryptSecret(encoded, key string) (string, error) {
+	ring, err := newSecretKeyring("", nil, key)
 	if err != nil {
 		return "", err
 	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
+	return ring.Decrypt(encoded)
+}
+
+// secretKeyring 支付凭据加密密钥环:一个活动密钥用于加密,其余密钥仅用于解密。
+// 密文格式为 "<keyID>:hkdf:<base64(salt)>:<base64(nonce|ciphertext|tag)>",每条密文使用随机 salt 经 HKDF-SHA256 派生密钥。
+// 兼容两种旧格式:"<keyID>:<base64>" 与不带前缀的 "<base64>"(视为 legacyKeyID),均使用旧版派生解密。
+type secretKeyring struct {
+	activeID string
+	keys     map[string]string
+}
+
+// newSecretKeyring 根据配置构造密钥环。legacyKey 非空且 keys 中未声明 legacyKeyID 时作为 legacyKeyID 加入;
+// activeID 为空时默认使用 legacyKeyID。
+func newSecretKeyring(activeID string, keys map[string]string, legacyKey string) (*secretKeyring, error) {
+	ring := &secretKeyring{activeID: activeID, keys: make(map[string]string, len(keys)+1)}
+	if ring.activeID == "" {
+		ring.activeID = legacyKeyID
+	}
+	for id, raw := range keys {
+		if id == "" || len(id) > maxKeyIDLength || strings.Contains(id, keyIDSeparator) {
+			return nil, fmt.Errorf("%s: %q", ErrInvalidEncryptionKeyID, id)
+		}
+		if raw == "" {
+			return nil, errors.New(ErrEncryptionKeyMissing)
+		}
+		ring.keys[id] = raw
 	}
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
+	if _, ok := ring.keys[legacyKeyID]; !ok && legacyKey != "" {
+		ring.keys[legacyKeyID] = legacyKey
+	}
+	if _, ok := ring.keys[ring.activeID]; !ok {
+		return nil, errors.New(ErrEncryptionKeyMissing)
 	}
-	ct := gcm.Seal(nil, nonce, []byte(plaintext), nil)
-	out := make([]byte, 0, len(nonce)+len(ct))
-	out = append(out, nonce...)
-	out = append(out, ct...)
-	return base64.StdEncoding.EncodeToString(out), nil
+	return ring, nil
 }
 
-// DecryptSecret 解密 EncryptSecret 的输出。
-func DecryptSecret(encoded, key string) (string, error) {
-	k, err := deriveAESKey(key)
+// currentKeyring 返回由全局配置构造的密钥环
+func currentKeyring() (*secretKeyring, error) {
+	p := config.Config.Payment
+	return newSecretKeyring(p.ActiveEncryptionKeyID, p.ConfigEncryptionKeys, p.ConfigEncryptionKey)
+}
+
+// ValidateEncryptionKeys 校验已配置的全部支付加密密钥强度,启用支付时还要求活动密钥存在
+func ValidateEncryptionKeys() error {
+	p := config.Config.Payment
+	ring, err := newSecretKeyring(p.ActiveEncryptionKeyID, p.ConfigEncryptionKeys, p.ConfigEncryptionKey)
 	if err != nil {
+		if !p.Enabled && len(p.ConfigEncryptionKeys) == 0 && p.ConfigEncryptionKey == "" {
+			return nil
+		}
+		return err
+	}
+	for id, raw := range ring.keys {
+		if err := utils.ValidateEncryptionKey(raw); err != nil {
+			return fmt.Errorf("payment encryption key %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Encrypt 使用活动密钥加密,输出带密钥 ID 与 salt 的密文
+func (r *secretKeyring) Encrypt(plaintext string) (string, error) {
+	salt := make([]byte, hkdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	raw, err := base64.StdEncoding.DecodeString(encoded)
+	k, err := utils.DeriveHKDFKey(r.keys[r.activeID], salt, hkdfInfo)
 	if err != nil {
-		return "", fmt.Errorf("decode secret: %w", err)
+		return "", err
 	}
-	block, err := aes.NewCipher(k)
+	enc, err := utils.EncryptAESGCM(k, plaintext)
 	if err != nil {
 		return "", err
 	}
-	gcm, err := cipher.NewGCM(block)
+	return strings.Join([]string{r.activeID, kdfHKDF, base64.StdEncoding.EncodeToString(salt), enc}, keyIDSeparator), nil
+}
+
+// Decrypt 按密文中的密钥 ID 与派生方式选择密钥解密
+func (r *secretKeyring) Decrypt(encoded string) (string, error) {
+	c, err := parseSecretCiphertext(encoded)
 	if err != nil {
 		return "", err
 	}
-	ns := gcm.NonceSize()
-	if len(raw) < ns+gcm.Overhead() {
-		return "", errors.New("ciphertext too short")
+	raw, ok := r.keys[c.keyID]
+	if !ok {
+		return "", fmt.Errorf("%s: %q", ErrEncryptionKeyUnknown, c.keyID)
+	}
+	var k []byte
+	if c.salt != nil {
+		k, err = utils.DeriveHKDFKey(raw, c.salt, hkdfInfo)
+	} else {
+		k, err = utils.DeriveLegacyAESKey(raw)
 	}
-	nonce, ct := raw[:ns], raw[ns:]
-	pt, err := gcm.Open(nil, nonce, ct, nil)
 	if err != nil {
 		return "", err
 	}
-	return string(pt), nil
+	return utils.DecryptAESGCM(k, c.data)
+}
+
+// NeedsRotation 判断密文是否需要以活动密钥和 HKDF 派生重新加密
+func (r *secretKeyring) NeedsRotation(encoded string) bool {
+	c, err := parseSecretCiphertext(encoded)
+	return err != nil || c.keyID != r.activeID || c.salt == nil
+}
+
+// secretCiphertext 解析后的密文,salt 为 nil 表示旧版密钥派生
+type secretCiphertext struct {
+	keyID string
+	salt  []byte
+	data  string
+}
+
+func parseSecretCiphertext(encoded string) (*secretCiphertext, error) {
+	parts := strings.Split(encoded, keyIDSeparator)
+	switch {
+	case len(parts) == 1:
+		return &secretCiphertext{keyID: legacyKeyID, data: parts[0]}, nil
+	case len(parts) == 2:
+		return &secretCiphertext{keyID: parts[0], data: parts[1]}, nil
+	case len(parts) == 4 && parts[1] == kdfHKDF:
+		salt, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil || len(salt) == 0 {
+			return nil, errors.New(ErrInvalidCiphertext)
+		}
+		return &secretCiphertext{keyID: parts[0], salt: salt, data: parts[3]}, nil
+	default:
+		return nil, errors.New(ErrInvalidCiphertext)
+	}
 }
 
 // BuildSign 按易支付/CodePay/VPay 兼容协议生成 MD5 签名(小写十六进制)。
@@ -25,10 +25,27 @@
 package payment
 
 import (
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/linux-do/cdk/internal/utils"
 )
 
+// legacyEncrypt 生成旧版(MD5 填充派生、无前缀)密文
+func legacyEncrypt(t *testing.T, plaintext, key string) string {
+	t.Helper()
+	k, err := utils.DeriveLegacyAESKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := utils.EncryptAESGCM(k, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
 func TestBuildSignOrdering(t *testing.T) {
 	// 示例取自 payment.txt:
 	// payload="money=10&name=Test&out_trade_no=M20250101&pid=001&type=epay"
@@ -124,3 +141,107 @@ func TestEncryptNonceRandomness(t *testing.T) {
 		t.Fatal("two encryptions of the same plaintext must differ (random nonce)")
 	}
 }
+
+func TestKeyringRotation(t *testing.T) {
+	legacy := "0123456789abcdef0123456789abcdef"
+	v2 := "fedcba9876543210fedcba9876543210"
+
+	// 历史密文无密钥 ID 前缀
+	legacyEnc := legacyEncrypt(t, "old-secret", legacy)
+
+	oldRing, err := newSecretKeyring("", nil, legacy)
+	if err != nil {
+		t.Fatalf("keyring err: %v", err)
+	}
+	defaultEnc, err := oldRing.Encrypt("default-secret")
+	if err != nil {
+		t.Fatalf("encrypt err: %v", err)
+	}
+	if !strings.HasPrefix(defaultEnc, legacyKeyID+keyIDSeparator+kdfHKDF+keyIDSeparator) {
+		t.Fatalf("ciphertext should carry key id, got %s", defaultEnc)
+	}
+
+	ring, err := newSecretKeyring("v2", map[string]string{"v2": v2}, legacy)
+	if err != nil {
+		t.Fatalf("keyring err: %v", err)
+	}
+	for enc, want := range map[string]string{legacyEnc: "old-secret", defaultEnc: "default-secret"} {
+		if !ring.NeedsRotation(enc) {
+			t.Fatalf("%s should need rotation", enc)
+		}
+		got, err := ring.Decrypt(enc)
+		if err != nil || got != want {
+			t.Fatalf("decrypt %s: got %q, %v", enc, got, err)
+		}
+	}
+
+	newEnc, err := ring.Encrypt("new-secret")
+	if err != nil {
+		t.Fatalf("encrypt err: %v", err)
+	}
+	if ring.NeedsRotation(newEnc) {
+		t.Fatal("ciphertext under active key should not need rotation")
+	}
+	if _, err := oldRing.Decrypt(newEnc); err == nil {
+		t.Fatal("keyring without v2 should fail to decrypt")
+	}
+}
+
+func TestKeyringRejectsInvalidConfig(t *testing.T) {
+	if _, err := newSecretKeyring("v2", nil, "0123456789abcdef0123456789abcdef"); err == nil {
+		t.Fatal("missing active key should be rejected")
+	}
+	if _, err := newSecretKeyring("", map[string]string{"a:b": "k"}, "k"); err == nil {
+		t.Fatal("key id containing separator should be rejected")
+	}
+}
+
+func TestDecryptLegacyDerivation(t *testing.T) {
+	// 旧版派生对短 key 使用 MD5 填充,新版本仍需能解密
+	for _, key := range []string{"short-key", "0123456789abcdef0123456789abcdef"} {
+		ring, err := newSecretKeyring("", nil, key)
+		if err != nil {
+			t.Fatalf("keyring err: %v", err)
+		}
+		legacyEnc := legacyEncrypt(t, "secret", key)
+		for _, enc := range []string{legacyEnc, legacyKeyID + keyIDSeparator + legacyEnc} {
+			got, err := ring.Decrypt(enc)
+			if err != nil || got != "secret" {
+				t.Fatalf("decrypt legacy %s with %q: got %q, %v", enc, key, got, err)
+			}
+			if !ring.NeedsRotation(enc) {
+				t.Fatal("legacy ciphertext should need rotation")
+			}
+		}
+	}
+}
+
+func TestHKDFSaltPerCiphertext(t *testing.T) {
+	key := "0123456789abcdef0123456789abcdef"
+	a, _ := EncryptSecret("abc", key)
+	b, _ := EncryptSecret("abc", key)
+	pa, err := parseSecretCiphertext(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := parseSecretCiphertext(b)
+	if len(pa.salt) != hkdfSaltSize || string(pa.salt) == string(pb.salt) {
+		t.Fatal("each ciphertext must carry its own random salt")
+	}
+	if _, err := DecryptSecret("default:hkdf:!!:abc", key); err == nil {
+		t.Fatal("malformed ciphertext should fail")
+	}
+}
+
+func TestValidateEncryptionKey(t *testing.T) {
+	if err := utils.ValidateEncryptionKey("short-key"); !errors.Is(err, utils.ErrWeakEncryptionKey) {
+		t.Fatalf("short key should be rejected, got %v", err)
+	}
+	if err := utils.ValidateEncryptionKey("0123456789abcdef0123456789abcdef"); err != nil {
+		t.Fatalf("32-byte key should pass: %v", err)
+	}
+	// base64 编码的 32 字节随机数
+	if err := utils.ValidateEncryptionKey("AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="); err != nil {
+		t.Fatalf("base64 32-byte key should pass: %v", err)
+	}
+}
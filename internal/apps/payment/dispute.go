@@ -0,0 +1,411 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"gorm.io/gorm"
+)
+
+// defaultDisputeWindowDays 未配置时付款后可发起争议的天数
+const defaultDisputeWindowDays = 7
+
+// DisputeStatus 争议状态机
+//
+//	OPEN(0) -> REFUNDED(3)                        // 创建者同意退款
+//	        -> REJECTED(1) -> ARBITRATING(2)      // 创建者拒绝,买家申请仲裁
+//	ARBITRATING / OPEN / REJECTED -> REFUNDED(3) / CLOSED(4)  // 管理员仲裁
+type DisputeStatus int8
+
+const (
+	DisputeStatusOpen        DisputeStatus = 0
+	DisputeStatusRejected    DisputeStatus = 1
+	DisputeStatusArbitrating DisputeStatus = 2
+	DisputeStatusRefunded    DisputeStatus = 3
+	DisputeStatusClosed      DisputeStatus = 4
+)
+
+// pendingDisputeStatuses 未结束的争议状态,仍可能经仲裁退款并作废 item
+var pendingDisputeStatuses = []DisputeStatus{DisputeStatusOpen, DisputeStatusRejected, DisputeStatusArbitrating}
+
+// PaymentDispute 买家对已完成订单发起的退款争议,每笔订单至多一条
+type PaymentDispute struct {
+	ID              uint64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	OutTradeNo      string        `gorm:"size:64;uniqueIndex;not null" json:"out_trade_no"`
+	ProjectID       string        `gorm:"size:64;index" json:"project_id"`
+	ItemID          uint64        `json:"item_id"`
+	PayerID         uint64        `gorm:"index:idx_dispute_payer_status,priority:1" json:"payer_id"`
+	PayeeID         uint64        `gorm:"index:idx_dispute_payee_status,priority:1" json:"payee_id"`
+	Status          DisputeStatus `gorm:"default:0;index:idx_dispute_payer_status,priority:2;index:idx_dispute_payee_status,priority:2;index" json:"status"`
+	Reason          string        `gorm:"size:512" json:"reason"`
+	CreatorResponse string        `gorm:"size:512" json:"creator_response"`
+	ArbitrationNote string        `gorm:"size:512" json:"arbitration_note"`
+	ResolvedBy      *uint64       `json:"resolved_by"`
+	ResolvedAt      *time.Time    `json:"resolved_at"`
+	RefundSucceeded bool          `json:"refund_succeeded"`
+	RevocationID    *uint64       `json:"revocation_id"`
+	CreatedAt       time.Time     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time     `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 自定义表名
+func (PaymentDispute) TableName() string { return "payment_disputes" }
+
+// disputeWindow 返回付款后可发起争议的时长
+func disputeWindow() time.Duration {
+	days := config.Config.Payment.DisputeWindowDays
+	if days <= 0 {
+		days = defaultDisputeWindowDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// getDispute 按 ID 读取争议
+func getDispute(ctx context.Context, disputeID uint64) (*PaymentDispute, error) {
+	var d PaymentDispute
+	if err := db.DB(ctx).Where("id = ?", disputeID).First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(ErrDisputeNotFound)
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// transitionDispute CAS 推进争议状态,同时按需切换订单状态;状态已变化时返回 ErrDisputeStateChanged
+func transitionDispute(tx *gorm.DB, d *PaymentDispute, from []DisputeStatus, updates map[string]any, orderFrom, orderTo *OrderStatus) error {
+	result := tx.Model(&PaymentDispute{}).
+		Where("id = ? AND status IN ?", d.ID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(ErrDisputeStateChanged)
+	}
+	if orderFrom == nil {
+		return nil
+	}
+	result = tx.Model(&PaymentOrder{}).
+		Where("out_trade_no = ? AND status = ?", d.OutTradeNo, *orderFrom).
+		Update("status", *orderTo)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(ErrDisputeStateChanged)
+	}
+	return nil
+}
+
+func orderStatusPtr(s OrderStatus) *OrderStatus { return &s }
+
+// hasPendingDispute 判断 item 是否存在未结束的争议;争议期间 item 只能经争议流程作废
+func hasPendingDispute(tx *gorm.DB, projectID string, itemID uint64) (bool, error) {
+	var count int64
+	if err := tx.Model(&PaymentDispute{}).
+		Where("project_id = ? AND item_id = ? AND status IN ?", projectID, itemID, pendingDisputeStatuses).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// OpenDispute 买家对自己已完成的订单发起争议,订单转为 DISPUTED
+func OpenDispute(ctx context.Context, payerID uint64, outTradeNo, reason string) (*PaymentDispute, error) {
+	var order PaymentOrder
+	if err := db.DB(ctx).Where("out_trade_no = ? AND payer_id = ?", outTradeNo, payerID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(ErrOrderNotFound)
+		}
+		return nil, err
+	}
+	if order.Status != OrderStatusCompleted {
+		return nil, errors.New(ErrDisputeOrderNotCompleted)
+	}
+	if order.PaidAt == nil || time.Since(*order.PaidAt) > disputeWindow() {
+		return nil, errors.New(ErrDisputeWindowClosed)
+	}
+
+	d := &PaymentDispute{
+		OutTradeNo: order.OutTradeNo,
+		ProjectID:  order.ProjectID,
+		ItemID:     order.ItemID,
+		PayerID:    order.PayerID,
+		PayeeID:    order.PayeeID,
+		Status:     DisputeStatusOpen,
+		Reason:     reason,
+	}
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var existed int64
+		if err := tx.Model(&PaymentDispute{}).Where("out_trade_no = ?", order.OutTradeNo).Count(&existed).Error; err != nil {
+			return err
+		}
+		if existed > 0 {
+			return errors.New(ErrDisputeExists)
+		}
+		result := tx.Model(&PaymentOrder{}).
+			Where("out_trade_no = ? AND status = ?", order.OutTradeNo, OrderStatusCompleted).
+			Update("status", OrderStatusDisputed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(ErrDisputeOrderNotCompleted)
+		}
+		return tx.Create(d).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	notifyDispute(ctx, d, disputeEventOpened)
+	return d, nil
+}
+
+// RejectDispute 创建者拒绝退款,订单恢复为 COMPLETED,买家可申请仲裁
+func RejectDispute(ctx context.Context, payeeID, disputeID uint64, response string) (*PaymentDispute, error) {
+	d, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if d.PayeeID != payeeID {
+		return nil, errors.New(ErrDisputeForbidden)
+	}
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		return transitionDispute(tx, d, []DisputeStatus{DisputeStatusOpen}, map[string]any{
+			"status":           DisputeStatusRejected,
+			"creator_response": response,
+		}, orderStatusPtr(OrderStatusDisputed), orderStatusPtr(OrderStatusCompleted))
+	}); err != nil {
+		return nil, err
+	}
+	d.Status = DisputeStatusRejected
+	d.CreatorResponse = response
+
+	notifyDispute(ctx, d, disputeEventRejected)
+	return d, nil
+}
+
+// AppealDispute 买家对被拒绝的争议申请管理员仲裁,订单重新转为 DISPUTED
+func AppealDispute(ctx context.Context, payerID, disputeID uint64) (*PaymentDispute, error) {
+	d, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if d.PayerID != payerID {
+		return nil, errors.New(ErrDisputeForbidden)
+	}
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		return transitionDispute(tx, d, []DisputeStatus{DisputeStatusRejected}, map[string]any{
+			"status": DisputeStatusArbitrating,
+		}, orderStatusPtr(OrderStatusCompleted), orderStatusPtr(OrderStatusDisputed))
+	}); err != nil {
+		return nil, err
+	}
+	d.Status = DisputeStatusArbitrating
+
+	notifyDispute(ctx, d, disputeEventAppealed)
+	return d, nil
+}
+
+// AcceptDispute 创建者同意退款:作废 item 并经渠道全额退款
+func AcceptDispute(ctx context.Context, payeeID, disputeID uint64, response string) (*PaymentDispute, error) {
+	d, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if d.PayeeID != payeeID {
+		return nil, errors.New(ErrDisputeForbidden)
+	}
+	if err := resolveDisputeWithRefund(ctx, d, payeeID, []DisputeStatus{DisputeStatusOpen}, map[string]any{
+		"creator_response": response,
+	}); err != nil {
+		return nil, err
+	}
+	d.CreatorResponse = response
+	return d, nil
+}
+
+// ArbitrateDispute 管理员仲裁:refund=true 时退款并作废 item,否则关闭争议并恢复订单
+func ArbitrateDispute(ctx context.Context, adminID, disputeID uint64, refund bool, note string) (*PaymentDispute, error) {
+	d, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if refund {
+		if err := resolveDisputeWithRefund(ctx, d, adminID, pendingDisputeStatuses, map[string]any{
+			"arbitration_note": note,
+		}); err != nil {
+			return nil, err
+		}
+		d.ArbitrationNote = note
+		return d, nil
+	}
+
+	now := time.Now()
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{
+			"status":           DisputeStatusClosed,
+			"arbitration_note": note,
+			"resolved_by":      adminID,
+			"resolved_at":      &now,
+		}
+		if d.Status == DisputeStatusRejected {
+			return transitionDispute(tx, d, []DisputeStatus{DisputeStatusRejected}, updates, nil, nil)
+		}
+		return transitionDispute(tx, d, []DisputeStatus{DisputeStatusOpen, DisputeStatusArbitrating}, updates,
+			orderStatusPtr(OrderStatusDisputed), orderStatusPtr(OrderStatusCompleted))
+	}); err != nil {
+		return nil, err
+	}
+	d.Status = DisputeStatusClosed
+	d.ArbitrationNote = note
+	d.ResolvedBy = &adminID
+	d.ResolvedAt = &now
+
+	notifyDispute(ctx, d, disputeEventClosed)
+	return d, nil
+}
+
+// resolveDisputeWithRefund 先 CAS 锁定争议为 REFUNDED,再作废 item 并退款;作废失败时恢复争议状态。
+// 已被驳回的争议订单处于 COMPLETED,锁定时一并置回 DISPUTED 以统一退款路径。
+// 退款失败时订单转为 REFUNDING,由重试任务继续退款。
+func resolveDisputeWithRefund(ctx context.Context, d *PaymentDispute, operatorID uint64, from []DisputeStatus, extra map[string]any) error {
+	previous := d.Status
+	now := time.Now()
+	updates := map[string]any{
+		"status":      DisputeStatusRefunded,
+		"resolved_by": operatorID,
+		"resolved_at": &now,
+	}
+	for k, v := range extra {
+		updates[k] = v
+	}
+	var orderFrom, orderTo *OrderStatus
+	if previous == DisputeStatusRejected {
+		orderFrom, orderTo = orderStatusPtr(OrderStatusCompleted), orderStatusPtr(OrderStatusDisputed)
+	}
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		return transitionDispute(tx, d, from, updates, orderFrom, orderTo)
+	}); err != nil {
+		return err
+	}
+
+	var order PaymentOrder
+	if err := db.DB(ctx).Where("out_trade_no = ? AND status = ?", d.OutTradeNo, OrderStatusDisputed).First(&order).Error; err != nil {
+		restoreDisputeStatus(ctx, d, previous)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(ErrDisputeStateChanged)
+		}
+		return err
+	}
+	// 项目可能已被下架,按主键读取而不限制状态
+	var p project.Project
+	if err := db.DB(ctx).Where("id = ?", d.ProjectID).First(&p).Error; err != nil {
+		restoreDisputeStatus(ctx, d, previous)
+		return err
+	}
+	revocation, err := p.RevokeItem(ctx, d.ItemID, operatorID, &project.RevokeItemRequest{
+		Action: project.RevokeActionRelease,
+		Reason: truncateRuneLen("买家争议退款: "+d.Reason, 255),
+		Refund: true,
+	}, nil)
+	if err != nil {
+		restoreDisputeStatus(ctx, d, previous)
+		return err
+	}
+
+	refundErr := refundRevokedOrder(ctx, &order, OrderStatusDisputed)
+	if err := revocation.MarkRevocationRefunded(db.DB(ctx), order.OutTradeNo, refundErr == nil); err != nil {
+		logger.ErrorF(ctx, "payment dispute %d: failed to record refund of order %s: %v", d.ID, order.OutTradeNo, err)
+	}
+	if err := db.DB(ctx).Model(&PaymentDispute{}).Where("id = ?", d.ID).Updates(map[string]any{
+		"refund_succeeded": refundErr == nil,
+		"revocation_id":    revocation.ID,
+	}).Error; err != nil {
+		logger.ErrorF(ctx, "payment dispute %d: failed to record resolution: %v", d.ID, err)
+	}
+	if refundErr != nil {
+		logger.WarnF(ctx, "payment dispute %d: refund of order %s deferred to retry task: %v", d.ID, order.OutTradeNo, refundErr)
+	}
+
+	d.Status = DisputeStatusRefunded
+	d.ResolvedBy = &operatorID
+	d.ResolvedAt = &now
+	d.RefundSucceeded = refundErr == nil
+	d.RevocationID = &revocation.ID
+
+	notifyDispute(ctx, d, disputeEventRefunded)
+	return nil
+}
+
+// restoreDisputeStatus 作废失败时把争议与订单恢复为锁定前的状态
+func restoreDisputeStatus(ctx context.Context, d *PaymentDispute, previous DisputeStatus) {
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&PaymentDispute{}).
+			Where("id = ? AND status = ?", d.ID, DisputeStatusRefunded).
+			Updates(map[string]any{"status": previous, "resolved_by": nil, "resolved_at": nil}).Error; err != nil {
+			return err
+		}
+		if previous != DisputeStatusRejected {
+			return nil
+		}
+		return tx.Model(&PaymentOrder{}).
+			Where("out_trade_no = ? AND status = ?", d.OutTradeNo, OrderStatusDisputed).
+			Update("status", OrderStatusCompleted).Error
+	}); err != nil {
+		logger.ErrorF(ctx, "payment dispute %d: failed to restore status: %v", d.ID, err)
+	}
+}
+
+// ListDisputes 分页查询争议;payerID/payeeID 为 0 表示不限,status 为 nil 表示不限状态
+func ListDisputes(ctx context.Context, payerID, payeeID uint64, status *DisputeStatus, offset, limit int) (int64, []PaymentDispute, error) {
+	query := db.DB(ctx).Model(&PaymentDispute{})
+	if payerID > 0 {
+		query = query.Where("payer_id = ?", payerID)
+	}
+	if payeeID > 0 {
+		query = query.Where("payee_id = ?", payeeID)
+	}
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	var disputes []PaymentDispute
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&disputes).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, disputes, nil
+}
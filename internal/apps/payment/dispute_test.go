@@ -0,0 +1,281 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/db"
+)
+
+// completedOrder 创建一笔已付款并发放完成的订单
+func completedOrder(t *testing.T, f *paymentFixture) *PaymentOrder {
+	t.Helper()
+	f.addItems(t, 1)
+	init := f.initiate(t)
+	f.pay(t, init.OutTradeNo)
+	order := loadOrder(t, init.OutTradeNo)
+	if order.Status != OrderStatusCompleted {
+		t.Fatalf("order should be completed, got status %d", order.Status)
+	}
+	return order
+}
+
+func loadDispute(t *testing.T, id uint64) *PaymentDispute {
+	t.Helper()
+	d, err := getDispute(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func loadItem(t *testing.T, itemID uint64) *project.ProjectItem {
+	t.Helper()
+	var item project.ProjectItem
+	if err := item.Exact(db.DB(context.Background()), itemID); err != nil {
+		t.Fatal(err)
+	}
+	return &item
+}
+
+// assertDisputeState 校验争议与订单的当前状态
+func assertDisputeState(t *testing.T, d *PaymentDispute, want DisputeStatus, wantOrder OrderStatus) {
+	t.Helper()
+	if got := loadDispute(t, d.ID).Status; got != want {
+		t.Fatalf("dispute status = %d, want %d", got, want)
+	}
+	if got := loadOrder(t, d.OutTradeNo).Status; got != wantOrder {
+		t.Fatalf("order status = %d, want %d", got, wantOrder)
+	}
+}
+
+func TestOpenDispute(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+
+	if _, err := OpenDispute(ctx, f.creator.ID, order.OutTradeNo, "not mine"); err == nil || err.Error() != ErrOrderNotFound {
+		t.Fatalf("non-payer should not open dispute, got %v", err)
+	}
+	d, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "code does not work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDisputeState(t, d, DisputeStatusOpen, OrderStatusDisputed)
+	if d.PayeeID != f.creator.ID || d.ItemID != order.ItemID {
+		t.Fatalf("unexpected dispute %+v", d)
+	}
+	if _, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "again"); err == nil || err.Error() != ErrDisputeOrderNotCompleted {
+		t.Fatalf("disputed order should not be disputed again, got %v", err)
+	}
+
+	// 驳回后订单恢复为 COMPLETED,但每笔订单至多一条争议
+	if _, err := RejectDispute(ctx, f.creator.ID, d.ID, "works for me"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "again"); err == nil || err.Error() != ErrDisputeExists {
+		t.Fatalf("second dispute should be rejected, got %v", err)
+	}
+}
+
+func TestOpenDisputeWindowClosed(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+	if err := db.DB(ctx).Model(&PaymentOrder{}).Where("out_trade_no = ?", order.OutTradeNo).
+		Update("paid_at", time.Now().Add(-disputeWindow()-time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "late"); err == nil || err.Error() != ErrDisputeWindowClosed {
+		t.Fatalf("expected window closed, got %v", err)
+	}
+}
+
+func TestAcceptDisputeRefunds(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+	d, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "code does not work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := AcceptDispute(ctx, f.payer.ID, d.ID, "ok"); err == nil || err.Error() != ErrDisputeForbidden {
+		t.Fatalf("payer should not accept own dispute, got %v", err)
+	}
+	d, err = AcceptDispute(ctx, f.creator.ID, d.ID, "sorry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDisputeState(t, d, DisputeStatusRefunded, OrderStatusRefunded)
+	stored := loadDispute(t, d.ID)
+	if !stored.RefundSucceeded || stored.RevocationID == nil || stored.ResolvedBy == nil || *stored.ResolvedBy != f.creator.ID {
+		t.Fatalf("unexpected resolution %+v", stored)
+	}
+	if !fakeRefunded(order.OutTradeNo) {
+		t.Fatal("provider should have refunded the order")
+	}
+	if loadItem(t, order.ItemID).RevokedAt == nil {
+		t.Fatal("disputed item should be revoked")
+	}
+
+	if _, err := RejectDispute(ctx, f.creator.ID, d.ID, "too late"); err == nil || err.Error() != ErrDisputeStateChanged {
+		t.Fatalf("resolved dispute should not be rejected, got %v", err)
+	}
+}
+
+func TestRejectAppealAndCloseDispute(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+	d, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "code does not work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RejectDispute(ctx, f.payer.ID, d.ID, "no"); err == nil || err.Error() != ErrDisputeForbidden {
+		t.Fatalf("payer should not reject, got %v", err)
+	}
+	if _, err := RejectDispute(ctx, f.creator.ID, d.ID, "works for me"); err != nil {
+		t.Fatal(err)
+	}
+	assertDisputeState(t, d, DisputeStatusRejected, OrderStatusCompleted)
+
+	if _, err := AppealDispute(ctx, f.creator.ID, d.ID); err == nil || err.Error() != ErrDisputeForbidden {
+		t.Fatalf("creator should not appeal, got %v", err)
+	}
+	if _, err := AppealDispute(ctx, f.payer.ID, d.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertDisputeState(t, d, DisputeStatusArbitrating, OrderStatusDisputed)
+	if _, err := AcceptDispute(ctx, f.creator.ID, d.ID, "ok"); err == nil || err.Error() != ErrDisputeStateChanged {
+		t.Fatalf("creator should not accept an arbitrating dispute, got %v", err)
+	}
+
+	const adminID = 99
+	closed, err := ArbitrateDispute(ctx, adminID, d.ID, false, "item delivered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDisputeState(t, closed, DisputeStatusClosed, OrderStatusCompleted)
+	if fakeRefunded(order.OutTradeNo) || loadItem(t, order.ItemID).RevokedAt != nil {
+		t.Fatal("closed dispute should not refund or revoke")
+	}
+}
+
+func TestArbitrateRejectedDisputeRefunds(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+	d, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "code does not work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RejectDispute(ctx, f.creator.ID, d.ID, "works for me"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 驳回状态下订单为 COMPLETED,仲裁退款时先置回 DISPUTED 再统一退款
+	if _, err := ArbitrateDispute(ctx, 99, d.ID, true, "refund"); err != nil {
+		t.Fatal(err)
+	}
+	assertDisputeState(t, d, DisputeStatusRefunded, OrderStatusRefunded)
+	if !fakeRefunded(order.OutTradeNo) {
+		t.Fatal("provider should have refunded the order")
+	}
+}
+
+func TestDisputeRefundRestoresOnRevokeFailure(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+	d, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "code does not work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RejectDispute(ctx, f.creator.ID, d.ID, "works for me"); err != nil {
+		t.Fatal(err)
+	}
+	// item 已在争议流程之外被作废,仲裁退款无法再作废
+	if _, err := f.project.RevokeItem(ctx, order.ItemID, f.creator.ID, &project.RevokeItemRequest{
+		Action:             project.RevokeActionReplace,
+		ReplacementContent: "new-code",
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ArbitrateDispute(ctx, 99, d.ID, true, "refund"); err == nil || err.Error() != project.ItemAlreadyRevoked {
+		t.Fatalf("expected item already revoked, got %v", err)
+	}
+	assertDisputeState(t, d, DisputeStatusRejected, OrderStatusCompleted)
+	stored := loadDispute(t, d.ID)
+	if stored.ResolvedBy != nil || stored.ResolvedAt != nil {
+		t.Fatal("restored dispute should not keep resolution")
+	}
+	if fakeRefunded(order.OutTradeNo) {
+		t.Fatal("failed resolution should not refund")
+	}
+}
+
+func TestHasPendingDispute(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+
+	pending := func() bool {
+		t.Helper()
+		ok, err := hasPendingDispute(db.DB(ctx), f.project.ID, order.ItemID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if pending() {
+		t.Fatal("item without dispute should not be pending")
+	}
+	d, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "code does not work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pending() {
+		t.Fatal("open dispute should block revocation")
+	}
+	if _, err := RejectDispute(ctx, f.creator.ID, d.ID, "works for me"); err != nil {
+		t.Fatal(err)
+	}
+	if !pending() {
+		t.Fatal("rejected dispute can still be appealed and should block revocation")
+	}
+	if _, err := ArbitrateDispute(ctx, 99, d.ID, false, "closed"); err != nil {
+		t.Fatal(err)
+	}
+	if pending() {
+		t.Fatal("closed dispute should not block revocation")
+	}
+}
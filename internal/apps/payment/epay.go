@@ -37,85 +37,180 @@ import (
 	"github.com/linux-do/cdk/internal/utils"
 )
 
-// submitURL 构造 /epay/pay/submit.php 的完整 GET 跳转 URL,由浏览器直接访问。
+// ProviderEasyPay 易支付(含 LDC credit.linux.do)兼容渠道
+const ProviderEasyPay = "epay"
+
+// act=order 返回的订单状态
+const (
+	epayOrderStatusPaid     = "1"
+	epayOrderStatusRefunded = "2"
+)
+
+// epayRejectedError api.php 返回 code!=1,区别于网络或解析错误
+type epayRejectedError struct {
+	act string
+	msg string
+}
+
+func (e *epayRejectedError) Error() string {
+	return fmt.Sprintf("%s rejected: %s", e.act, e.msg)
+}
+
+// epayOrderNotFoundMessages act=order 查无订单时网关返回的提示,鉴权失败等其他拒绝不在此列
+var epayOrderNotFoundMessages = []string{"不存在", "not exist", "not found"}
+
+// orderNotFound 判断网关拒绝是否因为查无订单
+func (e *epayRejectedError) orderNotFound() bool {
+	msg := strings.ToLower(e.msg)
+	for _, keyword := range epayOrderNotFoundMessages {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	RegisterProvider(ProviderEasyPay, func(creds ProviderCredentials) PaymentProvider {
+		return &easyPayProvider{creds: creds}
+	})
+}
+
+// easyPayProvider 易支付协议实现:submit.php 跳转支付,api.php 查询与退款,MD5 签名
+type easyPayProvider struct {
+	creds ProviderCredentials
+}
+
+func (e *easyPayProvider) Name() string { return ProviderEasyPay }
+
+func (e *easyPayProvider) apiURL(path string) string {
+	return strings.TrimRight(config.Config.Payment.ApiUrl, "/") + path
+}
+
+// PayURL 构造 /epay/pay/submit.php 的完整 GET 跳转 URL,由浏览器直接访问。
 // 不在后端跟随 302,以确保 credit.linux.do/paying 的付款会话对用户浏览器可见。
-func submitURL(clientID, secret, name, money, outTradeNo, notifyURL, returnURL string) string {
+func (e *easyPayProvider) PayURL(_ context.Context, req *PayRequest) (string, error) {
 	params := map[string]string{
-		"pid":          clientID,
+		"pid":          e.creds.ClientID,
 		"type":         "epay",
-		"name":         name,
-		"money":        money,
-		"out_trade_no": outTradeNo,
-		"notify_url":   notifyURL,
-		"return_url":   returnURL,
+		"name":         req.Name,
+		"money":        req.Money,
+		"out_trade_no": req.OutTradeNo,
+		"notify_url":   req.NotifyURL,
+		"return_url":   req.ReturnURL,
 		"sign_type":    "MD5",
 	}
-	params["sign"] = BuildSign(params, secret)
+	params["sign"] = BuildSign(params, e.creds.ClientSecret)
 	values := url.Values{}
 	for k, v := range params {
 		values.Set(k, v)
 	}
-	return strings.TrimRight(config.Config.Payment.ApiUrl, "/") + "/pay/submit.php?" + values.Encode()
-}
-
-// callbackNotifyURL 返回异步通知地址,用于下单参数与前端展示。
-func callbackNotifyURL() string {
-	return strings.TrimRight(config.Config.Payment.NotifyBaseURL, "/") + "/api/v1/payment/notify"
+	return e.apiURL("/pay/submit.php?") + values.Encode(), nil
 }
 
-// callbackReturnURL 返回同步回跳地址。
-func callbackReturnURL(projectID string) string {
-	baseURL := strings.TrimRight(config.Config.Payment.RedirectBaseURL, "/")
-	if projectID == "" {
-		return baseURL + "/received"
+// VerifyNotify 校验 MD5 签名与商户号,trade_status=TRADE_SUCCESS 视为已支付
+func (e *easyPayProvider) VerifyNotify(_ context.Context, params map[string]string) (*NotifyResult, error) {
+	if !VerifySign(params, e.creds.ClientSecret) {
+		return nil, errors.New("sign mismatch")
 	}
-	return baseURL + "/receive/" + projectID
+	if params["pid"] != e.creds.ClientID {
+		return nil, errors.New("pid mismatch")
+	}
+	return &NotifyResult{
+		OutTradeNo: params["out_trade_no"],
+		TradeNo:    params["trade_no"],
+		Money:      params["money"],
+		Paid:       params["trade_status"] == "TRADE_SUCCESS",
+	}, nil
 }
 
-// refundResponse 易支付退款接口响应
-type refundResponse struct {
-	Code int    `json:"code"`
-	Msg  string `json:"msg"`
+// epayAPIResponse 易支付 api.php 的通用响应,status=1 表示已支付
+type epayAPIResponse struct {
+	Code       int         `json:"code"`
+	Msg        string      `json:"msg"`
+	TradeNo    string      `json:"trade_no"`
+	OutTradeNo string      `json:"out_trade_no"`
+	Money      json.Number `json:"money"`
+	Status     json.Number `json:"status"`
 }
 
-// doEpayRefund 调用易支付兼容 /api.php 完成全额退款。
-// 参考文档:pid+key+trade_no+money 作为 form 参数,返回 {"code":1,"msg":"退款成功"}。
-// 不需要 sign,凭 key 直接鉴权。
-func doEpayRefund(ctx context.Context, clientID, clientSecret, tradeNo, money string) error {
-	form := url.Values{}
-	form.Set("act", "refund")
-	form.Set("pid", clientID)
-	form.Set("key", clientSecret)
-	form.Set("trade_no", tradeNo)
-	form.Set("money", money)
-
-	endpoint := strings.TrimRight(config.Config.Payment.ApiUrl, "/") + "/api.php"
+// callAPI 以 pid+key 鉴权调用 /api.php,code!=1 视为失败
+func (e *easyPayProvider) callAPI(ctx context.Context, form url.Values) (*epayAPIResponse, error) {
+	form.Set("pid", e.creds.ClientID)
+	form.Set("key", e.creds.ClientSecret)
 
 	resp, err := utils.Request(
 		ctx,
 		"POST",
-		endpoint,
+		e.apiURL("/api.php"),
 		strings.NewReader(form.Encode()),
 		map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
 		nil,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var rr refundResponse
-	if err := json.Unmarshal(body, &rr); err != nil {
-		return fmt.Errorf("parse refund response: %w (raw=%s)", err, string(body))
+	var r epayAPIResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("parse %s response: %w (raw=%s)", form.Get("act"), err, string(body))
+	}
+	if r.Code != 1 {
+		return nil, &epayRejectedError{act: form.Get("act"), msg: r.Msg}
+	}
+	return &r, nil
+}
+
+// QueryOrder 调用 act=order 查询订单,仅网关明确返回订单不存在时视为查无订单;
+// 鉴权失败等其他拒绝原样返回,由调用方按查询失败处理
+func (e *easyPayProvider) QueryOrder(ctx context.Context, outTradeNo string) (*OrderQueryResult, error) {
+	form := url.Values{}
+	form.Set("act", "order")
+	form.Set("out_trade_no", outTradeNo)
+	r, err := e.callAPI(ctx, form)
+	var rejected *epayRejectedError
+	if errors.As(err, &rejected) && rejected.orderNotFound() {
+		return nil, fmt.Errorf("%w: %v", ErrProviderOrderUnknown, err)
 	}
-	if rr.Code != 1 {
-		return fmt.Errorf("refund rejected: %s", rr.Msg)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &OrderQueryResult{
+		OutTradeNo: r.OutTradeNo,
+		TradeNo:    r.TradeNo,
+		Money:      r.Money.String(),
+		Paid:       r.Status.String() == epayOrderStatusPaid,
+		Refunded:   r.Status.String() == epayOrderStatusRefunded,
+	}, nil
+}
+
+// Refund 调用 act=refund 完成全额退款,返回 {"code":1,"msg":"退款成功"}
+func (e *easyPayProvider) Refund(ctx context.Context, tradeNo, money string) error {
+	form := url.Values{}
+	form.Set("act", "refund")
+	form.Set("trade_no", tradeNo)
+	form.Set("money", money)
+	_, err := e.callAPI(ctx, form)
+	return err
+}
+
+// callbackNotifyURL 返回异步通知地址,用于下单参数与前端展示。
+func callbackNotifyURL() string {
+	return strings.TrimRight(config.Config.Payment.NotifyBaseURL, "/") + "/api/v1/payment/notify"
+}
+
+// callbackReturnURL 返回同步回跳地址。
+func callbackReturnURL(projectID string) string {
+	baseURL := strings.TrimRight(config.Config.Payment.RedirectBaseURL, "/")
+	if projectID == "" {
+		return baseURL + "/received"
+	}
+	return baseURL + "/receive/" + projectID
 }
 
 // extractQueryMap 把 gin 的 query 拉平成 map[string]string,便于签名校验
@@ -131,8 +226,6 @@ func extractQueryMap(values url.Values) map[string]string {
 
 // validateEncryptionKeyConfigured 检查加密密钥是否配置
 func validateEncryptionKeyConfigured() error {
-	if config.Config.Payment.ConfigEncryptionKey == "" {
-		return errors.New(ErrEncryptionKeyMissing)
-	}
-	return nil
+	_, err := currentKeyring()
+	return err
 }
@@ -25,17 +25,41 @@
 package payment
 
 const (
-	ErrPaymentDisabled          = "支付功能未启用"
-	ErrInvalidAmount            = "金额必须大于 0 且最多 2 位小数"
-	ErrPriceRequiresOneForEach  = "仅一码一用分发支持设置金额"
-	ErrCreatorNotConfigured     = "项目创建者尚未配置支付凭据,无法发起支付"
-	ErrPendingOrderExists       = "当前项目存在进行中或已完成订单,不可重复创建"
-	ErrPaymentConfigNotFound    = "尚未配置支付凭据"
-	ErrEncryptionKeyMissing     = "服务端未配置支付密钥加密密钥"
-	ErrInvalidClientCredentials = "clientID 与 clientSecret 不能为空"
-	ErrOrderNotFound            = "订单不存在"
-	ErrOrderExpired             = "订单已过期"
-	ErrCannotDeleteHasActive    = "存在未结束的付费项目,无法删除支付配置"
-	ErrInvalidPriceDecimals     = "金额最多保留 2 位小数"
-	ErrPriceTooLarge            = "金额超出允许范围"
+	ErrPaymentDisabled             = "支付功能未启用"
+	ErrInvalidAmount               = "金额必须大于 0 且最多 2 位小数"
+	ErrCreatorNotConfigured        = "项目创建者尚未配置支付凭据,无法发起支付"
+	ErrPendingOrderExists          = "当前项目存在进行中或已完成订单,不可重复创建"
+	ErrClaimQuotaReached           = "已达到该项目的领取上限"
+	ErrPaymentConfigNotFound       = "尚未配置支付凭据"
+	ErrEncryptionKeyMissing        = "服务端未配置支付密钥加密密钥"
+	ErrEncryptionKeyUnknown        = "密文使用的加密密钥未配置"
+	ErrInvalidEncryptionKeyID      = "加密密钥 ID 不合法"
+	ErrInvalidCiphertext           = "密文格式不合法"
+	ErrProviderNotSupported        = "不支持的支付渠道"
+	ErrRefundingOrderNotFound      = "退款中的订单不存在"
+	ErrDisputeNotFound             = "争议不存在"
+	ErrDisputeExists               = "该订单已发起过争议"
+	ErrDisputeOrderNotCompleted    = "仅已完成的订单可以发起争议"
+	ErrDisputeWindowClosed         = "已超过可发起争议的期限"
+	ErrDisputeForbidden            = "无权处理该争议"
+	ErrDisputeStateChanged         = "争议状态已变化,请刷新后重试"
+	ErrItemDisputePending          = "该 item 存在未结束的争议,请先处理争议"
+	ErrInvalidTimeRange            = "结束时间必须晚于开始时间"
+	ErrCouponInvalid               = "优惠码无效、已过期或已用完"
+	ErrCouponQuoteTooFrequent      = "优惠码查询太频繁，请稍后再试"
+	ErrCouponCodeExists            = "该优惠码已存在"
+	ErrInvalidCouponCode           = "优惠码须为 4-32 位字母、数字、下划线或短横线"
+	ErrPriceRuleNotFound           = "优惠规则不存在"
+	ErrPriceRuleRequiresPaid       = "仅付费项目可以设置优惠"
+	ErrPriceRuleNotCheaper         = "优惠价必须低于项目原价"
+	ErrTooManyPriceRules           = "优惠规则数量已达上限"
+	ErrEarlyBirdRequiresEndTime    = "早鸟价必须设置结束时间"
+	ErrTrustLevelRuleRequiresLevel = "信任等级折扣必须设置最低信任等级"
+	ErrInvalidClientCredentials    = "clientID 与 clientSecret 不能为空"
+	ErrOrderNotFound               = "订单不存在"
+	ErrOrderExpired                = "订单已过期"
+	ErrOrderStatusChanged          = "订单状态已变化,请刷新后重试"
+	ErrCannotDeleteHasActive       = "存在未结束的付费项目,无法删除支付配置"
+	ErrInvalidPriceDecimals        = "金额最多保留 2 位小数"
+	ErrPriceTooLarge               = "金额超出允许范围"
 )
@@ -0,0 +1,154 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/notification"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"github.com/shopspring/decimal"
+)
+
+// 测试中总是注册 fake 渠道,运行时仍需显式开启 payment.enable_fake_provider
+func init() {
+	registerFakeProvider()
+}
+
+// paymentFixture 一个已配置 fake 渠道的付费项目及其买家
+type paymentFixture struct {
+	creator oauth.User
+	payer   oauth.User
+	project *project.Project
+	creds   ProviderCredentials
+}
+
+// setupPayment 准备 sqlite 与 miniredis,创建创建者、买家与单价 1.50 的付费项目
+func setupPayment(t *testing.T) *paymentFixture {
+	t.Helper()
+	dbtest.Setup(t,
+		&oauth.User{},
+		&project.Project{}, &project.ProjectItem{}, &project.ProjectRelease{}, &project.ProjectItemRevocation{},
+		&UserPaymentConfig{}, &PaymentOrder{}, &PaymentPriceRule{}, &PaymentDispute{}, &PaymentReconcileMismatch{},
+		&notification.Notification{},
+	)
+
+	old := config.Config.Payment
+	t.Cleanup(func() { config.Config.Payment = old })
+	config.Config.Payment.Enabled = true
+	config.Config.Payment.ConfigEncryptionKey = "0123456789abcdef0123456789abcdef"
+	config.Config.Payment.ConfigEncryptionKeys = nil
+	config.Config.Payment.ActiveEncryptionKeyID = ""
+
+	ctx := context.Background()
+	f := &paymentFixture{
+		creator: oauth.User{ID: 1, Username: "creator", TrustLevel: oauth.TrustLevel(2)},
+		payer:   oauth.User{ID: 2, Username: "payer", TrustLevel: oauth.TrustLevel(2)},
+		creds:   ProviderCredentials{ClientID: "1001", ClientSecret: "fake-secret"},
+	}
+	if err := db.DB(ctx).Create([]*oauth.User{&f.creator, &f.payer}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveUserPaymentConfig(ctx, f.creator.ID, ProviderFake, f.creds.ClientID, f.creds.ClientSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	f.project = &project.Project{
+		ID:               "paid-project",
+		Name:             "paid",
+		CreatorID:        f.creator.ID,
+		DistributionType: project.DistributionTypeOneForEach,
+		StartTime:        now.Add(-time.Hour),
+		EndTime:          now.Add(time.Hour),
+		AllowSameIP:      true,
+		Price:            decimal.RequireFromString("1.50"),
+		ClaimQuota:       1,
+	}
+	if err := db.DB(ctx).Create(f.project).Error; err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// addItems 创建 n 个 item 并推入库存队列
+func (f *paymentFixture) addItems(t *testing.T, n int) []uint64 {
+	t.Helper()
+	ctx := context.Background()
+	ids := make([]uint64, n)
+	for i := range n {
+		item := project.ProjectItem{ProjectID: f.project.ID, Content: "code-" + strconv.Itoa(i)}
+		if err := db.DB(ctx).Create(&item).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Redis.RPush(ctx, f.project.ItemsKey(), item.ID).Err(); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = item.ID
+	}
+	if err := db.DB(ctx).Model(f.project).Update("total_items", int64(n)).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+// initiate 以买家身份发起支付
+func (f *paymentFixture) initiate(t *testing.T) *PaymentInitiation {
+	t.Helper()
+	init, err := InitiatePayment(context.Background(), f.project, &f.payer, "127.0.0.1", "")
+	if err != nil {
+		t.Fatalf("initiate payment: %v", err)
+	}
+	return init
+}
+
+// pay 在 fake 渠道完成付款并投递回调
+func (f *paymentFixture) pay(t *testing.T, outTradeNo string) {
+	t.Helper()
+	params, err := FakePay(f.creds, outTradeNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, reason := HandleNotify(context.Background(), params); !ok {
+		t.Fatalf("handle notify: %s", reason)
+	}
+}
+
+// loadOrder 重新读取订单
+func loadOrder(t *testing.T, outTradeNo string) *PaymentOrder {
+	t.Helper()
+	var order PaymentOrder
+	if err := db.DB(context.Background()).Where("out_trade_no = ?", outTradeNo).First(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &order
+}
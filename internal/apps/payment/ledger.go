@@ -0,0 +1,213 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/utils"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ledgerCSVHeader 订单/销售 CSV 导出表头
+var ledgerCSVHeader = []string{"out_trade_no", "trade_no", "project_id", "project_name", "payer", "payee", "amount", "status", "paid_at", "refunded_at", "created_at"}
+
+// orderStatusNames 导出与展示使用的订单状态名
+var orderStatusNames = map[OrderStatus]string{
+	OrderStatusPending:   "pending",
+	OrderStatusPaid:      "paid",
+	OrderStatusCompleted: "completed",
+	OrderStatusRefunding: "refunding",
+	OrderStatusRefunded:  "refunded",
+	OrderStatusFailed:    "failed",
+	OrderStatusDisputed:  "disputed",
+}
+
+// revenueOrderStatuses 计入收入的订单状态,争议中的订单暂按已收款计
+var revenueOrderStatuses = []OrderStatus{OrderStatusCompleted, OrderStatusDisputed}
+
+// refundOrderStatuses 计入退款金额的订单状态
+var refundOrderStatuses = []OrderStatus{OrderStatusRefunding, OrderStatusRefunded}
+
+// LedgerFilter 订单/销售查询条件,PayerID 与 PayeeID 二选一
+type LedgerFilter struct {
+	PayerID   uint64
+	PayeeID   uint64
+	ProjectID string
+	Status    *OrderStatus
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// LedgerRow 订单列表的一行
+type LedgerRow struct {
+	OutTradeNo    string          `json:"out_trade_no"`
+	TradeNo       string          `json:"trade_no"`
+	ProjectID     string          `json:"project_id"`
+	ProjectName   string          `json:"project_name"`
+	PayerUsername string          `json:"payer_username"`
+	PayeeUsername string          `json:"payee_username"`
+	Amount        decimal.Decimal `json:"amount"`
+	Status        OrderStatus     `json:"status"`
+	PaidAt        *time.Time      `json:"paid_at"`
+	RefundedAt    *time.Time      `json:"refunded_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// record 转为 CSV 行
+func (r *LedgerRow) record() []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.DateTime)
+	}
+	return []string{
+		r.OutTradeNo, r.TradeNo, r.ProjectID, r.ProjectName, r.PayerUsername, r.PayeeUsername,
+		moneyString(r.Amount), orderStatusNames[r.Status], formatTime(r.PaidAt), formatTime(r.RefundedAt),
+		r.CreatedAt.Format(time.DateTime),
+	}
+}
+
+// ProjectRevenue 单个项目的收入汇总
+type ProjectRevenue struct {
+	ProjectID      string          `json:"project_id"`
+	ProjectName    string          `json:"project_name"`
+	OrderCount     int64           `json:"order_count"`
+	Revenue        decimal.Decimal `json:"revenue"`
+	RefundCount    int64           `json:"refund_count"`
+	RefundedAmount decimal.Decimal `json:"refunded_amount"`
+}
+
+// apply 追加过滤条件,status 过滤仅用于明细,不用于收入汇总
+func (f *LedgerFilter) apply(query *gorm.DB, withStatus bool) *gorm.DB {
+	if f.PayerID > 0 {
+		query = query.Where("po.payer_id = ?", f.PayerID)
+	}
+	if f.PayeeID > 0 {
+		query = query.Where("po.payee_id = ?", f.PayeeID)
+	}
+	if f.ProjectID != "" {
+		query = query.Where("po.project_id = ?", f.ProjectID)
+	}
+	if withStatus && f.Status != nil {
+		query = query.Where("po.status = ?", *f.Status)
+	}
+	if f.StartTime != nil {
+		query = query.Where("po.created_at >= ?", *f.StartTime)
+	}
+	if f.EndTime != nil {
+		query = query.Where("po.created_at < ?", *f.EndTime)
+	}
+	return query
+}
+
+// ledgerQuery 构造订单明细查询
+func (f *LedgerFilter) ledgerQuery(ctx context.Context) *gorm.DB {
+	query := db.DB(ctx).
+		Table("payment_orders AS po").
+		Select(`po.out_trade_no, po.trade_no, po.project_id, COALESCE(p.name, '') AS project_name,
+			COALESCE(payer.username, '') AS payer_username, COALESCE(payee.username, '') AS payee_username,
+			po.amount, po.status, po.paid_at, po.refunded_at, po.created_at`).
+		Joins("LEFT JOIN projects p ON p.id = po.project_id").
+		Joins("LEFT JOIN users payer ON payer.id = po.payer_id").
+		Joins("LEFT JOIN users payee ON payee.id = po.payee_id")
+	return f.apply(query, true)
+}
+
+// ListLedger 分页查询订单明细,按创建时间倒序
+func ListLedger(ctx context.Context, f *LedgerFilter, offset, limit int) (int64, []LedgerRow, error) {
+	var total int64
+	if err := f.apply(db.DB(ctx).Table("payment_orders AS po"), true).Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	rows := make([]LedgerRow, 0, limit)
+	if err := f.ledgerQuery(ctx).
+		Order("po.created_at DESC, po.id DESC").
+		Offset(offset).
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, rows, nil
+}
+
+// SumRevenueByProject 按项目汇总收入与退款,忽略状态过滤
+func SumRevenueByProject(ctx context.Context, f *LedgerFilter) ([]ProjectRevenue, error) {
+	query := db.DB(ctx).
+		Table("payment_orders AS po").
+		Select(`po.project_id, COALESCE(MAX(p.name), '') AS project_name,
+			SUM(CASE WHEN po.status IN ? THEN 1 ELSE 0 END) AS order_count,
+			COALESCE(SUM(CASE WHEN po.status IN ? THEN po.amount ELSE 0 END), 0) AS revenue,
+			SUM(CASE WHEN po.status IN ? THEN 1 ELSE 0 END) AS refund_count,
+			COALESCE(SUM(CASE WHEN po.status IN ? THEN po.amount ELSE 0 END), 0) AS refunded_amount`,
+			revenueOrderStatuses, revenueOrderStatuses, refundOrderStatuses, refundOrderStatuses).
+		Joins("LEFT JOIN projects p ON p.id = po.project_id").
+		Group("po.project_id").
+		Order("revenue DESC")
+	var results []ProjectRevenue
+	if err := f.apply(query, false).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ExportLedgerCSV 逐行导出符合条件的全部订单
+func ExportLedgerCSV(ctx context.Context, f *LedgerFilter, w io.Writer) error {
+	// 写入 BOM 便于 Excel 正确识别 UTF-8
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ledgerCSVHeader); err != nil {
+		return err
+	}
+
+	rows, err := f.ledgerQuery(ctx).Order("po.created_at DESC, po.id DESC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row LedgerRow
+		if err := db.DB(ctx).ScanRows(rows, &row); err != nil {
+			return err
+		}
+		// 项目名与用户名由用户填写,转义以防表格软件将其作为公式执行
+		if err := cw.Write(utils.EscapeFormulaRecord(row.record())); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,65 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/linux-do/cdk/internal/db"
+)
+
+func TestExportLedgerCSVEscapesFormulas(t *testing.T) {
+	f := setupPayment(t)
+	order := completedOrder(t, f)
+	ctx := context.Background()
+	if err := db.DB(ctx).Model(f.project).Update("name", "=HYPERLINK(\"http://evil\")").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := ExportLedgerCSV(ctx, &LedgerFilter{PayeeID: f.creator.ID}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\uFEFF"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and one row, got %d records", len(records))
+	}
+	row := records[1]
+	if row[0] != order.OutTradeNo {
+		t.Fatalf("unexpected order %q", row[0])
+	}
+	if row[3] != "'=HYPERLINK(\"http://evil\")" {
+		t.Fatalf("project name should be escaped, got %q", row[3])
+	}
+	if row[6] != "1.50" || row[7] != "completed" {
+		t.Fatalf("unexpected amount/status %q/%q", row[6], row[7])
+	}
+}
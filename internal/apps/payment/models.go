@@ -35,6 +35,7 @@ import (
 //	PENDING(0)   -> PAID(1) -> COMPLETED(2)           // 正常路径
 //	                        -> REFUNDING(3) -> REFUNDED(4)  // 发放失败
 //	PENDING      -> FAILED(5)                         // 未付款超时 / 创建失败
+//	COMPLETED   <-> DISPUTED(6) -> REFUNDED / REFUNDING // 买家争议,驳回后回到 COMPLETED
 type OrderStatus int8
 
 const (
@@ -44,11 +45,30 @@ const (
 	OrderStatusRefunding OrderStatus = 3
 	OrderStatusRefunded  OrderStatus = 4
 	OrderStatusFailed    OrderStatus = 5
+	OrderStatusDisputed  OrderStatus = 6
 )
 
+// paidOrderStatuses 已成交(含退款与争议中)的订单状态,项目导出时关联其订单号
+var paidOrderStatuses = []OrderStatus{
+	OrderStatusCompleted,
+	OrderStatusRefunding,
+	OrderStatusRefunded,
+	OrderStatusDisputed,
+}
+
+// exportOrderStatuses 以 project.ExportItems 所需的类型返回 paidOrderStatuses
+func exportOrderStatuses() []int8 {
+	statuses := make([]int8, len(paidOrderStatuses))
+	for i, status := range paidOrderStatuses {
+		statuses[i] = int8(status)
+	}
+	return statuses
+}
+
 // UserPaymentConfig 用户的商户凭据(一对一绑定 User)
 type UserPaymentConfig struct {
 	UserID          uint64    `gorm:"primaryKey" json:"user_id"`
+	Provider        string    `gorm:"size:32;not null;default:'epay'" json:"provider"`
 	ClientID        string    `gorm:"size:64;not null" json:"client_id"`
 	ClientSecretEnc string    `gorm:"size:512;not null" json:"-"`
 	SecretLast4     string    `gorm:"size:8" json:"secret_last4"`
@@ -62,24 +82,37 @@ type UserPaymentConfig struct {
 //   - idx_project_payer_status (project_id, payer_id, status)：查询某用户在某项目的待支付订单
 //   - idx_payer_status         (payer_id, status)：按用户快速查询待支付订单
 //   - idx_status_expire        (status, expire_at)：清理任务扫描超时 PENDING 订单
+//   - idx_status_next_refund   (status, next_refund_at)：重试任务扫描到期的 REFUNDING 订单
+//   - idx_payee_status_created (payee_id, status, created_at)：收款方销售明细与收入汇总
+//
+// RefundAttempts/NextRefundAt/RefundEscalatedAt 记录 REFUNDING 订单的自动重试进度。
+// Amount 为定价引擎计算后的成交价,回调金额据此校验;OriginalAmount 为下单时的项目原价,
+// PriceRuleID/PriceRuleType 记录命中的优惠规则。
 type PaymentOrder struct {
-	ID            uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
-	OutTradeNo    string          `gorm:"size:64;uniqueIndex;not null" json:"out_trade_no"`
-	TradeNo       string          `gorm:"size:64;index" json:"trade_no"`
-	ProjectID     string          `gorm:"size:64;not null;index:idx_project_payer_status,priority:1" json:"project_id"`
-	ItemID        uint64          `gorm:"index;not null" json:"item_id"`
-	PayerID       uint64          `gorm:"not null;index:idx_project_payer_status,priority:2;index:idx_payer_status,priority:1" json:"payer_id"`
-	PayeeID       uint64          `gorm:"index;not null" json:"payee_id"`
-	PayeeClientID string          `gorm:"size:64" json:"payee_client_id"`
-	Amount        decimal.Decimal `gorm:"type:decimal(10,2);not null" json:"amount"`
-	Status        OrderStatus     `gorm:"default:0;index:idx_project_payer_status,priority:3;index:idx_payer_status,priority:2;index:idx_status_expire,priority:1" json:"status"`
-	PaidAt        *time.Time      `json:"paid_at"`
-	RefundedAt    *time.Time      `json:"refunded_at"`
-	FailReason    string          `gorm:"size:255" json:"fail_reason"`
-	ExpireAt      time.Time       `gorm:"index:idx_status_expire,priority:2" json:"expire_at"`
-	ClientIP      string          `gorm:"size:64" json:"client_ip"`
-	CreatedAt     time.Time       `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+	ID                uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	OutTradeNo        string          `gorm:"size:64;uniqueIndex;not null" json:"out_trade_no"`
+	TradeNo           string          `gorm:"size:64;index" json:"trade_no"`
+	ProjectID         string          `gorm:"size:64;not null;index:idx_project_payer_status,priority:1" json:"project_id"`
+	ItemID            uint64          `gorm:"index;not null" json:"item_id"`
+	PayerID           uint64          `gorm:"not null;index:idx_project_payer_status,priority:2;index:idx_payer_status,priority:1" json:"payer_id"`
+	PayeeID           uint64          `gorm:"not null;index:idx_payee_status_created,priority:1" json:"payee_id"`
+	PayeeClientID     string          `gorm:"size:64" json:"payee_client_id"`
+	Provider          string          `gorm:"size:32;not null;default:'epay'" json:"provider"`
+	Amount            decimal.Decimal `gorm:"type:decimal(10,2);not null" json:"amount"`
+	OriginalAmount    decimal.Decimal `gorm:"type:decimal(10,2);not null;default:0" json:"original_amount"`
+	PriceRuleID       *uint64         `json:"price_rule_id"`
+	PriceRuleType     PriceRuleType   `gorm:"size:16" json:"price_rule_type"`
+	Status            OrderStatus     `gorm:"default:0;index:idx_project_payer_status,priority:3;index:idx_payer_status,priority:2;index:idx_status_expire,priority:1;index:idx_status_next_refund,priority:1;index:idx_payee_status_created,priority:2" json:"status"`
+	PaidAt            *time.Time      `json:"paid_at"`
+	RefundedAt        *time.Time      `json:"refunded_at"`
+	FailReason        string          `gorm:"size:255" json:"fail_reason"`
+	RefundAttempts    int             `gorm:"default:0" json:"refund_attempts"`
+	NextRefundAt      *time.Time      `gorm:"index:idx_status_next_refund,priority:2" json:"next_refund_at"`
+	RefundEscalatedAt *time.Time      `json:"refund_escalated_at"`
+	ExpireAt          time.Time       `gorm:"index:idx_status_expire,priority:2" json:"expire_at"`
+	ClientIP          string          `gorm:"size:64" json:"client_ip"`
+	CreatedAt         time.Time       `gorm:"autoCreateTime;index;index:idx_payee_status_created,priority:3" json:"created_at"`
+	UpdatedAt         time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 // TableName 自定义表名
@@ -0,0 +1,95 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/linux-do/cdk/internal/apps/notification"
+	"github.com/linux-do/cdk/internal/db"
+	"gorm.io/gorm"
+)
+
+type disputeEvent string
+
+const (
+	disputeEventOpened   disputeEvent = "买家发起了退款争议"
+	disputeEventRejected disputeEvent = "创建者拒绝了退款争议"
+	disputeEventAppealed disputeEvent = "买家申请了管理员仲裁"
+	disputeEventRefunded disputeEvent = "争议已退款"
+	disputeEventClosed   disputeEvent = "争议已被管理员驳回"
+)
+
+// notifyDispute 通知争议双方,配置了 LinuxDo.ApiKey 时同时以 Discourse 站内信送达,
+// 双方填写的内容经转义后拼接
+func notifyDispute(ctx context.Context, d *PaymentDispute, event disputeEvent) {
+	title := fmt.Sprintf("CDK 订单 %s: %s", d.OutTradeNo, event)
+	var body strings.Builder
+	fmt.Fprintf(&body, "订单 `%s` 的退款争议状态已更新:**%s**。\n\n", d.OutTradeNo, event)
+	fmt.Fprintf(&body, "- 争议原因:%s\n", notification.EscapeMarkdown(d.Reason))
+	if d.CreatorResponse != "" {
+		fmt.Fprintf(&body, "- 创建者回复:%s\n", notification.EscapeMarkdown(d.CreatorResponse))
+	}
+	if d.ArbitrationNote != "" {
+		fmt.Fprintf(&body, "- 仲裁说明:%s\n", notification.EscapeMarkdown(d.ArbitrationNote))
+	}
+	if event == disputeEventRefunded && !d.RefundSucceeded {
+		body.WriteString("- 退款正在处理中,系统将自动重试\n")
+	}
+
+	notification.SendToUsers(ctx, db.DB(ctx), []uint64{d.PayerID, d.PayeeID}, notification.Notification{
+		Type:       notification.NotificationTypeDispute,
+		Title:      title,
+		Content:    body.String(),
+		ProjectID:  d.ProjectID,
+		OutTradeNo: d.OutTradeNo,
+	})
+}
+
+// notifyOrderRefunded 通知买家订单已退款
+func notifyOrderRefunded(ctx context.Context, tx *gorm.DB, order *PaymentOrder) {
+	notification.Send(ctx, tx, &notification.Notification{
+		UserID:     order.PayerID,
+		Type:       notification.NotificationTypeOrderRefunded,
+		Title:      fmt.Sprintf("CDK 订单 %s 已退款", order.OutTradeNo),
+		Content:    fmt.Sprintf("订单 `%s` 已退款 %s 元,款项将原路退回。", order.OutTradeNo, moneyString(order.Amount)),
+		ProjectID:  order.ProjectID,
+		OutTradeNo: order.OutTradeNo,
+	})
+}
+
+// notifyItemSold 通知创建者付费项目售出
+func notifyItemSold(ctx context.Context, order *PaymentOrder) {
+	notification.Send(ctx, db.DB(ctx), &notification.Notification{
+		UserID:     order.PayeeID,
+		Type:       notification.NotificationTypeItemSold,
+		Title:      fmt.Sprintf("CDK 订单 %s 已售出", order.OutTradeNo),
+		Content:    fmt.Sprintf("项目 `%s` 售出一件物品,实收 %s 元。", order.ProjectID, moneyString(order.Amount)),
+		ProjectID:  order.ProjectID,
+		OutTradeNo: order.OutTradeNo,
+	})
+}
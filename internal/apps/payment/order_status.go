@@ -0,0 +1,210 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// orderEventChannelPrefix 订单离开 PENDING 后发布通知的 Redis 频道前缀,后接订单号
+	orderEventChannelPrefix = "payment:order:"
+	// orderWaitPollInterval 等待期间兜底轮询数据库的间隔,防止多实例下漏收通知
+	orderWaitPollInterval = 3 * time.Second
+)
+
+// OrderStatusView 买家视角的订单状态,完成后附带领取到的内容
+type OrderStatusView struct {
+	OutTradeNo string      `json:"out_trade_no"`
+	ProjectID  string      `json:"project_id"`
+	Amount     string      `json:"amount"`
+	Status     OrderStatus `json:"status"`
+	StatusName string      `json:"status_name"`
+	Settled    bool        `json:"settled"`
+	PaidAt     *time.Time  `json:"paid_at"`
+	RefundedAt *time.Time  `json:"refunded_at"`
+	ExpireAt   time.Time   `json:"expire_at"`
+	Content    string      `json:"content,omitempty"`
+}
+
+// publishOrderEvent 通知等待中的请求订单状态已变化,失败仅记录日志,等待方会兜底轮询
+func publishOrderEvent(ctx context.Context, outTradeNo string) {
+	if err := db.Redis.Publish(ctx, orderEventChannelPrefix+outTradeNo, "1").Err(); err != nil {
+		logger.WarnF(ctx, "payment: publish order %s event failed: %v", outTradeNo, err)
+	}
+}
+
+// orderEvents 进程内共享的订单事件订阅,所有长轮询共用一条 PSUBSCRIBE 连接
+var orderEvents = &orderEventHub{waiters: map[string]map[chan struct{}]struct{}{}}
+
+// orderEventHub 按订单号把订单事件分发给等待中的请求
+type orderEventHub struct {
+	mu      sync.Mutex
+	client  *redis.Client
+	pubsub  *redis.PubSub
+	waiters map[string]map[chan struct{}]struct{}
+}
+
+// wait 登记对订单事件的等待,返回通知通道与注销函数;订阅失败时返回错误,调用方退回轮询
+func (h *orderEventHub) wait(ctx context.Context, outTradeNo string) (<-chan struct{}, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.ensureSubscribed(ctx); err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan struct{}, 1)
+	if h.waiters[outTradeNo] == nil {
+		h.waiters[outTradeNo] = map[chan struct{}]struct{}{}
+	}
+	h.waiters[outTradeNo][ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.waiters[outTradeNo], ch)
+		if len(h.waiters[outTradeNo]) == 0 {
+			delete(h.waiters, outTradeNo)
+		}
+	}, nil
+}
+
+// ensureSubscribed 首次使用或 Redis 客户端被替换时建立订阅,需持有 h.mu。
+// 订阅确认后才返回,保证此后发布的事件不会丢失;断线由 go-redis 自动重连并恢复订阅。
+func (h *orderEventHub) ensureSubscribed(ctx context.Context) error {
+	if h.pubsub != nil && h.client == db.Redis {
+		return nil
+	}
+	if h.pubsub != nil {
+		_ = h.pubsub.Close()
+		h.client, h.pubsub = nil, nil
+	}
+	pubsub := db.Redis.PSubscribe(context.Background(), orderEventChannelPrefix+"*")
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return err
+	}
+	h.client, h.pubsub = db.Redis, pubsub
+	go h.dispatch(pubsub)
+	return nil
+}
+
+// dispatch 将收到的事件非阻塞地投递给对应订单的全部等待者,订阅关闭后退出
+func (h *orderEventHub) dispatch(pubsub *redis.PubSub) {
+	for msg := range pubsub.Channel() {
+		outTradeNo := strings.TrimPrefix(msg.Channel, orderEventChannelPrefix)
+		h.mu.Lock()
+		for ch := range h.waiters[outTradeNo] {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// GetOrderStatus 查询买家自己的订单状态
+func GetOrderStatus(ctx context.Context, payerID uint64, outTradeNo string) (*OrderStatusView, error) {
+	var order PaymentOrder
+	if err := db.DB(ctx).
+		Where("out_trade_no = ? AND payer_id = ?", outTradeNo, payerID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(ErrOrderNotFound)
+		}
+		return nil, err
+	}
+
+	view := &OrderStatusView{
+		OutTradeNo: order.OutTradeNo,
+		ProjectID:  order.ProjectID,
+		Amount:     moneyString(order.Amount),
+		Status:     order.Status,
+		StatusName: orderStatusNames[order.Status],
+		Settled:    order.Status != OrderStatusPending && order.Status != OrderStatusPaid,
+		PaidAt:     order.PaidAt,
+		RefundedAt: order.RefundedAt,
+		ExpireAt:   order.ExpireAt,
+	}
+	if order.Status == OrderStatusCompleted || order.Status == OrderStatusDisputed {
+		var item project.ProjectItem
+		if err := item.Exact(db.DB(ctx), order.ItemID); err != nil {
+			return nil, err
+		}
+		content, err := item.PlainContent()
+		if err != nil {
+			return nil, err
+		}
+		view.Content = content
+	}
+	return view, nil
+}
+
+// WaitOrderStatus 长轮询:订单仍在处理中时等待至多 timeout,状态变化或超时后返回最新状态。
+// 先登记等待再查询,避免查询与登记之间发布的通知丢失;订阅不可用时仅靠定时轮询。
+func WaitOrderStatus(ctx context.Context, payerID uint64, outTradeNo string, timeout time.Duration) (*OrderStatusView, error) {
+	if timeout <= 0 {
+		return GetOrderStatus(ctx, payerID, outTradeNo)
+	}
+
+	events, cancel, err := orderEvents.wait(ctx, outTradeNo)
+	if err != nil {
+		logger.WarnF(ctx, "payment: subscribe order events failed, falling back to polling: %v", err)
+	} else {
+		defer cancel()
+	}
+
+	view, err := GetOrderStatus(ctx, payerID, outTradeNo)
+	if err != nil || view.Settled {
+		return view, err
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(orderWaitPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return view, nil
+		case <-deadline.C:
+			return GetOrderStatus(ctx, payerID, outTradeNo)
+		case <-events:
+		case <-ticker.C:
+		}
+		if view, err = GetOrderStatus(ctx, payerID, outTradeNo); err != nil || view.Settled {
+			return view, err
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/db"
+)
+
+// closeOrderEvents 在测试 Redis 关闭前关闭共享订阅,下一个测试会重新订阅
+func closeOrderEvents(t *testing.T) {
+	t.Cleanup(func() {
+		orderEvents.mu.Lock()
+		defer orderEvents.mu.Unlock()
+		if orderEvents.pubsub != nil {
+			_ = orderEvents.pubsub.Close()
+			orderEvents.client, orderEvents.pubsub = nil, nil
+		}
+	})
+}
+
+func TestWaitOrderStatusWakesOnEvent(t *testing.T) {
+	f := setupPayment(t)
+	closeOrderEvents(t)
+	f.addItems(t, 1)
+	init := f.initiate(t)
+	ctx := context.Background()
+
+	type waitResult struct {
+		view *OrderStatusView
+		err  error
+	}
+	done := make(chan waitResult, 1)
+	start := time.Now()
+	go func() {
+		view, err := WaitOrderStatus(ctx, f.payer.ID, init.OutTradeNo, 10*time.Second)
+		done <- waitResult{view, err}
+	}()
+
+	// 等待者登记后再付款,回调发布的事件应在兜底轮询之前唤醒等待
+	deadline := time.Now().Add(time.Second)
+	for {
+		orderEvents.mu.Lock()
+		registered := len(orderEvents.waiters[init.OutTradeNo]) > 0
+		orderEvents.mu.Unlock()
+		if registered {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("waiter was not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.pay(t, init.OutTradeNo)
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if r.view.Status != OrderStatusCompleted || r.view.Content == "" {
+			t.Fatalf("unexpected view %+v", r.view)
+		}
+		if elapsed := time.Since(start); elapsed >= orderWaitPollInterval {
+			t.Fatalf("wait should return on event, took %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return")
+	}
+
+	orderEvents.mu.Lock()
+	defer orderEvents.mu.Unlock()
+	if _, ok := orderEvents.waiters[init.OutTradeNo]; ok {
+		t.Fatal("waiter should be removed after return")
+	}
+}
+
+func TestOrderEventHubSharesSubscription(t *testing.T) {
+	setupPayment(t)
+	closeOrderEvents(t)
+	ctx := context.Background()
+
+	for _, outTradeNo := range []string{"order-a", "order-b", "order-a"} {
+		_, cancel, err := orderEvents.wait(ctx, outTradeNo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cancel()
+	}
+	patterns, err := db.Redis.PubSubNumPat(ctx).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patterns != 1 {
+		t.Fatalf("expected one shared pattern subscription, got %d", patterns)
+	}
+}
@@ -0,0 +1,317 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PriceRuleType 优惠规则类型
+type PriceRuleType string
+
+const (
+	PriceRuleCoupon     PriceRuleType = "coupon"      // 优惠码,下单时提交 code 才生效
+	PriceRuleEarlyBird  PriceRuleType = "early_bird"  // 早鸟价,在时间窗口内自动生效
+	PriceRuleTrustLevel PriceRuleType = "trust_level" // 信任等级折扣,达到等级自动生效
+)
+
+// maxPriceRulesPerProject 单个项目可配置的优惠规则上限
+const maxPriceRulesPerProject = 20
+
+// couponCodePattern 优惠码仅允许字母、数字、下划线与短横线
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{4,32}$`)
+
+// PaymentPriceRule 付费项目的优惠规则,命中时以 Price 作为成交价。
+// 所有类型都受 StartTime/EndTime、MinTrustLevel 限制;多条规则同时命中时取最低价。
+//
+// 联合索引：
+//   - idx_project_code (project_id, code)：同一项目内优惠码唯一,非优惠码规则 code 为 NULL
+type PaymentPriceRule struct {
+	ID            uint64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID     string           `gorm:"size:64;not null;uniqueIndex:idx_project_code,priority:1" json:"project_id"`
+	Type          PriceRuleType    `gorm:"size:16;not null" json:"type"`
+	Code          *string          `gorm:"size:32;uniqueIndex:idx_project_code,priority:2" json:"code"`
+	Price         decimal.Decimal  `gorm:"type:decimal(10,2);not null" json:"price"`
+	MinTrustLevel oauth.TrustLevel `gorm:"default:0" json:"min_trust_level"`
+	StartTime     *time.Time       `json:"start_time"`
+	EndTime       *time.Time       `json:"end_time"`
+	MaxUses       int64            `gorm:"default:0" json:"max_uses"`
+	UsedCount     int64            `gorm:"default:0" json:"used_count"`
+	CreatedAt     time.Time        `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 自定义表名
+func (PaymentPriceRule) TableName() string { return "payment_price_rules" }
+
+// normalizeCouponCode 优惠码不区分大小写
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// applicable 判断规则对指定用户与时间是否生效,优惠码规则还需匹配 code
+func (r *PaymentPriceRule) applicable(level oauth.TrustLevel, couponCode string, now time.Time) bool {
+	if r.StartTime != nil && now.Before(*r.StartTime) {
+		return false
+	}
+	if r.EndTime != nil && !now.Before(*r.EndTime) {
+		return false
+	}
+	if level < r.MinTrustLevel {
+		return false
+	}
+	if r.Type == PriceRuleCoupon {
+		if r.Code == nil || couponCode == "" || *r.Code != couponCode {
+			return false
+		}
+		if r.MaxUses > 0 && r.UsedCount >= r.MaxUses {
+			return false
+		}
+	}
+	return true
+}
+
+// PriceQuote 定价结果,Rule 为空表示按原价
+type PriceQuote struct {
+	OriginalAmount decimal.Decimal   `json:"original_amount"`
+	Amount         decimal.Decimal   `json:"amount"`
+	Rule           *PaymentPriceRule `json:"rule"`
+}
+
+// quotePrice 计算用户购买项目的成交价:在所有命中的规则中取低于原价的最低价。
+// 提交了优惠码但没有命中对应规则时返回 ErrCouponInvalid,避免用户以为已优惠。
+func quotePrice(tx *gorm.DB, p *project.Project, payer *oauth.User, couponCode string, now time.Time) (*PriceQuote, error) {
+	quote := &PriceQuote{OriginalAmount: p.Price, Amount: p.Price}
+
+	var rules []PaymentPriceRule
+	if err := tx.Where("project_id = ?", p.ID).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	couponCode = normalizeCouponCode(couponCode)
+	couponMatched := false
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.applicable(payer.TrustLevel, couponCode, now) {
+			continue
+		}
+		if rule.Type == PriceRuleCoupon {
+			couponMatched = true
+		}
+		if rule.Price.GreaterThan(decimal.Zero) && rule.Price.LessThan(quote.Amount) {
+			quote.Amount = rule.Price
+			quote.Rule = rule
+		}
+	}
+	if couponCode != "" && !couponMatched {
+		return nil, errors.New(ErrCouponInvalid)
+	}
+	return quote, nil
+}
+
+// consumeCouponUse 在下单事务中占用一次优惠码次数,并发下超出上限时返回 ErrCouponInvalid
+func consumeCouponUse(tx *gorm.DB, rule *PaymentPriceRule) error {
+	if rule == nil || rule.Type != PriceRuleCoupon {
+		return nil
+	}
+	result := tx.Model(&PaymentPriceRule{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", rule.ID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(ErrCouponInvalid)
+	}
+	return nil
+}
+
+// releaseCouponUse 订单未成交(超时或发放失败退款)时归还优惠码次数
+func releaseCouponUse(tx *gorm.DB, order *PaymentOrder) error {
+	if order.PriceRuleID == nil || order.PriceRuleType != PriceRuleCoupon {
+		return nil
+	}
+	return tx.Model(&PaymentPriceRule{}).
+		Where("id = ? AND used_count > 0", *order.PriceRuleID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// CreatePriceRuleRequest 创建优惠规则请求体
+type CreatePriceRuleRequest struct {
+	Type          PriceRuleType   `json:"type" binding:"required,oneof=coupon early_bird trust_level"`
+	Code          string          `json:"code" binding:"omitempty,max=32"`
+	Price         decimal.Decimal `json:"price" binding:"required"`
+	MinTrustLevel int8            `json:"min_trust_level" binding:"min=0,max=4"`
+	StartTime     *time.Time      `json:"start_time"`
+	EndTime       *time.Time      `json:"end_time"`
+	MaxUses       int64           `json:"max_uses" binding:"min=0"`
+}
+
+// Validate 校验规则与项目原价、类型必填项
+func (r *CreatePriceRuleRequest) Validate(p *project.Project) error {
+	if !p.IsPaid() {
+		return errors.New(ErrPriceRuleRequiresPaid)
+	}
+	if !r.Price.GreaterThan(decimal.Zero) {
+		return errors.New(ErrInvalidAmount)
+	}
+	if !r.Price.Equal(r.Price.Truncate(2)) {
+		return errors.New(ErrInvalidPriceDecimals)
+	}
+	if !r.Price.LessThan(p.Price) {
+		return errors.New(ErrPriceRuleNotCheaper)
+	}
+	if r.StartTime != nil && r.EndTime != nil && !r.EndTime.After(*r.StartTime) {
+		return errors.New(ErrInvalidTimeRange)
+	}
+	switch r.Type {
+	case PriceRuleCoupon:
+		r.Code = normalizeCouponCode(r.Code)
+		if !couponCodePattern.MatchString(r.Code) {
+			return errors.New(ErrInvalidCouponCode)
+		}
+	case PriceRuleEarlyBird:
+		if r.EndTime == nil {
+			return errors.New(ErrEarlyBirdRequiresEndTime)
+		}
+	case PriceRuleTrustLevel:
+		if r.MinTrustLevel <= 0 {
+			return errors.New(ErrTrustLevelRuleRequiresLevel)
+		}
+	}
+	return nil
+}
+
+// toRule 转为待保存的规则,非优惠码类型不保存 code 与使用次数
+func (r *CreatePriceRuleRequest) toRule(projectID string) *PaymentPriceRule {
+	rule := &PaymentPriceRule{
+		ProjectID:     projectID,
+		Type:          r.Type,
+		Price:         r.Price,
+		MinTrustLevel: oauth.TrustLevel(r.MinTrustLevel),
+		StartTime:     r.StartTime,
+		EndTime:       r.EndTime,
+	}
+	if r.Type == PriceRuleCoupon {
+		code := r.Code
+		rule.Code = &code
+		rule.MaxUses = r.MaxUses
+	}
+	return rule
+}
+
+// ListPriceRules 列出项目的全部优惠规则
+func ListPriceRules(ctx context.Context, projectID string) ([]PaymentPriceRule, error) {
+	rules := make([]PaymentPriceRule, 0)
+	if err := db.DB(ctx).Where("project_id = ?", projectID).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreatePriceRule 为项目新增优惠规则,调用方需已完成 Validate
+func CreatePriceRule(ctx context.Context, p *project.Project, req *CreatePriceRuleRequest) (*PaymentPriceRule, error) {
+	rule := req.toRule(p.ID)
+	err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&PaymentPriceRule{}).Where("project_id = ?", p.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxPriceRulesPerProject {
+			return errors.New(ErrTooManyPriceRules)
+		}
+		if rule.Code != nil {
+			var exists int64
+			if err := tx.Model(&PaymentPriceRule{}).
+				Where("project_id = ? AND code = ?", p.ID, *rule.Code).
+				Count(&exists).Error; err != nil {
+				return err
+			}
+			if exists > 0 {
+				return errors.New(ErrCouponCodeExists)
+			}
+		}
+		return tx.Create(rule).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeletePriceRule 删除项目的优惠规则,已下单订单保留规则 ID 作为记录
+func DeletePriceRule(ctx context.Context, projectID string, ruleID uint64) error {
+	result := db.DB(ctx).Where("id = ? AND project_id = ?", ruleID, projectID).Delete(&PaymentPriceRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(ErrPriceRuleNotFound)
+	}
+	return nil
+}
+
+// BackfillOriginalAmounts 将定价引擎上线前的订单原价回填为成交价,返回回填数量
+func BackfillOriginalAmounts(ctx context.Context) (int64, error) {
+	result := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("original_amount = 0").
+		Update("original_amount", gorm.Expr("amount"))
+	return result.RowsAffected, result.Error
+}
+
+// QuotePrice 预览当前用户购买项目的成交价
+func QuotePrice(ctx context.Context, p *project.Project, payer *oauth.User, couponCode string) (*PriceQuote, error) {
+	return quotePrice(db.DB(ctx), p, payer, couponCode, time.Now())
+}
+
+// defaultCouponQuoteRateLimit 未配置时每个用户每分钟携带优惠码预览价格的次数上限
+const defaultCouponQuoteRateLimit = 10
+
+// allowCouponQuote 按用户限制每分钟携带优惠码的价格预览次数,防止借预览接口枚举优惠码
+func allowCouponQuote(ctx context.Context, userID uint64) (bool, error) {
+	limit := config.Config.Payment.CouponQuoteRateLimit
+	if limit <= 0 {
+		limit = defaultCouponQuoteRateLimit
+	}
+	key := fmt.Sprintf("rate_limit:payment:coupon_quote:%d:%d", userID, time.Now().Unix()/60)
+	count, err := db.Redis.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		db.Redis.Expire(ctx, key, time.Minute)
+	}
+	return count <= int64(limit), nil
+}
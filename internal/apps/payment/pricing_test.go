@@ -0,0 +1,213 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/shopspring/decimal"
+)
+
+func TestPriceRuleApplicable(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	code := "EARLY2025"
+
+	cases := []struct {
+		name  string
+		rule  PaymentPriceRule
+		level oauth.TrustLevel
+		code  string
+		want  bool
+	}{
+		{"early bird in window", PaymentPriceRule{Type: PriceRuleEarlyBird, EndTime: &future}, 0, "", true},
+		{"early bird ended", PaymentPriceRule{Type: PriceRuleEarlyBird, EndTime: &past}, 0, "", false},
+		{"not started", PaymentPriceRule{Type: PriceRuleEarlyBird, StartTime: &future, EndTime: &future}, 0, "", false},
+		{"trust level reached", PaymentPriceRule{Type: PriceRuleTrustLevel, MinTrustLevel: 2}, 3, "", true},
+		{"trust level too low", PaymentPriceRule{Type: PriceRuleTrustLevel, MinTrustLevel: 2}, 1, "", false},
+		{"coupon matched", PaymentPriceRule{Type: PriceRuleCoupon, Code: &code}, 0, code, true},
+		{"coupon missing", PaymentPriceRule{Type: PriceRuleCoupon, Code: &code}, 0, "", false},
+		{"coupon exhausted", PaymentPriceRule{Type: PriceRuleCoupon, Code: &code, MaxUses: 1, UsedCount: 1}, 0, code, false},
+	}
+	for _, tc := range cases {
+		if got := tc.rule.applicable(tc.level, tc.code, now); got != tc.want {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCreatePriceRuleRequestValidate(t *testing.T) {
+	p := &project.Project{Price: decimal.RequireFromString("10")}
+
+	req := CreatePriceRuleRequest{Type: PriceRuleCoupon, Code: " early-1 ", Price: decimal.RequireFromString("8")}
+	if err := req.Validate(p); err != nil {
+		t.Fatalf("valid coupon rejected: %v", err)
+	}
+	if req.Code != "EARLY-1" {
+		t.Fatalf("coupon code not normalized: %q", req.Code)
+	}
+
+	invalid := []CreatePriceRuleRequest{
+		{Type: PriceRuleCoupon, Code: "EARLY-1", Price: decimal.RequireFromString("10")},
+		{Type: PriceRuleCoupon, Code: "EARLY-1", Price: decimal.RequireFromString("8.001")},
+		{Type: PriceRuleCoupon, Code: "a!", Price: decimal.RequireFromString("8")},
+		{Type: PriceRuleEarlyBird, Price: decimal.RequireFromString("8")},
+		{Type: PriceRuleTrustLevel, Price: decimal.RequireFromString("8")},
+	}
+	for i, r := range invalid {
+		if err := r.Validate(p); err == nil {
+			t.Fatalf("case %d: expected validation error", i)
+		}
+	}
+}
+
+// quotePriceHTTP 以 user 身份请求价格预览接口
+func quotePriceHTTP(t *testing.T, projectID string, user *oauth.User, couponCode string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/price?coupon_code="+url.QueryEscape(couponCode), nil)
+	c.Params = gin.Params{{Key: "id", Value: projectID}}
+	oauth.SetUserToContext(c, user)
+	QuotePriceHTTP(c)
+	return w
+}
+
+func TestQuotePriceHTTPReturnsOnlyPriceAndRuleType(t *testing.T) {
+	f := setupPayment(t)
+	code := "SAVE50"
+	if err := db.DB(context.Background()).Create(&PaymentPriceRule{
+		ProjectID: f.project.ID, Type: PriceRuleCoupon, Code: &code,
+		Price: decimal.RequireFromString("0.75"), MaxUses: 5, UsedCount: 2,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w := quotePriceHTTP(t, f.project.ID, &f.payer, "save50")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["amount"] != "0.75" || resp.Data["original_amount"] != "1.5" || resp.Data["rule_type"] != string(PriceRuleCoupon) {
+		t.Fatalf("unexpected quote %v", resp.Data)
+	}
+	for _, leaked := range []string{"used_count", "max_uses", "code", "rule"} {
+		if strings.Contains(w.Body.String(), `"`+leaked+`"`) {
+			t.Fatalf("quote should not expose %q: %s", leaked, w.Body)
+		}
+	}
+
+	// 无优惠时不返回规则类型
+	w = quotePriceHTTP(t, f.project.ID, &f.payer, "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "rule_type") {
+		t.Fatalf("unexpected quote without coupon: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestQuotePriceHTTPRateLimitsCoupons(t *testing.T) {
+	f := setupPayment(t)
+	config.Config.Payment.CouponQuoteRateLimit = 2
+
+	for i := range 2 {
+		if w := quotePriceHTTP(t, f.project.ID, &f.payer, "GUESS"+string(rune('A'+i))); w.Code != http.StatusBadRequest {
+			t.Fatalf("attempt %d: status = %d, want 400", i, w.Code)
+		}
+	}
+	if w := quotePriceHTTP(t, f.project.ID, &f.payer, "GUESSC"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	// 不携带优惠码的预览不受限
+	if w := quotePriceHTTP(t, f.project.ID, &f.payer, ""); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	// 限频按用户计数
+	if w := quotePriceHTTP(t, f.project.ID, &f.creator, "GUESSD"); w.Code != http.StatusBadRequest {
+		t.Fatalf("other user: status = %d, want 400", w.Code)
+	}
+}
+
+func TestQuotePriceHTTPHidesInviteOnlyProject(t *testing.T) {
+	f := setupPayment(t)
+	ctx := context.Background()
+	if err := db.DB(ctx).AutoMigrate(&project.ProjectInvitee{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB(ctx).Model(f.project).Update("distribution_type", project.DistributionTypeInvite).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if w := quotePriceHTTP(t, f.project.ID, &f.payer, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("non-invitee: status = %d, want 404", w.Code)
+	}
+	if w := quotePriceHTTP(t, f.project.ID, &f.creator, ""); w.Code != http.StatusOK {
+		t.Fatalf("creator: status = %d, want 200", w.Code)
+	}
+	if err := db.DB(ctx).Create(&project.ProjectInvitee{ProjectID: f.project.ID, Username: f.payer.Username}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if w := quotePriceHTTP(t, f.project.ID, &f.payer, ""); w.Code != http.StatusOK {
+		t.Fatalf("invitee: status = %d, want 200", w.Code)
+	}
+}
+
+func TestBackfillOriginalAmounts(t *testing.T) {
+	f := setupPayment(t)
+	f.addItems(t, 1)
+	init := f.initiate(t)
+	ctx := context.Background()
+	// 定价引擎上线前的订单没有原价
+	if err := db.DB(ctx).Model(&PaymentOrder{}).Where("out_trade_no = ?", init.OutTradeNo).
+		Updates(map[string]any{"amount": decimal.RequireFromString("1.20"), "original_amount": 0}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 重复执行不会覆盖已有原价
+	for _, want := range []int64{1, 0} {
+		count, err := BackfillOriginalAmounts(ctx)
+		if err != nil || count != want {
+			t.Fatalf("backfilled %d orders, err %v; want %d", count, err, want)
+		}
+	}
+	if got := loadOrder(t, init.OutTradeNo).OriginalAmount.String(); got != "1.2" {
+		t.Errorf("original amount = %s, want 1.2", got)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ProviderCredentials 创建者在支付渠道的商户凭据
+type ProviderCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// PayRequest 发起支付所需的订单信息
+type PayRequest struct {
+	OutTradeNo string
+	Name       string
+	Money      string
+	NotifyURL  string
+	ReturnURL  string
+}
+
+// NotifyResult 验签通过后的异步通知内容
+type NotifyResult struct {
+	OutTradeNo string
+	TradeNo    string
+	Money      string
+	Paid       bool
+}
+
+// OrderQueryResult 主动查询到的渠道侧订单状态,已退款的订单 Paid 为 false、Refunded 为 true
+type OrderQueryResult struct {
+	OutTradeNo string
+	TradeNo    string
+	Money      string
+	Paid       bool
+	Refunded   bool
+}
+
+// PaymentProvider 支付渠道,每个实例绑定一位创建者的商户凭据
+type PaymentProvider interface {
+	// Name 渠道标识,与 UserPaymentConfig.Provider 对应
+	Name() string
+	// PayURL 构造浏览器直接跳转的支付地址
+	PayURL(ctx context.Context, req *PayRequest) (string, error)
+	// VerifyNotify 校验异步通知并解析结果,签名或商户不匹配时返回错误
+	VerifyNotify(ctx context.Context, params map[string]string) (*NotifyResult, error)
+	// QueryOrder 按本地订单号查询渠道侧订单状态,查无订单时返回 ErrProviderOrderUnknown
+	QueryOrder(ctx context.Context, outTradeNo string) (*OrderQueryResult, error)
+	// Refund 按渠道订单号全额退款
+	Refund(ctx context.Context, tradeNo, money string) error
+}
+
+// ErrProviderOrderUnknown 渠道侧查无此订单,视为未付款
+var ErrProviderOrderUnknown = errors.New("provider has no record of the order")
+
+// ProviderFactory 以商户凭据创建渠道实例
+type ProviderFactory func(creds ProviderCredentials) PaymentProvider
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+// RegisterProvider 注册支付渠道,重复注册会覆盖
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+// ProviderNames 返回已注册的渠道标识
+func ProviderNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsProviderRegistered 判断渠道是否可用
+func IsProviderRegistered(name string) bool {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	_, ok := providers[name]
+	return ok
+}
+
+// NewProvider 按渠道标识创建渠道实例
+func NewProvider(name string, creds ProviderCredentials) (PaymentProvider, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, errors.New(ErrProviderNotSupported)
+	}
+	return factory(creds), nil
+}
+
+// configProvider 以创建者的支付配置创建渠道实例
+func configProvider(cfg *UserPaymentConfig, name string) (PaymentProvider, error) {
+	secret, err := decryptUserClientSecret(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = cfg.Provider
+	}
+	return NewProvider(name, ProviderCredentials{ClientID: cfg.ClientID, ClientSecret: secret})
+}
+
+// orderProvider 返回订单创建时所用渠道的实例,凭据取自收款方当前配置
+func orderProvider(ctx context.Context, order *PaymentOrder) (PaymentProvider, error) {
+	cfg, err := GetUserPaymentConfig(ctx, order.PayeeID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, errors.New(ErrPaymentConfigNotFound)
+	}
+	return configProvider(cfg, order.Provider)
+}
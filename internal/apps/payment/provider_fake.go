@@ -0,0 +1,144 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+
+	"github.com/linux-do/cdk/internal/config"
+)
+
+// ProviderFake 本地模拟渠道,不访问网络,用于测试与本地开发。
+// 仅在非生产环境显式开启 payment.enable_fake_provider 时注册,避免创建者在支付配置中选用。
+const ProviderFake = "fake"
+
+func init() {
+	if config.Config.App.Env == "production" || !config.Config.Payment.EnableFakeProvider {
+		return
+	}
+	registerFakeProvider()
+}
+
+func registerFakeProvider() {
+	RegisterProvider(ProviderFake, func(creds ProviderCredentials) PaymentProvider {
+		return &fakeProvider{creds: creds}
+	})
+}
+
+// fakeLedger 模拟渠道侧的订单账本,按本地订单号索引
+var fakeLedger = struct {
+	sync.Mutex
+	orders map[string]*fakeOrder
+}{orders: map[string]*fakeOrder{}}
+
+type fakeOrder struct {
+	money    string
+	tradeNo  string
+	paid     bool
+	refunded bool
+}
+
+// fakeProvider 以易支付签名规则模拟支付、回调、查询与退款
+type fakeProvider struct {
+	creds ProviderCredentials
+}
+
+func (f *fakeProvider) Name() string { return ProviderFake }
+
+func (f *fakeProvider) PayURL(_ context.Context, req *PayRequest) (string, error) {
+	fakeLedger.Lock()
+	defer fakeLedger.Unlock()
+	fakeLedger.orders[req.OutTradeNo] = &fakeOrder{money: req.Money}
+
+	values := url.Values{}
+	values.Set("out_trade_no", req.OutTradeNo)
+	values.Set("money", req.Money)
+	return "fake://pay?" + values.Encode(), nil
+}
+
+func (f *fakeProvider) VerifyNotify(_ context.Context, params map[string]string) (*NotifyResult, error) {
+	if !VerifySign(params, f.creds.ClientSecret) {
+		return nil, errors.New("sign mismatch")
+	}
+	if params["pid"] != f.creds.ClientID {
+		return nil, errors.New("pid mismatch")
+	}
+	return &NotifyResult{
+		OutTradeNo: params["out_trade_no"],
+		TradeNo:    params["trade_no"],
+		Money:      params["money"],
+		Paid:       params["trade_status"] == "TRADE_SUCCESS",
+	}, nil
+}
+
+func (f *fakeProvider) QueryOrder(_ context.Context, outTradeNo string) (*OrderQueryResult, error) {
+	fakeLedger.Lock()
+	defer fakeLedger.Unlock()
+	o, ok := fakeLedger.orders[outTradeNo]
+	if !ok {
+		return nil, ErrProviderOrderUnknown
+	}
+	return &OrderQueryResult{OutTradeNo: outTradeNo, TradeNo: o.tradeNo, Money: o.money, Paid: o.paid && !o.refunded, Refunded: o.refunded}, nil
+}
+
+func (f *fakeProvider) Refund(_ context.Context, tradeNo, money string) error {
+	fakeLedger.Lock()
+	defer fakeLedger.Unlock()
+	for _, o := range fakeLedger.orders {
+		if o.paid && o.tradeNo == tradeNo {
+			if o.refunded || o.money != money {
+				return errors.New("refund rejected")
+			}
+			o.refunded = true
+			return nil
+		}
+	}
+	return errors.New(ErrOrderNotFound)
+}
+
+// FakePay 模拟用户在渠道侧完成付款,返回应投递给 HandleNotify 的已签名回调参数
+func FakePay(creds ProviderCredentials, outTradeNo string) (map[string]string, error) {
+	fakeLedger.Lock()
+	defer fakeLedger.Unlock()
+	o, ok := fakeLedger.orders[outTradeNo]
+	if !ok {
+		return nil, errors.New(ErrOrderNotFound)
+	}
+	o.paid = true
+	o.tradeNo = "FAKE" + outTradeNo
+	params := map[string]string{
+		"pid":          creds.ClientID,
+		"out_trade_no": outTradeNo,
+		"trade_no":     o.tradeNo,
+		"money":        o.money,
+		"trade_status": "TRADE_SUCCESS",
+		"sign_type":    "MD5",
+	}
+	params["sign"] = BuildSign(params, creds.ClientSecret)
+	return params, nil
+}
@@ -0,0 +1,200 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/linux-do/cdk/internal/apps/notification"
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+)
+
+func TestFakeProviderFlow(t *testing.T) {
+	ctx := context.Background()
+	creds := ProviderCredentials{ClientID: "1001", ClientSecret: "fake-secret"}
+	provider, err := NewProvider(ProviderFake, creds)
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	payURL, err := provider.PayURL(ctx, &PayRequest{OutTradeNo: "CDKFAKE1", Name: "CDK-test", Money: "1.50"})
+	if err != nil || !strings.HasPrefix(payURL, "fake://pay?") {
+		t.Fatalf("pay url: %q, %v", payURL, err)
+	}
+	if q, _ := provider.QueryOrder(ctx, "CDKFAKE1"); q == nil || q.Paid {
+		t.Fatal("order should be unpaid before FakePay")
+	}
+
+	params, err := FakePay(creds, "CDKFAKE1")
+	if err != nil {
+		t.Fatalf("fake pay: %v", err)
+	}
+	result, err := provider.VerifyNotify(ctx, params)
+	if err != nil {
+		t.Fatalf("verify notify: %v", err)
+	}
+	if !result.Paid || result.Money != "1.50" || result.TradeNo == "" {
+		t.Fatalf("unexpected notify result: %+v", result)
+	}
+
+	tampered := map[string]string{}
+	for k, v := range params {
+		tampered[k] = v
+	}
+	tampered["money"] = "0.01"
+	if _, err := provider.VerifyNotify(ctx, tampered); err == nil {
+		t.Fatal("tampered notify should be rejected")
+	}
+
+	if err := provider.Refund(ctx, result.TradeNo, "1.50"); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if err := provider.Refund(ctx, result.TradeNo, "1.50"); err == nil {
+		t.Fatal("double refund should be rejected")
+	}
+	if q, _ := provider.QueryOrder(ctx, "CDKFAKE1"); q.Paid {
+		t.Fatal("refunded order should not be reported as paid")
+	}
+}
+
+func TestEasyPayProviderAPI(t *testing.T) {
+	var gotAct []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		gotAct = append(gotAct, r.PostForm.Get("act"))
+		if r.PostForm.Get("pid") != "1001" || r.PostForm.Get("key") != "S" {
+			_, _ = w.Write([]byte(`{"code":-1,"msg":"auth failed"}`))
+			return
+		}
+		switch r.PostForm.Get("act") {
+		case "order":
+			if r.PostForm.Get("out_trade_no") != "CDK1" {
+				_, _ = w.Write([]byte(`{"code":-1,"msg":"订单号不存在"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":1,"trade_no":"T1","out_trade_no":"CDK1","money":"2.00","status":"1"}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":-1,"msg":"already refunded"}`))
+		}
+	}))
+	defer srv.Close()
+
+	old := config.Config.Payment.ApiUrl
+	config.Config.Payment.ApiUrl = srv.URL
+	defer func() { config.Config.Payment.ApiUrl = old }()
+
+	provider, err := NewProvider(ProviderEasyPay, ProviderCredentials{ClientID: "1001", ClientSecret: "S"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := provider.QueryOrder(context.Background(), "CDK1")
+	if err != nil || !q.Paid || q.TradeNo != "T1" || q.Money != "2.00" {
+		t.Fatalf("query order: %+v, %v", q, err)
+	}
+	if err := provider.Refund(context.Background(), "T1", "2.00"); err == nil || !strings.Contains(err.Error(), "already refunded") {
+		t.Fatalf("refund should surface gateway message, got %v", err)
+	}
+	if strings.Join(gotAct, ",") != "order,refund" {
+		t.Fatalf("unexpected api calls: %v", gotAct)
+	}
+
+	// 仅查无订单映射为 ErrProviderOrderUnknown,鉴权失败等按查询失败处理
+	if _, err := provider.QueryOrder(context.Background(), "CDK404"); !errors.Is(err, ErrProviderOrderUnknown) {
+		t.Fatalf("missing order should be unknown, got %v", err)
+	}
+	badCreds, err := NewProvider(ProviderEasyPay, ProviderCredentials{ClientID: "1001", ClientSecret: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := badCreds.QueryOrder(context.Background(), "CDK1"); err == nil || errors.Is(err, ErrProviderOrderUnknown) {
+		t.Fatalf("auth failure must not be treated as unknown order, got %v", err)
+	}
+
+	params := map[string]string{"pid": "9999", "out_trade_no": "CDK1", "money": "2.00", "trade_status": "TRADE_SUCCESS"}
+	params["sign"] = BuildSign(params, "S")
+	if _, err := provider.VerifyNotify(context.Background(), params); err == nil {
+		t.Fatal("notify for another merchant should be rejected")
+	}
+}
+
+func TestInitiatePaymentToHandleNotify(t *testing.T) {
+	f := setupPayment(t)
+	itemIDs := f.addItems(t, 2)
+	ctx := context.Background()
+
+	init := f.initiate(t)
+	if init.Amount != "1.50" || !strings.HasPrefix(init.PayURL, "fake://pay?") {
+		t.Fatalf("unexpected initiation: %+v", init)
+	}
+	order := loadOrder(t, init.OutTradeNo)
+	if order.Status != OrderStatusPending || order.ItemID != itemIDs[0] || order.Provider != ProviderFake {
+		t.Fatalf("unexpected pending order: %+v", order)
+	}
+	// 未付款前不能重复下单
+	if _, err := InitiatePayment(ctx, f.project, &f.payer, "127.0.0.1", ""); err == nil || err.Error() != ErrPendingOrderExists {
+		t.Fatalf("expected pending order error, got %v", err)
+	}
+
+	params, err := FakePay(f.creds, init.OutTradeNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, reason := HandleNotify(ctx, params); !ok {
+		t.Fatalf("handle notify: %s", reason)
+	}
+	order = loadOrder(t, init.OutTradeNo)
+	if order.Status != OrderStatusCompleted || order.TradeNo == "" || order.PaidAt == nil {
+		t.Fatalf("order not completed: %+v", order)
+	}
+	var item project.ProjectItem
+	if err := item.Exact(db.DB(ctx), order.ItemID); err != nil {
+		t.Fatal(err)
+	}
+	if item.ReceiverID == nil || *item.ReceiverID != f.payer.ID {
+		t.Fatalf("item not fulfilled to payer: %+v", item)
+	}
+	var sold int64
+	if err := db.DB(ctx).Model(&notification.Notification{}).
+		Where("user_id = ? AND type = ?", f.creator.ID, notification.NotificationTypeItemSold).
+		Count(&sold).Error; err != nil || sold != 1 {
+		t.Fatalf("creator should be notified once, got %d (%v)", sold, err)
+	}
+
+	// 重复回调幂等
+	if ok, reason := HandleNotify(ctx, params); !ok || reason != "idempotent" {
+		t.Fatalf("repeated notify: %v %s", ok, reason)
+	}
+	// 领取上限为 1,已完成订单后再次下单返回额度错误
+	if _, err := InitiatePayment(ctx, f.project, &f.payer, "127.0.0.1", ""); err == nil || err.Error() != ErrClaimQuotaReached {
+		t.Fatalf("expected claim quota error, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// reconcileGiveUpAfter 渠道持续不可查询时,超时超过该时长的订单不再等待、直接置 FAILED
+	reconcileGiveUpAfter = 24 * time.Hour
+	// reconcileReportBatchSize 日对账每批查询的订单数
+	reconcileReportBatchSize = 200
+)
+
+// ReconcileMismatchReason 对账差异类型
+type ReconcileMismatchReason string
+
+const (
+	// ReconcileMismatchPaidButFailed 渠道已付款,本地订单失败(未发放)
+	ReconcileMismatchPaidButFailed ReconcileMismatchReason = "paid_but_failed"
+	// ReconcileMismatchCompletedButUnpaid 本地已发放,渠道未付款或查无订单
+	ReconcileMismatchCompletedButUnpaid ReconcileMismatchReason = "completed_but_unpaid"
+	// ReconcileMismatchAmount 渠道付款金额与订单金额不一致
+	ReconcileMismatchAmount ReconcileMismatchReason = "amount_mismatch"
+	// ReconcileMismatchQueryFailed 渠道查询失败,无法核对
+	ReconcileMismatchQueryFailed ReconcileMismatchReason = "query_failed"
+)
+
+// PaymentReconcileMismatch 日对账报告中的一条差异记录
+type PaymentReconcileMismatch struct {
+	ID            uint64                  `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReportDate    string                  `gorm:"size:10;not null;uniqueIndex:idx_report_date_order,priority:1" json:"report_date"`
+	OutTradeNo    string                  `gorm:"size:64;not null;uniqueIndex:idx_report_date_order,priority:2" json:"out_trade_no"`
+	ProjectID     string                  `gorm:"size:64;index" json:"project_id"`
+	PayeeID       uint64                  `gorm:"index" json:"payee_id"`
+	LocalStatus   OrderStatus             `json:"local_status"`
+	LocalAmount   string                  `gorm:"size:16" json:"local_amount"`
+	ProviderPaid  bool                    `json:"provider_paid"`
+	ProviderMoney string                  `gorm:"size:16" json:"provider_money"`
+	Reason        ReconcileMismatchReason `gorm:"size:32" json:"reason"`
+	Detail        string                  `gorm:"size:255" json:"detail"`
+	CreatedAt     time.Time               `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 自定义表名
+func (PaymentReconcileMismatch) TableName() string { return "payment_reconcile_mismatches" }
+
+// reconcileStaleOrder 在置 FAILED 前向渠道查询超时订单,返回是否应继续过期处理。
+//   - 渠道已付款且金额一致:走 confirmPaidOrder 正常发放,不再过期
+//   - 渠道已付款但金额不一致:保持 PENDING 并记入对账差异,由管理员人工处理
+//   - 渠道未付款或查无订单:过期
+//   - 渠道暂不可查询:本轮跳过,超过 reconcileGiveUpAfter 后放弃等待
+func reconcileStaleOrder(ctx context.Context, order *PaymentOrder) bool {
+	provider, err := orderProvider(ctx, order)
+	if err != nil {
+		logger.WarnF(ctx, "payment reconcile: provider unavailable for order %s: %v", order.OutTradeNo, err)
+		return true
+	}
+	result, err := provider.QueryOrder(ctx, order.OutTradeNo)
+	if errors.Is(err, ErrProviderOrderUnknown) {
+		return true
+	}
+	if err != nil {
+		if time.Since(order.ExpireAt) > reconcileGiveUpAfter {
+			logger.WarnF(ctx, "payment reconcile: giving up querying order %s: %v", order.OutTradeNo, err)
+			return true
+		}
+		logger.WarnF(ctx, "payment reconcile: failed to query order %s, retry later: %v", order.OutTradeNo, err)
+		return false
+	}
+	if !result.Paid {
+		return true
+	}
+	if result.Money != moneyString(order.Amount) {
+		// 用户已付款,过期会让其钱货两空,保留订单与预占 item 等待人工处理
+		logger.ErrorF(ctx, "payment reconcile: order %s paid %s, want %s, kept pending for manual review", order.OutTradeNo, result.Money, moneyString(order.Amount))
+		flagAmountMismatch(ctx, order, result)
+		return false
+	}
+
+	ok, reason := confirmPaidOrder(ctx, provider, order, result.TradeNo)
+	logger.InfoF(ctx, "payment reconcile: order %s paid without notify, confirmed=%t reason=%s", order.OutTradeNo, ok, reason)
+	return false
+}
+
+// flagAmountMismatch 将付款金额不一致的超时订单记入当日对账差异,同一订单同日只记录一次
+func flagAmountMismatch(ctx context.Context, order *PaymentOrder, result *OrderQueryResult) {
+	mismatch := &PaymentReconcileMismatch{
+		ReportDate:    time.Now().Format(time.DateOnly),
+		OutTradeNo:    order.OutTradeNo,
+		ProjectID:     order.ProjectID,
+		PayeeID:       order.PayeeID,
+		LocalStatus:   order.Status,
+		LocalAmount:   moneyString(order.Amount),
+		ProviderPaid:  result.Paid,
+		ProviderMoney: result.Money,
+		Reason:        ReconcileMismatchAmount,
+		Detail:        "paid amount differs, order kept pending for manual review",
+	}
+	if err := db.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(mismatch).Error; err != nil {
+		logger.ErrorF(ctx, "payment reconcile: failed to flag order %s: %v", order.OutTradeNo, err)
+	}
+}
+
+// HandleDailyReconcileReport 核对前一自然日创建的 FAILED/COMPLETED/DISPUTED 订单与渠道侧状态,差异写入对账报告。
+// 同一日期重复执行时已记录的差异不会重复写入。
+func HandleDailyReconcileReport(ctx context.Context, _ *asynq.Task) error {
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := end.AddDate(0, 0, -1)
+	reportDate := start.Format(time.DateOnly)
+
+	var (
+		lastID     uint64
+		checked    int
+		mismatches int
+	)
+	for {
+		var orders []PaymentOrder
+		if err := db.DB(ctx).
+			Where("id > ? AND created_at >= ? AND created_at < ? AND status IN ?",
+				lastID, start, end, []OrderStatus{OrderStatusFailed, OrderStatusCompleted, OrderStatusDisputed}).
+			Order("id ASC").
+			Limit(reconcileReportBatchSize).
+			Find(&orders).Error; err != nil {
+			logger.ErrorF(ctx, "payment reconcile report: failed to query orders: %v", err)
+			return err
+		}
+		if len(orders) == 0 {
+			break
+		}
+		lastID = orders[len(orders)-1].ID
+
+		for i := range orders {
+			checked++
+			mismatch := reconcileOrder(ctx, &orders[i])
+			if mismatch == nil {
+				continue
+			}
+			mismatch.ReportDate = reportDate
+			if err := db.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(mismatch).Error; err != nil {
+				logger.ErrorF(ctx, "payment reconcile report: failed to save mismatch of order %s: %v", mismatch.OutTradeNo, err)
+				return err
+			}
+			mismatches++
+		}
+	}
+
+	logger.InfoF(ctx, "payment reconcile report %s: checked %d orders, %d mismatches", reportDate, checked, mismatches)
+	return nil
+}
+
+// reconcileOrder 核对单笔订单,无差异返回 nil
+func reconcileOrder(ctx context.Context, order *PaymentOrder) *PaymentReconcileMismatch {
+	mismatch := &PaymentReconcileMismatch{
+		OutTradeNo:  order.OutTradeNo,
+		ProjectID:   order.ProjectID,
+		PayeeID:     order.PayeeID,
+		LocalStatus: order.Status,
+		LocalAmount: moneyString(order.Amount),
+	}
+
+	provider, err := orderProvider(ctx, order)
+	if err != nil {
+		mismatch.Reason = ReconcileMismatchQueryFailed
+		mismatch.Detail = truncateRuneLen(err.Error(), 200)
+		return mismatch
+	}
+	result, err := provider.QueryOrder(ctx, order.OutTradeNo)
+	switch {
+	case errors.Is(err, ErrProviderOrderUnknown):
+		if order.Status != OrderStatusFailed {
+			mismatch.Reason = ReconcileMismatchCompletedButUnpaid
+			mismatch.Detail = truncateRuneLen(err.Error(), 200)
+			return mismatch
+		}
+		return nil
+	case err != nil:
+		mismatch.Reason = ReconcileMismatchQueryFailed
+		mismatch.Detail = truncateRuneLen(err.Error(), 200)
+		return mismatch
+	}
+
+	mismatch.ProviderPaid = result.Paid
+	mismatch.ProviderMoney = result.Money
+	switch {
+	case result.Paid && result.Money != mismatch.LocalAmount:
+		mismatch.Reason = ReconcileMismatchAmount
+	case result.Paid && order.Status == OrderStatusFailed:
+		mismatch.Reason = ReconcileMismatchPaidButFailed
+	case !result.Paid && order.Status != OrderStatusFailed:
+		mismatch.Reason = ReconcileMismatchCompletedButUnpaid
+	default:
+		return nil
+	}
+	return mismatch
+}
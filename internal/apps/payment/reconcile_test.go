@@ -0,0 +1,192 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/db"
+)
+
+// expireNow 将订单的过期时间推到清理宽限期之前
+func expireNow(t *testing.T, outTradeNo string) {
+	t.Helper()
+	if err := db.DB(context.Background()).Model(&PaymentOrder{}).
+		Where("out_trade_no = ?", outTradeNo).
+		Update("expire_at", time.Now().Add(-10*time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpireStaleOrdersConfirmsPaidOrder(t *testing.T) {
+	f := setupPayment(t)
+	f.addItems(t, 1)
+	init := f.initiate(t)
+
+	// 渠道侧已付款但回调丢失
+	if _, err := FakePay(f.creds, init.OutTradeNo); err != nil {
+		t.Fatal(err)
+	}
+	expireNow(t, init.OutTradeNo)
+	if err := HandleExpireStaleOrders(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if order := loadOrder(t, init.OutTradeNo); order.Status != OrderStatusCompleted {
+		t.Fatalf("paid order should be fulfilled, got status %d", order.Status)
+	}
+}
+
+func TestExpireStaleOrdersExpiresUnpaidOrder(t *testing.T) {
+	f := setupPayment(t)
+	f.addItems(t, 1)
+	init := f.initiate(t)
+	ctx := context.Background()
+
+	expireNow(t, init.OutTradeNo)
+	if err := HandleExpireStaleOrders(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	order := loadOrder(t, init.OutTradeNo)
+	if order.Status != OrderStatusFailed {
+		t.Fatalf("unpaid order should expire, got status %d", order.Status)
+	}
+	if n, err := db.Redis.LLen(ctx, f.project.ItemsKey()).Result(); err != nil || n != 1 {
+		t.Fatalf("reserved item should be returned, stock=%d err=%v", n, err)
+	}
+}
+
+func TestExpireStaleOrdersRefillsWaitlist(t *testing.T) {
+	f := setupPayment(t)
+	f.addItems(t, 1)
+	init := f.initiate(t)
+	ctx := context.Background()
+	if err := f.project.JoinWaitlist(ctx, "waiter"); err != nil {
+		t.Fatal(err)
+	}
+
+	expireNow(t, init.OutTradeNo)
+	if err := HandleExpireStaleOrders(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 归还的 item 在订单事务提交后预留给等候队列中的用户
+	if ok, err := f.project.HasReservation(ctx, "waiter"); err != nil || !ok {
+		t.Fatalf("returned item should be reserved for the waitlist, ok=%v err=%v", ok, err)
+	}
+	assertStock(t, f, 0)
+	var stored project.Project
+	if err := db.DB(ctx).First(&stored, "id = ?", f.project.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsCompleted {
+		t.Error("project should be completed once the waitlist takes the last item")
+	}
+}
+
+func TestExpireStaleOrdersKeepsAmountMismatch(t *testing.T) {
+	f := setupPayment(t)
+	f.addItems(t, 1)
+	init := f.initiate(t)
+	ctx := context.Background()
+
+	if _, err := FakePay(f.creds, init.OutTradeNo); err != nil {
+		t.Fatal(err)
+	}
+	fakeLedger.Lock()
+	fakeLedger.orders[init.OutTradeNo].money = "0.01"
+	fakeLedger.Unlock()
+
+	expireNow(t, init.OutTradeNo)
+	for range 2 {
+		if err := HandleExpireStaleOrders(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if order := loadOrder(t, init.OutTradeNo); order.Status != OrderStatusPending {
+		t.Fatalf("paid order with amount mismatch must stay pending, got status %d", order.Status)
+	}
+	var mismatches []PaymentReconcileMismatch
+	if err := db.DB(ctx).Where("out_trade_no = ?", init.OutTradeNo).Find(&mismatches).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Reason != ReconcileMismatchAmount || mismatches[0].ProviderMoney != "0.01" {
+		t.Fatalf("expected one amount mismatch, got %+v", mismatches)
+	}
+}
+
+func TestDailyReconcileReport(t *testing.T) {
+	f := setupPayment(t)
+	f.addItems(t, 2)
+	ctx := context.Background()
+
+	// 已完成订单,但渠道侧查无记录
+	completed := f.initiate(t)
+	f.pay(t, completed.OutTradeNo)
+	fakeLedger.Lock()
+	delete(fakeLedger.orders, completed.OutTradeNo)
+	fakeLedger.Unlock()
+
+	// 本地已过期,渠道侧实际已付款
+	f.project.ClaimQuota = 2
+	failed := f.initiate(t)
+	if _, err := FakePay(f.creds, failed.OutTradeNo); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("out_trade_no = ?", failed.OutTradeNo).
+		Update("status", OrderStatusFailed).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if err := db.DB(ctx).Model(&PaymentOrder{}).Where("1 = 1").Update("created_at", yesterday).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 重复执行不重复记录
+	for range 2 {
+		if err := HandleDailyReconcileReport(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var mismatches []PaymentReconcileMismatch
+	if err := db.DB(ctx).Order("id ASC").Find(&mismatches).Error; err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]ReconcileMismatchReason{}
+	for _, m := range mismatches {
+		if m.ReportDate != yesterday.Format(time.DateOnly) {
+			t.Errorf("unexpected report date %s", m.ReportDate)
+		}
+		got[m.OutTradeNo] = m.Reason
+	}
+	if len(mismatches) != 2 ||
+		got[completed.OutTradeNo] != ReconcileMismatchCompletedButUnpaid ||
+		got[failed.OutTradeNo] != ReconcileMismatchPaidButFailed {
+		t.Fatalf("unexpected mismatches: %+v", mismatches)
+	}
+}
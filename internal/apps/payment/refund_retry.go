@@ -0,0 +1,221 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+)
+
+const (
+	// defaultRefundMaxAttempts 未配置时自动退款的最大重试次数
+	defaultRefundMaxAttempts = 8
+	// defaultRefundRetryBaseSeconds 未配置时首次重试的退避间隔
+	defaultRefundRetryBaseSeconds = 60
+	// maxRefundRetryInterval 退避间隔上限
+	maxRefundRetryInterval = 6 * time.Hour
+	// refundRetryBatchSize 每轮最多处理的订单数
+	refundRetryBatchSize = 100
+	// refundClaimLease 认领退款后的租约,处理中断的订单在租约到期后重新进入重试
+	refundClaimLease = 5 * time.Minute
+)
+
+// refundRetryPolicy 返回最大重试次数与首次退避间隔
+func refundRetryPolicy() (int, time.Duration) {
+	maxAttempts := config.Config.Payment.RefundMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRefundMaxAttempts
+	}
+	base := config.Config.Payment.RefundRetryBaseSeconds
+	if base <= 0 {
+		base = defaultRefundRetryBaseSeconds
+	}
+	return maxAttempts, time.Duration(base) * time.Second
+}
+
+// refundBackoff 第 attempts 次失败后的等待时长:base * 2^(attempts-1),不超过 maxRefundRetryInterval
+func refundBackoff(base time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := float64(base) * math.Pow(2, float64(attempts-1))
+	if backoff > float64(maxRefundRetryInterval) {
+		return maxRefundRetryInterval
+	}
+	return time.Duration(backoff)
+}
+
+// HandleRetryRefunds 重试到期的 REFUNDING 订单退款。
+// 成功后经 markOrderRefundedAndReturnItem 置 REFUNDED 并归还库存;失败按指数退避安排下次重试,
+// 达到最大次数后标记升级,不再自动重试,由管理员在后台处理。
+func HandleRetryRefunds(ctx context.Context, _ *asynq.Task) error {
+	var orders []PaymentOrder
+	if err := db.DB(ctx).
+		Where("status = ? AND refund_escalated_at IS NULL AND (next_refund_at IS NULL OR next_refund_at <= ?)",
+			OrderStatusRefunding, time.Now()).
+		Order("next_refund_at ASC").
+		Limit(refundRetryBatchSize).
+		Find(&orders).Error; err != nil {
+		logger.ErrorF(ctx, "payment refund retry: failed to query refunding orders: %v", err)
+		return err
+	}
+
+	maxAttempts, base := refundRetryPolicy()
+	for i := range orders {
+		retryRefund(ctx, &orders[i], maxAttempts, base)
+	}
+	return nil
+}
+
+// claimRefund 以 refund_attempts 为 CAS 条件认领 REFUNDING 订单并计入一次尝试,认领成功者才可调用渠道退款,
+// 避免重试任务与回调的 REFUNDING 分支并发重复退款。认领同时设置租约,处理中断时租约到期后重新进入重试
+func claimRefund(ctx context.Context, order *PaymentOrder) (bool, error) {
+	lease := time.Now().Add(refundClaimLease)
+	result := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("out_trade_no = ? AND status = ? AND refund_attempts = ?", order.OutTradeNo, OrderStatusRefunding, order.RefundAttempts).
+		Updates(map[string]any{
+			"refund_attempts": order.RefundAttempts + 1,
+			"next_refund_at":  &lease,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	order.RefundAttempts++
+	return true, nil
+}
+
+// refundClaimedOrder 对已认领的订单退款:先向渠道查询,已退款(如上次退款成功但未能落库)则不再重复退款;
+// 查询失败时无法确认退款状态,按本次失败处理
+func refundClaimedOrder(ctx context.Context, order *PaymentOrder) error {
+	provider, err := orderProvider(ctx, order)
+	if err != nil {
+		return err
+	}
+	result, err := provider.QueryOrder(ctx, order.OutTradeNo)
+	if err != nil {
+		return fmt.Errorf("query refund status: %w", err)
+	}
+	if result.Refunded {
+		return nil
+	}
+	return provider.Refund(ctx, order.TradeNo, moneyString(order.Amount))
+}
+
+// retryRefund 认领并重试单笔 REFUNDING 订单的退款,返回订单是否已退款。
+// 认领失败说明其他任务或回调正在处理,直接跳过。
+func retryRefund(ctx context.Context, order *PaymentOrder, maxAttempts int, base time.Duration) bool {
+	claimed, err := claimRefund(ctx, order)
+	if err != nil {
+		logger.ErrorF(ctx, "payment refund retry: failed to claim order %s: %v", order.OutTradeNo, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	refundErr := refundClaimedOrder(ctx, order)
+	if refundErr == nil {
+		now := time.Now()
+		processed, err := markOrderRefundedAndReturnItem(ctx, order, map[string]any{
+			"status":         OrderStatusRefunded,
+			"refunded_at":    &now,
+			"next_refund_at": nil,
+		}, OrderStatusRefunding)
+		if err != nil {
+			logger.ErrorF(ctx, "payment refund retry: failed to mark order %s refunded: %v", order.OutTradeNo, err)
+			return false
+		}
+		if processed {
+			logger.InfoF(ctx, "payment refund retry: order %s refunded after %d attempts", order.OutTradeNo, order.RefundAttempts)
+		}
+		return true
+	}
+
+	attempts := order.RefundAttempts
+	now := time.Now()
+	updates := map[string]any{
+		"fail_reason": truncateRuneLen(refundErr.Error(), 200),
+	}
+	if attempts >= maxAttempts {
+		updates["refund_escalated_at"] = &now
+		updates["next_refund_at"] = nil
+	} else {
+		next := now.Add(refundBackoff(base, attempts))
+		updates["next_refund_at"] = &next
+	}
+	result := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("out_trade_no = ? AND status = ? AND refund_attempts = ?", order.OutTradeNo, OrderStatusRefunding, attempts).
+		Updates(updates)
+	if result.Error != nil {
+		logger.ErrorF(ctx, "payment refund retry: failed to update order %s: %v", order.OutTradeNo, result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+	if attempts >= maxAttempts {
+		logger.ErrorF(ctx, "payment refund retry: order %s escalated to admins after %d failed attempts: %v", order.OutTradeNo, attempts, refundErr)
+		return false
+	}
+	logger.WarnF(ctx, "payment refund retry: order %s attempt %d failed: %v", order.OutTradeNo, attempts, refundErr)
+	return false
+}
+
+// ListEscalatedRefunds 分页返回已升级、等待管理员处理的退款订单
+func ListEscalatedRefunds(ctx context.Context, offset, limit int) (int64, []PaymentOrder, error) {
+	query := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("status = ? AND refund_escalated_at IS NOT NULL", OrderStatusRefunding)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	var orders []PaymentOrder
+	if err := query.Order("refund_escalated_at ASC").Offset(offset).Limit(limit).Find(&orders).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, orders, nil
+}
+
+// ResetRefundRetry 清除升级标记与重试计数,订单在下一轮重试任务中立即重试
+func ResetRefundRetry(ctx context.Context, outTradeNo string) (bool, error) {
+	result := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("out_trade_no = ? AND status = ?", outTradeNo, OrderStatusRefunding).
+		Updates(map[string]any{
+			"refund_attempts":     0,
+			"next_refund_at":      nil,
+			"refund_escalated_at": nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,207 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/db"
+)
+
+func TestRefundBackoff(t *testing.T) {
+	base := time.Minute
+	cases := map[int]time.Duration{
+		0:  time.Minute,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		5:  16 * time.Minute,
+		20: maxRefundRetryInterval,
+	}
+	for attempts, want := range cases {
+		if got := refundBackoff(base, attempts); got != want {
+			t.Fatalf("attempts=%d: got %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+// refundingOrder 构造一笔渠道侧已付款、本地发放失败且首次退款失败的 REFUNDING 订单,返回订单号与回调参数
+func refundingOrder(t *testing.T, f *paymentFixture) (string, map[string]string) {
+	t.Helper()
+	f.addItems(t, 1)
+	init := f.initiate(t)
+	params, err := FakePay(f.creds, init.OutTradeNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB(context.Background()).Model(&PaymentOrder{}).
+		Where("out_trade_no = ?", init.OutTradeNo).
+		Updates(map[string]any{"status": OrderStatusRefunding, "trade_no": params["trade_no"]}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return init.OutTradeNo, params
+}
+
+// setFakeLedger 修改 fake 渠道侧的订单,用于模拟退款被拒或已退款
+func setFakeLedger(outTradeNo string, update func(o *fakeOrder)) {
+	fakeLedger.Lock()
+	defer fakeLedger.Unlock()
+	update(fakeLedger.orders[outTradeNo])
+}
+
+func fakeRefunded(outTradeNo string) bool {
+	fakeLedger.Lock()
+	defer fakeLedger.Unlock()
+	return fakeLedger.orders[outTradeNo].refunded
+}
+
+func assertStock(t *testing.T, f *paymentFixture, want int64) {
+	t.Helper()
+	stock, err := f.project.Stock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stock != want {
+		t.Fatalf("stock = %d, want %d", stock, want)
+	}
+}
+
+func TestRetryRefundSucceeds(t *testing.T) {
+	f := setupPayment(t)
+	outTradeNo, _ := refundingOrder(t, f)
+	assertStock(t, f, 0)
+
+	if err := HandleRetryRefunds(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	order := loadOrder(t, outTradeNo)
+	if order.Status != OrderStatusRefunded || order.RefundedAt == nil || order.NextRefundAt != nil {
+		t.Fatalf("order should be refunded, got status %d", order.Status)
+	}
+	if order.RefundAttempts != 1 {
+		t.Fatalf("refund attempts = %d, want 1", order.RefundAttempts)
+	}
+	if !fakeRefunded(outTradeNo) {
+		t.Fatal("provider should have refunded the order")
+	}
+	assertStock(t, f, 1)
+}
+
+func TestRetryRefundSkipsProviderRefundedOrder(t *testing.T) {
+	f := setupPayment(t)
+	outTradeNo, _ := refundingOrder(t, f)
+	// 上次退款在渠道侧已成功,但未能落库;再次调用 Refund 会被渠道拒绝
+	setFakeLedger(outTradeNo, func(o *fakeOrder) { o.refunded = true })
+
+	if err := HandleRetryRefunds(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if order := loadOrder(t, outTradeNo); order.Status != OrderStatusRefunded {
+		t.Fatalf("order should be marked refunded, got status %d (%s)", order.Status, order.FailReason)
+	}
+	assertStock(t, f, 1)
+}
+
+func TestRetryRefundBacksOffAndEscalates(t *testing.T) {
+	f := setupPayment(t)
+	outTradeNo, _ := refundingOrder(t, f)
+	// 金额不一致使 fake 渠道拒绝退款
+	setFakeLedger(outTradeNo, func(o *fakeOrder) { o.money = "9.99" })
+	ctx := context.Background()
+	base := time.Minute
+
+	before := time.Now()
+	retryRefund(ctx, loadOrder(t, outTradeNo), 2, base)
+	order := loadOrder(t, outTradeNo)
+	if order.Status != OrderStatusRefunding || order.RefundAttempts != 1 || order.FailReason == "" {
+		t.Fatalf("unexpected order after first failure: status=%d attempts=%d reason=%q", order.Status, order.RefundAttempts, order.FailReason)
+	}
+	if order.NextRefundAt == nil || order.NextRefundAt.Before(before.Add(base)) {
+		t.Fatalf("next refund should be scheduled after backoff, got %v", order.NextRefundAt)
+	}
+	if order.RefundEscalatedAt != nil {
+		t.Fatal("order should not be escalated before max attempts")
+	}
+
+	retryRefund(ctx, order, 2, base)
+	order = loadOrder(t, outTradeNo)
+	if order.Status != OrderStatusRefunding || order.RefundAttempts != 2 || order.RefundEscalatedAt == nil {
+		t.Fatalf("order should be escalated at max attempts: status=%d attempts=%d", order.Status, order.RefundAttempts)
+	}
+
+	// 已升级的订单不再被重试任务选中
+	setFakeLedger(outTradeNo, func(o *fakeOrder) { o.money = "1.50" })
+	if err := HandleRetryRefunds(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if loadOrder(t, outTradeNo).Status != OrderStatusRefunding || fakeRefunded(outTradeNo) {
+		t.Fatal("escalated order should be left to admins")
+	}
+}
+
+func TestRetryRefundStaleClaimLoses(t *testing.T) {
+	f := setupPayment(t)
+	outTradeNo, _ := refundingOrder(t, f)
+	ctx := context.Background()
+	first := loadOrder(t, outTradeNo)
+	stale := loadOrder(t, outTradeNo)
+
+	// 模拟另一任务已认领、正在调用渠道退款
+	claimed, err := claimRefund(ctx, first)
+	if err != nil || !claimed {
+		t.Fatalf("first claim: claimed=%v err=%v", claimed, err)
+	}
+	// 持有旧快照的并发任务无法认领,也不会调用渠道退款
+	if retryRefund(ctx, stale, 5, time.Minute) {
+		t.Fatal("stale retry should not claim the order")
+	}
+	if fakeRefunded(outTradeNo) {
+		t.Fatal("stale retry should not refund")
+	}
+	if order := loadOrder(t, outTradeNo); order.Status != OrderStatusRefunding || order.RefundAttempts != 1 {
+		t.Fatalf("stale retry should leave the claim intact: status=%d attempts=%d", order.Status, order.RefundAttempts)
+	}
+}
+
+func TestHandleNotifyRetriesRefundingOrder(t *testing.T) {
+	f := setupPayment(t)
+	outTradeNo, params := refundingOrder(t, f)
+	ctx := context.Background()
+
+	if ok, reason := HandleNotify(ctx, params); !ok {
+		t.Fatalf("handle notify: %s", reason)
+	}
+	order := loadOrder(t, outTradeNo)
+	if order.Status != OrderStatusRefunded || order.RefundAttempts != 1 {
+		t.Fatalf("notify should refund the order: status=%d attempts=%d", order.Status, order.RefundAttempts)
+	}
+	assertStock(t, f, 1)
+
+	// 重复回调走幂等分支,不再退款
+	if ok, reason := HandleNotify(ctx, params); !ok || reason != "idempotent" {
+		t.Fatalf("repeated notify: ok=%v reason=%s", ok, reason)
+	}
+}
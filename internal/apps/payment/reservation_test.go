@@ -0,0 +1,127 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/db"
+)
+
+// setupInviteProject 把 fixture 项目改为邀请制,并以 username -> itemID 为买家预留一个 item
+func setupInviteProject(t *testing.T, f *paymentFixture) uint64 {
+	t.Helper()
+	ctx := context.Background()
+	f.project.DistributionType = project.DistributionTypeInvite
+	if err := db.DB(ctx).Model(f.project).Update("distribution_type", project.DistributionTypeInvite).Error; err != nil {
+		t.Fatal(err)
+	}
+	item := project.ProjectItem{ProjectID: f.project.ID, Content: "invite-code"}
+	if err := db.DB(ctx).Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Redis.HSet(ctx, f.project.ItemsKey(), f.payer.Username, item.ID).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB(ctx).Model(f.project).Update("total_items", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	return item.ID
+}
+
+// assertReserved 校验买家在邀请制项目哈希中仍持有该 item,且未出现重复项
+func assertReserved(t *testing.T, f *paymentFixture, itemID uint64) {
+	t.Helper()
+	ctx := context.Background()
+	val, err := db.Redis.HGet(ctx, f.project.ItemsKey(), f.payer.Username).Result()
+	if err != nil {
+		t.Fatalf("payer reservation missing: %v", err)
+	}
+	if val != strconv.FormatUint(itemID, 10) {
+		t.Fatalf("reserved item = %s, want %d", val, itemID)
+	}
+	if n := db.Redis.HLen(ctx, f.project.ItemsKey()).Val(); n != 1 {
+		t.Fatalf("hash size = %d, want 1", n)
+	}
+	if n := db.Redis.LLen(ctx, f.project.ItemsKey()).Val(); n != 0 {
+		t.Fatalf("per-user item should not be pushed to a list, got %d", n)
+	}
+}
+
+func TestInviteReservationKeptOnExpiry(t *testing.T) {
+	f := setupPayment(t)
+	itemID := setupInviteProject(t, f)
+	init := f.initiate(t)
+	// HGet 预占不移除哈希项
+	assertReserved(t, f, itemID)
+
+	expireNow(t, init.OutTradeNo)
+	if err := HandleExpireStaleOrders(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if order := loadOrder(t, init.OutTradeNo); order.Status != OrderStatusFailed {
+		t.Fatalf("unpaid order should fail, got status %d", order.Status)
+	}
+	assertReserved(t, f, itemID)
+}
+
+func TestInviteReservationReturnedOnRefund(t *testing.T) {
+	f := setupPayment(t)
+	itemID := setupInviteProject(t, f)
+	ctx := context.Background()
+	init := f.initiate(t)
+	params, err := FakePay(f.creds, init.OutTradeNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模拟发放中途已 HDel、随后失败并转入退款重试
+	if err := db.Redis.HDel(ctx, f.project.ItemsKey(), f.payer.Username).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB(ctx).Model(&PaymentOrder{}).Where("out_trade_no = ?", init.OutTradeNo).
+		Updates(map[string]any{"status": OrderStatusRefunding, "trade_no": params["trade_no"]}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := HandleRetryRefunds(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if order := loadOrder(t, init.OutTradeNo); order.Status != OrderStatusRefunded {
+		t.Fatalf("order should be refunded, got status %d", order.Status)
+	}
+	// HSet 写回后买家可以重新购买
+	assertReserved(t, f, itemID)
+	again := f.initiate(t)
+	f.pay(t, again.OutTradeNo)
+	if order := loadOrder(t, again.OutTradeNo); order.Status != OrderStatusCompleted || order.ItemID != itemID {
+		t.Fatalf("repurchase should complete with item %d, got status %d item %d", itemID, order.Status, order.ItemID)
+	}
+	if db.Redis.HExists(ctx, f.project.ItemsKey(), f.payer.Username).Val() {
+		t.Fatal("fulfilled reservation should be removed from the hash")
+	}
+}
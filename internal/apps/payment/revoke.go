@@ -0,0 +1,82 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// revokeItem 作废已发放的 item,按需对原订单全额退款。
+// 锁定订单、校验争议与认领退款在作废事务内完成,与 OpenDispute 对订单的 CAS 串行:
+// 争议未结束时只能经争议流程作废并退款。退款在作废事务提交后执行,失败时订单保持 REFUNDING 等待重试,作废结果不回滚。
+// 作废成功但退款失败时同时返回作废记录与退款错误。
+func revokeItem(ctx context.Context, p *project.Project, itemID, operatorID uint64, req *project.RevokeItemRequest) (*project.ProjectItemRevocation, error) {
+	var order PaymentOrder
+	revocation, err := p.RevokeItem(ctx, itemID, operatorID, req, func(tx *gorm.DB) error {
+		return claimRevokedItemOrder(tx, p.ID, itemID, req.Refund, &order)
+	})
+	if err != nil || !req.Refund {
+		return revocation, err
+	}
+
+	refundErr := refundClaimedRevokedOrder(ctx, &order)
+	if err := revocation.MarkRevocationRefunded(db.DB(ctx), order.OutTradeNo, refundErr == nil); err != nil {
+		logger.ErrorF(ctx, "revoke item %d: failed to record refund of order %s: %v", itemID, order.OutTradeNo, err)
+	}
+	return revocation, refundErr
+}
+
+// claimRevokedItemOrder 锁定 item 的已完成或争议中订单并校验争议,需要退款时将订单认领为 REFUNDING
+func claimRevokedItemOrder(tx *gorm.DB, projectID string, itemID uint64, refund bool, order *PaymentOrder) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("item_id = ? AND project_id = ? AND status IN ?", itemID, projectID, []OrderStatus{OrderStatusCompleted, OrderStatusDisputed}).
+		Order("id DESC").
+		First(order).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	pending, err := hasPendingDispute(tx, projectID, itemID)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return errors.New(ErrItemDisputePending)
+	}
+	if !refund {
+		return nil
+	}
+	if order.Status != OrderStatusCompleted || order.OutTradeNo == "" {
+		return errors.New(ErrOrderNotFound)
+	}
+	return claimRevokedOrder(tx, order, OrderStatusCompleted)
+}
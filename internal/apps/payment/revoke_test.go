@@ -0,0 +1,170 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/db"
+)
+
+func TestRevokeItemAndRefund(t *testing.T) {
+	cases := []struct {
+		name      string
+		req       project.RevokeItemRequest
+		stock     int   // 作废前补充的库存
+		wantStock int64 // 作废后剩余的库存
+		prepare   func(t *testing.T, f *paymentFixture, order *PaymentOrder)
+		want      OrderStatus
+		revoked   bool
+		refunded  bool
+		err       string
+	}{
+		{
+			name:     "release and refund",
+			req:      project.RevokeItemRequest{Action: project.RevokeActionRelease, Refund: true},
+			want:     OrderStatusRefunded,
+			revoked:  true,
+			refunded: true,
+		},
+		{
+			name:     "replace from stock and refund",
+			req:      project.RevokeItemRequest{Action: project.RevokeActionReplace, Refund: true},
+			stock:    1,
+			want:     OrderStatusRefunded,
+			revoked:  true,
+			refunded: true,
+		},
+		{
+			name:      "replace with new content and refund",
+			req:       project.RevokeItemRequest{Action: project.RevokeActionReplace, ReplacementContent: "new-code", Refund: true},
+			stock:     1,
+			wantStock: 1,
+			want:      OrderStatusRefunded,
+			revoked:   true,
+			refunded:  true,
+		},
+		{
+			name:    "replace without refund",
+			req:     project.RevokeItemRequest{Action: project.RevokeActionReplace, ReplacementContent: "new-code"},
+			want:    OrderStatusCompleted,
+			revoked: true,
+		},
+		{
+			name: "provider rejects refund",
+			req:  project.RevokeItemRequest{Action: project.RevokeActionRelease, Refund: true},
+			prepare: func(t *testing.T, f *paymentFixture, order *PaymentOrder) {
+				setFakeLedger(order.OutTradeNo, func(o *fakeOrder) { o.money = "9.99" })
+			},
+			want:    OrderStatusRefunding,
+			revoked: true,
+			err:     "refund rejected",
+		},
+		{
+			name: "order already refunding",
+			req:  project.RevokeItemRequest{Action: project.RevokeActionRelease, Refund: true},
+			prepare: func(t *testing.T, f *paymentFixture, order *PaymentOrder) {
+				if err := db.DB(context.Background()).Model(order).Update("status", OrderStatusRefunding).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: OrderStatusRefunding,
+			err:  ErrOrderNotFound,
+		},
+		{
+			name: "open dispute",
+			req:  project.RevokeItemRequest{Action: project.RevokeActionRelease, Refund: true},
+			prepare: func(t *testing.T, f *paymentFixture, order *PaymentOrder) {
+				if _, err := OpenDispute(context.Background(), f.payer.ID, order.OutTradeNo, "broken"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: OrderStatusDisputed,
+			err:  ErrItemDisputePending,
+		},
+		{
+			name: "rejected dispute awaiting appeal",
+			req:  project.RevokeItemRequest{Action: project.RevokeActionReplace, ReplacementContent: "new-code"},
+			prepare: func(t *testing.T, f *paymentFixture, order *PaymentOrder) {
+				ctx := context.Background()
+				d, err := OpenDispute(ctx, f.payer.ID, order.OutTradeNo, "broken")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := RejectDispute(ctx, f.creator.ID, d.ID, "works for me"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: OrderStatusCompleted,
+			err:  ErrItemDisputePending,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := setupPayment(t)
+			order := completedOrder(t, f)
+			ctx := context.Background()
+			if tc.stock > 0 {
+				f.addItems(t, tc.stock)
+			}
+			if tc.prepare != nil {
+				tc.prepare(t, f, order)
+			}
+
+			revocation, err := revokeItem(ctx, f.project, order.ItemID, f.creator.ID, &tc.req)
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err != "" && (err == nil || err.Error() != tc.err) {
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			}
+			if (revocation != nil) != tc.revoked || (loadItem(t, order.ItemID).RevokedAt != nil) != tc.revoked {
+				t.Fatalf("revocation = %+v, want revoked %v", revocation, tc.revoked)
+			}
+
+			stored := loadOrder(t, order.OutTradeNo)
+			if stored.Status != tc.want {
+				t.Fatalf("order status = %d, want %d", stored.Status, tc.want)
+			}
+			if fakeRefunded(order.OutTradeNo) != tc.refunded {
+				t.Fatalf("provider refunded = %v, want %v", fakeRefunded(order.OutTradeNo), tc.refunded)
+			}
+			if revocation != nil && tc.req.Refund && (revocation.Refunded != tc.refunded || revocation.OutTradeNo != order.OutTradeNo) {
+				t.Fatalf("revocation refund not recorded: %+v", revocation)
+			}
+			// 作废的 item 不归还库存
+			assertStock(t, f, tc.wantStock)
+			if tc.want == OrderStatusRefunding && tc.revoked {
+				// 失败的退款计入一次尝试,退避后交由重试任务
+				if stored.RefundAttempts != 1 || stored.FailReason == "" || stored.NextRefundAt == nil || !stored.NextRefundAt.After(time.Now()) {
+					t.Fatalf("failed refund not scheduled for retry: %+v", stored)
+				}
+			}
+		})
+	}
+}
@@ -25,14 +25,21 @@
 package payment
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/apps/project"
 	"github.com/linux-do/cdk/internal/config"
 	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -44,12 +51,14 @@ type Response struct {
 
 // GetPaymentConfigResponseData 当前用户支付配置的安全视图
 type GetPaymentConfigResponseData struct {
-	HasConfig         bool   `json:"has_config"`
-	ClientID          string `json:"client_id"`
-	SecretLast4       string `json:"secret_last4"`
-	CallbackNotifyURL string `json:"callback_notify_url"`
-	CallbackReturnURL string `json:"callback_return_url"`
-	PaymentEnabled    bool   `json:"payment_enabled"`
+	HasConfig         bool     `json:"has_config"`
+	Provider          string   `json:"provider"`
+	Providers         []string `json:"providers"`
+	ClientID          string   `json:"client_id"`
+	SecretLast4       string   `json:"secret_last4"`
+	CallbackNotifyURL string   `json:"callback_notify_url"`
+	CallbackReturnURL string   `json:"callback_return_url"`
+	PaymentEnabled    bool     `json:"payment_enabled"`
 }
 
 // GetPaymentConfig GET /api/v1/users/payment-config
@@ -63,12 +72,14 @@ func GetPaymentConfig(c *gin.Context) {
 	}
 	notifyURL, returnURL := CallbackURLs()
 	resp := GetPaymentConfigResponseData{
+		Providers:         ProviderNames(),
 		CallbackNotifyURL: notifyURL,
 		CallbackReturnURL: returnURL,
 		PaymentEnabled:    config.Config.Payment.Enabled,
 	}
 	if cfg != nil {
 		resp.HasConfig = true
+		resp.Provider = cfg.Provider
 		resp.ClientID = cfg.ClientID
 		resp.SecretLast4 = cfg.SecretLast4
 	}
@@ -77,6 +88,7 @@ func GetPaymentConfig(c *gin.Context) {
 
 // UpsertPaymentConfigRequest PUT 请求体
 type UpsertPaymentConfigRequest struct {
+	Provider     string `json:"provider" binding:"omitempty,max=32"`
 	ClientID     string `json:"client_id" binding:"required,min=1,max=64"`
 	ClientSecret string `json:"client_secret" binding:"required,min=1,max=256"`
 }
@@ -93,7 +105,7 @@ func UpsertPaymentConfig(c *gin.Context) {
 		return
 	}
 	userID := oauth.GetUserIDFromContext(c)
-	if err := SaveUserPaymentConfig(c.Request.Context(), userID, req.ClientID, req.ClientSecret); err != nil {
+	if err := SaveUserPaymentConfig(c.Request.Context(), userID, req.Provider, req.ClientID, req.ClientSecret); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
 		return
 	}
@@ -117,9 +129,15 @@ type ReceiveResponse struct {
 	PayURL         string `json:"pay_url,omitempty"`
 	OutTradeNo     string `json:"out_trade_no,omitempty"`
 	Amount         string `json:"amount,omitempty"`
+	OriginalAmount string `json:"original_amount,omitempty"`
 	ExpireAt       string `json:"expire_at,omitempty"`
 }
 
+// ReceiveRequest 领取请求体,可选;付费项目可附带优惠码
+type ReceiveRequest struct {
+	CouponCode string `json:"coupon_code" binding:"omitempty,max=32"`
+}
+
 // DispatchReceive POST /api/v1/projects/:id/receive
 // 运行在 project.ReceiveProjectMiddleware() 之后,已通过资格校验并在 context 注入 project。
 // 付费项目:返回 {require_payment:true, pay_url, ...};前端直接跳转 pay_url。
@@ -139,9 +157,14 @@ func DispatchReceive(c *gin.Context) {
 
 	// 付费分叉
 	if p.IsPaid() {
-		init, err := InitiatePayment(ctx, p, currentUser, c.ClientIP())
+		var req ReceiveRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+			return
+		}
+		init, err := InitiatePayment(ctx, p, currentUser, c.ClientIP(), req.CouponCode)
 		if err != nil {
-			if err.Error() == ErrPendingOrderExists {
+			if err.Error() == ErrPendingOrderExists || err.Error() == ErrClaimQuotaReached || err.Error() == ErrCouponInvalid {
 				c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
 				return
 			}
@@ -153,6 +176,7 @@ func DispatchReceive(c *gin.Context) {
 			PayURL:         init.PayURL,
 			OutTradeNo:     init.OutTradeNo,
 			Amount:         init.Amount,
+			OriginalAmount: init.OriginalAmount,
 			ExpireAt:       init.ExpireAt.Format("2006-01-02 15:04:05"),
 		}})
 		return
@@ -180,7 +204,572 @@ func DispatchReceive(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, project.ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, project.ProjectResponse{Data: ReceiveResponse{ItemContent: item.Content}})
+	content, err := item.PlainContent()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, project.ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, project.ProjectResponse{Data: ReceiveResponse{ItemContent: content}})
+}
+
+// RevokeItem POST /api/v1/projects/:id/items/:item_id/revoke
+// 运行在 project.ProjectCreatorPermMiddleware() 之后。作废已发放的 item,按需对原订单全额退款,见 revokeItem。
+func RevokeItem(c *gin.Context) {
+	ctx := c.Request.Context()
+	p, _ := project.GetProjectFromContext(c)
+
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	var req project.RevokeItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	if err := req.Validate(p); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+
+	revocation, err := revokeItem(ctx, p, itemID, oauth.GetUserIDFromContext(c), &req)
+	if revocation == nil {
+		c.JSON(revokeErrorStatus(err), Response{ErrorMsg: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error(), Data: revocation})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: revocation})
+}
+
+// ExportProjectItems
+// @Tags project
+// @Produce octet-stream
+// @Param id path string true "项目ID"
+// @Param request query project.ExportProjectItemsRequest true "request query"
+// @Success 200 {file} file
+// @Router /api/v1/projects/{id}/items/export [get]
+func ExportProjectItems(c *gin.Context) {
+	// load project
+	p, _ := project.GetProjectFromContext(c)
+
+	// validate req
+	req := &project.ExportProjectItemsRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, project.ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = project.ExportFormatCSV
+	}
+
+	// write headers
+	contentType := "text/csv; charset=utf-8"
+	if req.Format == project.ExportFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="project-%s-items.%s"`, p.ID, req.Format))
+	c.Status(http.StatusOK)
+
+	// stream rows, the status has been sent so errors can only be logged
+	writer, err := project.NewExportWriter(c.Writer, req.Format)
+	if err == nil {
+		err = p.ExportItems(c.Request.Context(), writer, exportOrderStatuses(), c.Writer.Flush)
+	}
+	if err != nil {
+		logger.ErrorF(c.Request.Context(), "export items of project %s failed: %v", p.ID, err)
+		_ = c.Error(err)
+	}
+}
+
+// ListEscalatedRefundsRequest 升级退款列表分页参数
+type ListEscalatedRefundsRequest struct {
+	Current int `json:"current" form:"current" binding:"min=1"`
+	Size    int `json:"size" form:"size" binding:"min=1,max=100"`
+}
+
+// ListEscalatedRefundsResponseData 升级退款列表
+type ListEscalatedRefundsResponseData struct {
+	Total   int64          `json:"total"`
+	Results []PaymentOrder `json:"results"`
+}
+
+// ListEscalatedRefundsHTTP GET /api/v1/admin/payment/refunds/escalated
+// 管理员查看自动重试已达上限、等待人工处理的退款订单。
+func ListEscalatedRefundsHTTP(c *gin.Context) {
+	var req ListEscalatedRefundsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	total, orders, err := ListEscalatedRefunds(c.Request.Context(), (req.Current-1)*req.Size, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: ListEscalatedRefundsResponseData{Total: total, Results: orders}})
+}
+
+// RetryRefundHTTP POST /api/v1/admin/payment/orders/:out_trade_no/refund/retry
+// 管理员确认渠道恢复后重置重试计数,订单在下一轮重试任务中立即重试。
+func RetryRefundHTTP(c *gin.Context) {
+	ok, err := ResetRefundRetry(c.Request.Context(), c.Param("out_trade_no"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, Response{ErrorMsg: ErrRefundingOrderNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, Response{})
+}
+
+// ListPriceRulesHTTP GET /api/v1/projects/:id/price-rules
+// 创建者查看项目的优惠规则。
+func ListPriceRulesHTTP(c *gin.Context) {
+	p, _ := project.GetProjectFromContext(c)
+	rules, err := ListPriceRules(c.Request.Context(), p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: rules})
+}
+
+// CreatePriceRuleHTTP POST /api/v1/projects/:id/price-rules
+// 创建者为付费项目新增优惠码、早鸟价或信任等级折扣。
+func CreatePriceRuleHTTP(c *gin.Context) {
+	p, _ := project.GetProjectFromContext(c)
+	var req CreatePriceRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	if err := req.Validate(p); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	rule, err := CreatePriceRule(c.Request.Context(), p, &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == ErrTooManyPriceRules || err.Error() == ErrCouponCodeExists {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: rule})
+}
+
+// DeletePriceRuleHTTP DELETE /api/v1/projects/:id/price-rules/:rule_id
+func DeletePriceRuleHTTP(c *gin.Context) {
+	p, _ := project.GetProjectFromContext(c)
+	ruleID, err := strconv.ParseUint(c.Param("rule_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	if err := DeletePriceRule(c.Request.Context(), p.ID, ruleID); err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == ErrPriceRuleNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{})
+}
+
+// QuotePriceRequest 价格预览查询参数
+type QuotePriceRequest struct {
+	CouponCode string `json:"coupon_code" form:"coupon_code" binding:"omitempty,max=32"`
+}
+
+// QuotePriceResponse 价格预览结果,仅返回成交价与命中的规则类型,不暴露规则详情与使用次数
+type QuotePriceResponse struct {
+	OriginalAmount decimal.Decimal `json:"original_amount"`
+	Amount         decimal.Decimal `json:"amount"`
+	RuleType       PriceRuleType   `json:"rule_type,omitempty"`
+}
+
+// QuotePriceHTTP GET /api/v1/projects/:id/price
+// 预览当前用户领取付费项目的成交价,优惠码无效时返回 400,携带优惠码的查询按用户限频。
+func QuotePriceHTTP(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req QuotePriceRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	var p project.Project
+	if err := p.Exact(db.DB(ctx), c.Param("id"), true); err != nil {
+		c.JSON(http.StatusNotFound, Response{ErrorMsg: err.Error()})
+		return
+	}
+	currentUser, _ := oauth.GetUserFromContext(c)
+	if visible, err := p.IsVisibleTo(db.DB(ctx), currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	} else if !visible {
+		c.JSON(http.StatusNotFound, Response{ErrorMsg: project.NotFound})
+		return
+	}
+	if !p.IsPaid() {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: ErrPriceRuleRequiresPaid})
+		return
+	}
+	if req.CouponCode != "" {
+		if allowed, err := allowCouponQuote(ctx, currentUser.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+			return
+		} else if !allowed {
+			c.JSON(http.StatusTooManyRequests, Response{ErrorMsg: ErrCouponQuoteTooFrequent})
+			return
+		}
+	}
+	quote, err := QuotePrice(ctx, &p, currentUser, req.CouponCode)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == ErrCouponInvalid {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, Response{ErrorMsg: err.Error()})
+		return
+	}
+	resp := QuotePriceResponse{OriginalAmount: quote.OriginalAmount, Amount: quote.Amount}
+	if quote.Rule != nil {
+		resp.RuleType = quote.Rule.Type
+	}
+	c.JSON(http.StatusOK, Response{Data: resp})
+}
+
+// GetOrderRequest 订单状态查询参数,wait>0 时长轮询直到订单处理完成或超时
+type GetOrderRequest struct {
+	Wait int `json:"wait" form:"wait" binding:"omitempty,min=0,max=30"`
+}
+
+// GetOrderHTTP GET /api/v1/payment/orders/:out_trade_no
+// 支付回跳后前端查询订单状态;订单完成时返回领取到的内容。
+func GetOrderHTTP(c *gin.Context) {
+	var req GetOrderRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	view, err := WaitOrderStatus(c.Request.Context(), oauth.GetUserIDFromContext(c), c.Param("out_trade_no"), time.Duration(req.Wait)*time.Second)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == ErrOrderNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: view})
+}
+
+// OpenDisputeRequest 发起争议请求体
+type OpenDisputeRequest struct {
+	Reason string `json:"reason" binding:"required,min=1,max=512"`
+}
+
+// OpenDisputeHTTP POST /api/v1/payment/orders/:out_trade_no/dispute
+// 买家对自己已完成的订单发起退款争议。
+func OpenDisputeHTTP(c *gin.Context) {
+	var req OpenDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	d, err := OpenDispute(c.Request.Context(), oauth.GetUserIDFromContext(c), c.Param("out_trade_no"), req.Reason)
+	if err != nil {
+		c.JSON(disputeErrorStatus(err), Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: d})
+}
+
+// ListDisputesRequest 争议列表查询参数,role=payer 查看自己发起的,role=payee 查看针对自己项目的
+type ListDisputesRequest struct {
+	Role    string         `json:"role" form:"role" binding:"omitempty,oneof=payer payee"`
+	Status  *DisputeStatus `json:"status" form:"status" binding:"omitempty,oneof=0 1 2 3 4"`
+	Current int            `json:"current" form:"current" binding:"min=1"`
+	Size    int            `json:"size" form:"size" binding:"min=1,max=100"`
+}
+
+// ListDisputesResponseData 争议列表
+type ListDisputesResponseData struct {
+	Total   int64            `json:"total"`
+	Results []PaymentDispute `json:"results"`
+}
+
+// ListDisputesHTTP GET /api/v1/payment/disputes
+func ListDisputesHTTP(c *gin.Context) {
+	var req ListDisputesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	userID := oauth.GetUserIDFromContext(c)
+	var payerID, payeeID uint64
+	if req.Role == "payee" {
+		payeeID = userID
+	} else {
+		payerID = userID
+	}
+	total, disputes, err := ListDisputes(c.Request.Context(), payerID, payeeID, req.Status, (req.Current-1)*req.Size, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: ListDisputesResponseData{Total: total, Results: disputes}})
+}
+
+// RespondDisputeRequest 创建者处理争议的请求体
+type RespondDisputeRequest struct {
+	Response string `json:"response" binding:"max=512"`
+}
+
+// AcceptDisputeHTTP POST /api/v1/payment/disputes/:id/accept
+// 创建者同意退款,作废 item 并全额退款。
+func AcceptDisputeHTTP(c *gin.Context) {
+	respondDispute(c, AcceptDispute)
+}
+
+// RejectDisputeHTTP POST /api/v1/payment/disputes/:id/reject
+// 创建者拒绝退款,买家可申请管理员仲裁。
+func RejectDisputeHTTP(c *gin.Context) {
+	respondDispute(c, RejectDispute)
+}
+
+func respondDispute(c *gin.Context, handle func(context.Context, uint64, uint64, string) (*PaymentDispute, error)) {
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	var req RespondDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	d, err := handle(c.Request.Context(), oauth.GetUserIDFromContext(c), disputeID, req.Response)
+	if err != nil {
+		c.JSON(disputeErrorStatus(err), Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: d})
+}
+
+// AppealDisputeHTTP POST /api/v1/payment/disputes/:id/appeal
+// 买家对被拒绝的争议申请管理员仲裁。
+func AppealDisputeHTTP(c *gin.Context) {
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	d, err := AppealDispute(c.Request.Context(), oauth.GetUserIDFromContext(c), disputeID)
+	if err != nil {
+		c.JSON(disputeErrorStatus(err), Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: d})
+}
+
+// AdminListDisputesRequest 管理员争议列表查询参数
+type AdminListDisputesRequest struct {
+	Status  *DisputeStatus `json:"status" form:"status" binding:"omitempty,oneof=0 1 2 3 4"`
+	Current int            `json:"current" form:"current" binding:"min=1"`
+	Size    int            `json:"size" form:"size" binding:"min=1,max=100"`
+}
+
+// AdminListDisputesHTTP GET /api/v1/admin/payment/disputes
+func AdminListDisputesHTTP(c *gin.Context) {
+	var req AdminListDisputesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	total, disputes, err := ListDisputes(c.Request.Context(), 0, 0, req.Status, (req.Current-1)*req.Size, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: ListDisputesResponseData{Total: total, Results: disputes}})
+}
+
+// ArbitrateDisputeRequest 管理员仲裁请求体
+type ArbitrateDisputeRequest struct {
+	Refund bool   `json:"refund"`
+	Note   string `json:"note" binding:"max=512"`
+}
+
+// ArbitrateDisputeHTTP POST /api/v1/admin/payment/disputes/:id/arbitrate
+// 管理员仲裁:refund=true 退款并作废 item,否则驳回争议。
+func ArbitrateDisputeHTTP(c *gin.Context) {
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	var req ArbitrateDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return
+	}
+	d, err := ArbitrateDispute(c.Request.Context(), oauth.GetUserIDFromContext(c), disputeID, req.Refund, req.Note)
+	if err != nil {
+		c.JSON(disputeErrorStatus(err), Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: d})
+}
+
+// revokeErrorStatus 将作废前的订单与争议校验错误映射为 HTTP 状态码
+func revokeErrorStatus(err error) int {
+	switch err.Error() {
+	case ErrOrderNotFound, ErrItemDisputePending, ErrOrderStatusChanged:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// disputeErrorStatus 将争议相关业务错误映射为 HTTP 状态码
+func disputeErrorStatus(err error) int {
+	switch err.Error() {
+	case ErrOrderNotFound, ErrDisputeNotFound:
+		return http.StatusNotFound
+	case ErrDisputeForbidden:
+		return http.StatusForbidden
+	case ErrDisputeExists, ErrDisputeOrderNotCompleted, ErrDisputeWindowClosed, ErrDisputeStateChanged:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListLedgerRequest 订单/销售查询参数,format=csv 时忽略分页并导出全部
+type ListLedgerRequest struct {
+	ProjectID string       `json:"project_id" form:"project_id" binding:"omitempty,max=64"`
+	Status    *OrderStatus `json:"status" form:"status" binding:"omitempty,oneof=0 1 2 3 4 5 6"`
+	StartTime *time.Time   `json:"start_time" form:"start_time"`
+	EndTime   *time.Time   `json:"end_time" form:"end_time"`
+	Format    string       `json:"format" form:"format" binding:"omitempty,oneof=csv"`
+	Current   int          `json:"current" form:"current" binding:"omitempty,min=1"`
+	Size      int          `json:"size" form:"size" binding:"omitempty,min=1,max=100"`
+}
+
+// ListOrdersResponseData 买家订单列表
+type ListOrdersResponseData struct {
+	Total   int64       `json:"total"`
+	Results []LedgerRow `json:"results"`
+}
+
+// ListSalesResponseData 创建者销售明细与收入汇总
+type ListSalesResponseData struct {
+	Total          int64            `json:"total"`
+	Results        []LedgerRow      `json:"results"`
+	Projects       []ProjectRevenue `json:"projects"`
+	TotalRevenue   decimal.Decimal  `json:"total_revenue"`
+	TotalRefunded  decimal.Decimal  `json:"total_refunded"`
+	TotalOrders    int64            `json:"total_orders"`
+	TotalRefundCnt int64            `json:"total_refund_count"`
+}
+
+// bindLedgerRequest 解析查询参数并构造过滤条件
+func bindLedgerRequest(c *gin.Context) (*ListLedgerRequest, *LedgerFilter, bool) {
+	var req ListLedgerRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: err.Error()})
+		return nil, nil, false
+	}
+	if req.StartTime != nil && req.EndTime != nil && !req.EndTime.After(*req.StartTime) {
+		c.JSON(http.StatusBadRequest, Response{ErrorMsg: ErrInvalidTimeRange})
+		return nil, nil, false
+	}
+	if req.Current == 0 {
+		req.Current = 1
+	}
+	if req.Size == 0 {
+		req.Size = 20
+	}
+	return &req, &LedgerFilter{
+		ProjectID: req.ProjectID,
+		Status:    req.Status,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}, true
+}
+
+// exportLedger 以 CSV 附件流式导出,响应头发出后错误只能记录日志
+func exportLedger(c *gin.Context, f *LedgerFilter, name string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, name, time.Now().Format("20060102150405")))
+	c.Status(http.StatusOK)
+	if err := ExportLedgerCSV(c.Request.Context(), f, c.Writer); err != nil {
+		logger.ErrorF(c.Request.Context(), "export payment %s failed: %v", name, err)
+		_ = c.Error(err)
+	}
+}
+
+// ListOrdersHTTP GET /api/v1/payment/orders
+// 当前用户作为买家的订单,支持按项目、状态、创建时间过滤,format=csv 导出。
+func ListOrdersHTTP(c *gin.Context) {
+	req, filter, ok := bindLedgerRequest(c)
+	if !ok {
+		return
+	}
+	filter.PayerID = oauth.GetUserIDFromContext(c)
+	if req.Format == "csv" {
+		exportLedger(c, filter, "orders")
+		return
+	}
+	total, rows, err := ListLedger(c.Request.Context(), filter, (req.Current-1)*req.Size, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, Response{Data: ListOrdersResponseData{Total: total, Results: rows}})
+}
+
+// ListSalesHTTP GET /api/v1/payment/sales
+// 当前用户作为收款方的销售明细及按项目的收入汇总,format=csv 导出明细。
+func ListSalesHTTP(c *gin.Context) {
+	req, filter, ok := bindLedgerRequest(c)
+	if !ok {
+		return
+	}
+	filter.PayeeID = oauth.GetUserIDFromContext(c)
+	if req.Format == "csv" {
+		exportLedger(c, filter, "sales")
+		return
+	}
+	ctx := c.Request.Context()
+	total, rows, err := ListLedger(ctx, filter, (req.Current-1)*req.Size, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	projects, err := SumRevenueByProject(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{ErrorMsg: err.Error()})
+		return
+	}
+	data := ListSalesResponseData{Total: total, Results: rows, Projects: projects}
+	for _, p := range projects {
+		data.TotalRevenue = data.TotalRevenue.Add(p.Revenue)
+		data.TotalRefunded = data.TotalRefunded.Add(p.RefundedAmount)
+		data.TotalOrders += p.OrderCount
+		data.TotalRefundCnt += p.RefundCount
+	}
+	c.JSON(http.StatusOK, Response{Data: data})
 }
 
 // HandleNotifyHTTP GET /api/v1/payment/notify
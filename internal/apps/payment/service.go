@@ -55,15 +55,22 @@ func GetUserPaymentConfig(ctx context.Context, userID uint64) (*UserPaymentConfi
 	return &cfg, nil
 }
 
-// SaveUserPaymentConfig 保存/更新用户的支付凭据,clientSecret 明文进入后会被加密。
-func SaveUserPaymentConfig(ctx context.Context, userID uint64, clientID, clientSecret string) error {
+// SaveUserPaymentConfig 保存/更新用户的支付凭据,clientSecret 明文进入后会被加密;provider 为空时使用易支付。
+func SaveUserPaymentConfig(ctx context.Context, userID uint64, provider, clientID, clientSecret string) error {
 	if clientID == "" || clientSecret == "" {
 		return errors.New(ErrInvalidClientCredentials)
 	}
-	if err := validateEncryptionKeyConfigured(); err != nil {
+	if provider == "" {
+		provider = ProviderEasyPay
+	}
+	if !IsProviderRegistered(provider) {
+		return errors.New(ErrProviderNotSupported)
+	}
+	ring, err := currentKeyring()
+	if err != nil {
 		return err
 	}
-	enc, err := EncryptSecret(clientSecret, config.Config.Payment.ConfigEncryptionKey)
+	enc, err := ring.Encrypt(clientSecret)
 	if err != nil {
 		return err
 	}
@@ -75,6 +82,7 @@ func SaveUserPaymentConfig(ctx context.Context, userID uint64, clientID, clientS
 	queryErr := db.DB(ctx).Where("user_id = ?", userID).First(cfg).Error
 	if errors.Is(queryErr, gorm.ErrRecordNotFound) {
 		cfg.UserID = userID
+		cfg.Provider = provider
 		cfg.ClientID = clientID
 		cfg.ClientSecretEnc = enc
 		cfg.SecretLast4 = last4
@@ -82,6 +90,7 @@ func SaveUserPaymentConfig(ctx context.Context, userID uint64, clientID, clientS
 	} else if queryErr != nil {
 		return queryErr
 	}
+	cfg.Provider = provider
 	cfg.ClientID = clientID
 	cfg.ClientSecretEnc = enc
 	cfg.SecretLast4 = last4
@@ -108,10 +117,64 @@ func DeleteUserPaymentConfig(ctx context.Context, userID uint64) error {
 
 // decryptUserClientSecret 解密指定配置的 clientSecret。
 func decryptUserClientSecret(cfg *UserPaymentConfig) (string, error) {
-	if err := validateEncryptionKeyConfigured(); err != nil {
+	ring, err := currentKeyring()
+	if err != nil {
 		return "", err
 	}
-	return DecryptSecret(cfg.ClientSecretEnc, config.Config.Payment.ConfigEncryptionKey)
+	return ring.Decrypt(cfg.ClientSecretEnc)
+}
+
+// reencryptBatchSize 重新加密支付凭据时每批处理的行数
+const reencryptBatchSize = 200
+
+// ReencryptPaymentSecrets 分批将未使用活动密钥或仍为旧版密钥派生的 clientSecret 重新加密,返回重新加密的行数。
+// 更新时以旧密文作为条件,避免覆盖并发写入的新凭据。
+func ReencryptPaymentSecrets(ctx context.Context) (int64, error) {
+	ring, err := currentKeyring()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		total      int64
+		lastUserID uint64
+	)
+	for {
+		var cfgs []UserPaymentConfig
+		if err := db.DB(ctx).
+			Where("user_id > ?", lastUserID).
+			Order("user_id ASC").
+			Limit(reencryptBatchSize).
+			Find(&cfgs).Error; err != nil {
+			return total, err
+		}
+		if len(cfgs) == 0 {
+			return total, nil
+		}
+		lastUserID = cfgs[len(cfgs)-1].UserID
+
+		for _, cfg := range cfgs {
+			if !ring.NeedsRotation(cfg.ClientSecretEnc) {
+				continue
+			}
+			plain, err := ring.Decrypt(cfg.ClientSecretEnc)
+			if err != nil {
+				return total, fmt.Errorf("decrypt payment config of user %d: %w", cfg.UserID, err)
+			}
+			enc, err := ring.Encrypt(plain)
+			if err != nil {
+				return total, err
+			}
+			result := db.DB(ctx).
+				Model(&UserPaymentConfig{}).
+				Where("user_id = ? AND client_secret_enc = ?", cfg.UserID, cfg.ClientSecretEnc).
+				UpdateColumn("client_secret_enc", enc)
+			if result.Error != nil {
+				return total, result.Error
+			}
+			total += result.RowsAffected
+		}
+	}
 }
 
 // genOutTradeNo 生成本地订单号:CDK + yyyyMMddHHmmss + 8 位随机十六进制。
@@ -128,17 +191,18 @@ func moneyString(d decimal.Decimal) string {
 
 // PaymentInitiation 返回给前端的发起支付信息
 type PaymentInitiation struct {
-	OutTradeNo string    `json:"out_trade_no"`
-	PayURL     string    `json:"pay_url"`
-	Amount     string    `json:"amount"`
-	ExpireAt   time.Time `json:"expire_at"`
+	OutTradeNo     string    `json:"out_trade_no"`
+	PayURL         string    `json:"pay_url"`
+	Amount         string    `json:"amount"`
+	OriginalAmount string    `json:"original_amount"`
+	ExpireAt       time.Time `json:"expire_at"`
 }
 
 // InitiatePayment 为付费项目的一次领取行为创建支付订单,并返回前端可直接跳转的支付 URL。
 // 调用方已通过 ReceiveProjectMiddleware 的前置校验。
-// 流程:载入商户凭据 → Redis LPop 预占 item → 持久化订单 PENDING → 构造 submit URL 返回。
+// 流程:载入商户凭据 → 定价引擎计算成交价 → Redis LPop 预占 item → 持久化订单 PENDING → 构造 submit URL 返回。
 // 若创建订单失败或拼接失败,需立即把 itemID RPush 回 Redis 以恢复库存。
-func InitiatePayment(ctx context.Context, p *project.Project, payer *oauth.User, clientIP string) (*PaymentInitiation, error) {
+func InitiatePayment(ctx context.Context, p *project.Project, payer *oauth.User, clientIP, couponCode string) (*PaymentInitiation, error) {
 	if !config.Config.Payment.Enabled {
 		return nil, errors.New(ErrPaymentDisabled)
 	}
@@ -153,7 +217,7 @@ func InitiatePayment(ctx context.Context, p *project.Project, payer *oauth.User,
 	if cfg == nil {
 		return nil, errors.New(ErrCreatorNotConfigured)
 	}
-	secret, err := decryptUserClientSecret(cfg)
+	provider, err := configProvider(cfg, "")
 	if err != nil {
 		return nil, err
 	}
@@ -189,13 +253,33 @@ func InitiatePayment(ctx context.Context, p *project.Project, payer *oauth.User,
 					OrderStatusPending,
 					OrderStatusPaid,
 					OrderStatusCompleted,
+					OrderStatusDisputed,
 				},
 			).
 			Count(&existedCount).Error; err != nil {
 			return err
 		}
-		if existedCount > 0 {
-			return errors.New(ErrPendingOrderExists)
+		// 进行中与已完成订单合计不得超过该用户的领取上限
+		if existedCount >= p.ClaimQuotaFor(payer.TrustLevel) {
+			var pendingCount int64
+			if err := tx.Model(&PaymentOrder{}).
+				Where("project_id = ? AND payer_id = ? AND status = ?", p.ID, payer.ID, OrderStatusPending).
+				Count(&pendingCount).Error; err != nil {
+				return err
+			}
+			if pendingCount > 0 {
+				return errors.New(ErrPendingOrderExists)
+			}
+			return errors.New(ErrClaimQuotaReached)
+		}
+
+		// 计算成交价并占用优惠码次数,事务回滚时一并撤销
+		quote, err := quotePrice(tx, p, payer, couponCode, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := consumeCouponUse(tx, quote.Rule); err != nil {
+			return err
 		}
 
 		// 预占 item(Redis LPOP 原子)
@@ -206,45 +290,62 @@ func InitiatePayment(ctx context.Context, p *project.Project, payer *oauth.User,
 		itemID = reservedItemID
 		logger.InfoF(ctx, "Reserved item %d for project %s and payer %d", itemID, p.ID, payer.ID)
 
+		var (
+			priceRuleID   *uint64
+			priceRuleType PriceRuleType
+		)
+		if quote.Rule != nil {
+			priceRuleID = &quote.Rule.ID
+			priceRuleType = quote.Rule.Type
+		}
+
 		outTradeNo := genOutTradeNo()
 		order := PaymentOrder{
-			OutTradeNo:    outTradeNo,
-			ProjectID:     p.ID,
-			ItemID:        itemID,
-			PayerID:       payer.ID,
-			PayeeID:       p.CreatorID,
-			PayeeClientID: cfg.ClientID,
-			Amount:        p.Price,
-			Status:        OrderStatusPending,
-			ExpireAt:      expireAt,
-			ClientIP:      clientIP,
+			OutTradeNo:     outTradeNo,
+			ProjectID:      p.ID,
+			ItemID:         itemID,
+			PayerID:        payer.ID,
+			PayeeID:        p.CreatorID,
+			PayeeClientID:  cfg.ClientID,
+			Provider:       provider.Name(),
+			Amount:         quote.Amount,
+			OriginalAmount: quote.OriginalAmount,
+			Status:         OrderStatusPending,
+			PriceRuleID:    priceRuleID,
+			PriceRuleType:  priceRuleType,
+			ExpireAt:       expireAt,
+			ClientIP:       clientIP,
 		}
 		if err := tx.Create(&order).Error; err != nil {
 			return err
 		}
 
 		// 构造支付跳转 URL(名称最长 64)
-		name := truncateRuneLen("CDK-"+p.Name, 60)
-		init = PaymentInitiation{
+		payURL, err := provider.PayURL(ctx, &PayRequest{
 			OutTradeNo: outTradeNo,
-			PayURL: submitURL(
-				cfg.ClientID,
-				secret,
-				name,
-				moneyString(p.Price),
-				outTradeNo,
-				callbackNotifyURL(),
-				callbackReturnURL(p.ID),
-			),
-			Amount:   moneyString(p.Price),
-			ExpireAt: expireAt,
+			Name:       truncateRuneLen("CDK-"+p.Name, 60),
+			Money:      moneyString(quote.Amount),
+			NotifyURL:  callbackNotifyURL(),
+			ReturnURL:  callbackReturnURL(p.ID),
+		})
+		if err != nil {
+			return err
+		}
+		init = PaymentInitiation{
+			OutTradeNo:     outTradeNo,
+			PayURL:         payURL,
+			Amount:         moneyString(quote.Amount),
+			OriginalAmount: moneyString(quote.OriginalAmount),
+			ExpireAt:       expireAt,
 		}
 		return nil
 	})
 	if err != nil {
 		// 仅在已成功预占 item 的情况下回滚库存。
 		if itemID > 0 {
-			db.Redis.RPush(ctx, p.ItemsKey(), itemID)
+			if returnErr := p.ReturnReservation(ctx, payer.Username, itemID); returnErr != nil {
+				logger.ErrorF(ctx, "payment: failed to return item %d to project %s: %v", itemID, p.ID, returnErr)
+			}
 		}
 		return nil, err
 	}
@@ -284,57 +385,50 @@ func HandleNotify(ctx context.Context, q map[string]string) (bool, string) {
 	if err := db.DB(ctx).Where("out_trade_no = ?", outTradeNo).First(&order).Error; err != nil {
 		return false, fmt.Sprintf("order not found: %v", err)
 	}
-	cfg, err := GetUserPaymentConfig(ctx, order.PayeeID)
-	if err != nil || cfg == nil {
-		return false, "payee config missing"
-	}
-	secret, err := decryptUserClientSecret(cfg)
+	provider, err := orderProvider(ctx, &order)
 	if err != nil {
-		return false, "decrypt secret failed"
+		return false, fmt.Sprintf("payee provider unavailable: %v", err)
 	}
-	if !VerifySign(q, secret) {
-		return false, "sign mismatch"
+	result, err := provider.VerifyNotify(ctx, q)
+	if err != nil {
+		return false, err.Error()
 	}
-	if q["trade_status"] != "TRADE_SUCCESS" {
+	if !result.Paid {
 		return false, "trade_status not success"
 	}
-	if q["pid"] != cfg.ClientID {
-		return false, "pid mismatch"
-	}
-	if q["money"] != moneyString(order.Amount) {
+	if result.Money != moneyString(order.Amount) {
 		return false, "money mismatch"
 	}
 
 	// 幂等分支
-	if order.Status == OrderStatusCompleted || order.Status == OrderStatusRefunded {
+	if order.Status == OrderStatusCompleted || order.Status == OrderStatusRefunded || order.Status == OrderStatusDisputed {
 		return true, "idempotent"
 	}
 
-	// 增加对 Refunding 状态的处理
+	// REFUNDING 订单与重试任务共用认领流程,避免并发重复退款
 	if order.Status == OrderStatusRefunding {
-		refundErr := doEpayRefund(ctx, cfg.ClientID, secret, order.TradeNo, moneyString(order.Amount))
-		if refundErr == nil {
-			tNow := time.Now()
-			processed, updateErr := markOrderRefundedAndReturnItem(ctx, &order, map[string]any{"status": OrderStatusRefunded, "refunded_at": &tNow}, OrderStatusRefunding)
-			if updateErr != nil {
-				logger.ErrorF(ctx, "payment refund retry: failed to mark order %s refunded: %v", outTradeNo, updateErr)
-				return false, "update order status failed"
-			}
-			if !processed {
-				return true, "idempotent"
-			}
+		maxAttempts, base := refundRetryPolicy()
+		if retryRefund(ctx, &order, maxAttempts, base) {
 			return true, "refund retry ok"
 		}
 		return false, "refund retry failed"
 	}
 
+	return confirmPaidOrder(ctx, provider, &order, result.TradeNo)
+}
+
+// confirmPaidOrder 在已确认渠道侧付款后推进订单:CAS PENDING -> PAID,执行发放,发放失败则退款。
+// 由异步回调与主动对账共用,返回值语义同 HandleNotify。
+func confirmPaidOrder(ctx context.Context, provider PaymentProvider, order *PaymentOrder, tradeNo string) (bool, string) {
+	outTradeNo := order.OutTradeNo
+
 	// CAS: PENDING -> PAID
 	now := time.Now()
 	rows := db.DB(ctx).Model(&PaymentOrder{}).
 		Where("out_trade_no = ? AND status = ?", outTradeNo, OrderStatusPending).
 		Updates(map[string]any{
 			"status":   OrderStatusPaid,
-			"trade_no": q["trade_no"],
+			"trade_no": tradeNo,
 			"paid_at":  &now,
 		}).RowsAffected
 	if rows == 0 {
@@ -342,16 +436,18 @@ func HandleNotify(ctx context.Context, q map[string]string) (bool, string) {
 		// 仍返回 success 让对方停止重试,结果以订单最终状态为准
 		return true, "concurrent or non-pending"
 	}
+	// 无论发放成功与否,结束时通知等待该订单的长轮询
+	defer publishOrderEvent(ctx, outTradeNo)
 
 	// 重新读一次订单
-	if err := db.DB(ctx).Where("out_trade_no = ?", outTradeNo).First(&order).Error; err != nil {
+	if err := db.DB(ctx).Where("out_trade_no = ?", outTradeNo).First(order).Error; err != nil {
 		return false, err.Error()
 	}
 
 	// 发放
-	if err := fulfillPaidOrder(ctx, &order); err != nil {
+	if err := fulfillPaidOrder(ctx, order); err != nil {
 		// 退款 + RPush
-		refundErr := doEpayRefund(ctx, cfg.ClientID, secret, order.TradeNo, moneyString(order.Amount))
+		refundErr := provider.Refund(ctx, order.TradeNo, moneyString(order.Amount))
 		updates := map[string]any{
 			"fail_reason": truncateRuneLen(err.Error(), 200),
 		}
@@ -359,7 +455,7 @@ func HandleNotify(ctx context.Context, q map[string]string) (bool, string) {
 			tNow := time.Now()
 			updates["status"] = OrderStatusRefunded
 			updates["refunded_at"] = &tNow
-			if _, updateErr := markOrderRefundedAndReturnItem(ctx, &order, updates, OrderStatusPaid); updateErr != nil {
+			if _, updateErr := markOrderRefundedAndReturnItem(ctx, order, updates, OrderStatusPaid); updateErr != nil {
 				logger.ErrorF(ctx, "payment refund: failed to mark order %s refunded: %v", outTradeNo, updateErr)
 				return false, "update order status failed"
 			}
@@ -375,9 +471,13 @@ func HandleNotify(ctx context.Context, q map[string]string) (bool, string) {
 		return false, fmt.Sprintf("fulfill failed: %v", err)
 	}
 
-	// 成功
-	db.DB(ctx).Model(&PaymentOrder{}).
-		Where("out_trade_no = ?", outTradeNo).Update("status", OrderStatusCompleted)
+	// 成功,状态落库后才通知创建者
+	if err := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("out_trade_no = ?", outTradeNo).Update("status", OrderStatusCompleted).Error; err != nil {
+		logger.ErrorF(ctx, "payment notify: failed to mark order %s completed: %v", outTradeNo, err)
+		return false, "update order status failed"
+	}
+	notifyItemSold(ctx, order)
 	return true, "ok"
 }
 
@@ -396,6 +496,73 @@ func fulfillPaidOrder(ctx context.Context, order *PaymentOrder) error {
 	})
 }
 
+// refundRevokedOrder 对作废 item 对应的订单全额退款,item 已作废因此不归还库存。
+// expectedStatus 为订单当前状态(COMPLETED 或 DISPUTED)。先认领订单,认领成功者才调用渠道退款。
+func refundRevokedOrder(ctx context.Context, order *PaymentOrder, expectedStatus OrderStatus) error {
+	if err := claimRevokedOrder(db.DB(ctx), order, expectedStatus); err != nil {
+		return err
+	}
+	return refundClaimedRevokedOrder(ctx, order)
+}
+
+// claimRevokedOrder 以状态为 CAS 条件将订单认领为 REFUNDING,与 claimRefund 一样计入一次尝试并设置租约
+func claimRevokedOrder(tx *gorm.DB, order *PaymentOrder, expectedStatus OrderStatus) error {
+	lease := time.Now().Add(refundClaimLease)
+	result := tx.Model(&PaymentOrder{}).
+		Where("out_trade_no = ? AND status = ?", order.OutTradeNo, expectedStatus).
+		Updates(map[string]any{
+			"status":          OrderStatusRefunding,
+			"refund_attempts": order.RefundAttempts + 1,
+			"next_refund_at":  &lease,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errors.New(ErrOrderStatusChanged)
+	}
+	order.Status = OrderStatusRefunding
+	order.RefundAttempts++
+	return nil
+}
+
+// refundClaimedRevokedOrder 对已认领的作废订单调用渠道退款,
+// 退款失败时订单保持 REFUNDING 并按退避时间交由重试任务继续退款
+func refundClaimedRevokedOrder(ctx context.Context, order *PaymentOrder) error {
+	provider, refundErr := orderProvider(ctx, order)
+	if refundErr == nil {
+		refundErr = provider.Refund(ctx, order.TradeNo, moneyString(order.Amount))
+	}
+	now := time.Now()
+	updates := map[string]any{}
+	if refundErr == nil {
+		updates["status"] = OrderStatusRefunded
+		updates["refunded_at"] = &now
+		updates["next_refund_at"] = nil
+	} else {
+		_, base := refundRetryPolicy()
+		next := now.Add(refundBackoff(base, order.RefundAttempts))
+		updates["fail_reason"] = truncateRuneLen(refundErr.Error(), 200)
+		updates["next_refund_at"] = &next
+	}
+	result := db.DB(ctx).Model(&PaymentOrder{}).
+		Where("out_trade_no = ? AND status = ? AND refund_attempts = ?", order.OutTradeNo, OrderStatusRefunding, order.RefundAttempts).
+		Updates(updates)
+	if result.Error != nil {
+		logger.ErrorF(ctx, "payment revoke refund: failed to update order %s: %v", order.OutTradeNo, result.Error)
+		return result.Error
+	}
+	if refundErr != nil {
+		return refundErr
+	}
+	if result.RowsAffected == 1 {
+		order.Status = OrderStatusRefunded
+		order.RefundedAt = &now
+		notifyOrderRefunded(ctx, db.DB(ctx), order)
+	}
+	return nil
+}
+
 // CallbackURLs 返回当前平台配置的回调地址,用于前端展示给用户。
 func CallbackURLs() (notifyURL, returnURL string) {
 	return callbackNotifyURL(), callbackReturnURL("")
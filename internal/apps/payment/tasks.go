@@ -38,6 +38,7 @@ import (
 // 查询条件:status=PENDING 且 expire_at 超时超过 5 分钟。
 // 额外 5 分钟宽限期确保 epay 的异步 notify 回调在此之前已到达，
 // 避免"cleanup 先置 FAILED + RPush，notify 随后到达发现已无 PENDING 订单"的竞态。
+// 置 FAILED 前先向渠道主动查询,已付款但回调丢失的订单走正常发放流程。
 func HandleExpireStaleOrders(ctx context.Context, _ *asynq.Task) error {
 	// expire_at 已超过 5 分钟才认为真正超时
 	deadline := time.Now().Add(-5 * time.Minute)
@@ -53,7 +54,9 @@ func HandleExpireStaleOrders(ctx context.Context, _ *asynq.Task) error {
 	logger.InfoF(ctx, "payment cleanup: found %d stale orders", len(orders))
 
 	for _, order := range orders {
-		expireOrder(ctx, &order)
+		if reconcileStaleOrder(ctx, &order) {
+			expireOrder(ctx, &order)
+		}
 	}
 	return nil
 }
@@ -76,7 +79,10 @@ func expireOrder(ctx context.Context, order *PaymentOrder) {
 			return nil
 		}
 
-		if err := returnReservedItem(ctx, tx, order.ProjectID, order.ItemID); err != nil {
+		if err := returnReservedItem(ctx, tx, order); err != nil {
+			return err
+		}
+		if err := releaseCouponUse(tx, order); err != nil {
 			return err
 		}
 
@@ -92,6 +98,8 @@ func expireOrder(ctx context.Context, order *PaymentOrder) {
 		return
 	}
 
+	refillWaitlist(ctx, order.ProjectID)
 	logger.InfoF(ctx, "payment cleanup: returned item %d to project %s stock", order.ItemID, order.ProjectID)
 	logger.InfoF(ctx, "payment cleanup: order %s expired and marked as FAILED", order.OutTradeNo)
+	publishOrderEvent(ctx, order.OutTradeNo)
 }
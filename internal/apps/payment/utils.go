@@ -28,19 +28,36 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/apps/project"
 	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
 	"gorm.io/gorm"
 )
 
 // returnReservedItem 把支付预占的 item 归还 Redis，并在同一个数据库事务中重置项目完成状态。
-// Redis RPush 或项目状态更新失败时返回 error，由调用方触发外层数据库事务回滚。
-func returnReservedItem(ctx context.Context, tx *gorm.DB, projectID string, itemID uint64) error {
+// 一码一用 RPush 回队列,抽奖与邀请 HSet 写回买家的哈希项。
+// Redis 写入或项目状态更新失败时返回 error，由调用方触发外层数据库事务回滚。
+// 等候补货的预留可能随事务回滚失效,由调用方在提交后经 refillWaitlist 完成。
+func returnReservedItem(ctx context.Context, tx *gorm.DB, order *PaymentOrder) error {
+	projectID, itemID := order.ProjectID, order.ItemID
 	var proj project.Project
 	if err := tx.Where("id = ?", projectID).First(&proj).Error; err != nil {
 		return fmt.Errorf("load project %s: %w", projectID, err)
 	}
-	if err := db.Redis.RPush(ctx, project.ProjectItemsKey(projectID), itemID).Err(); err != nil {
+	// 已作废的 item 退款后不归还库存
+	var item project.ProjectItem
+	if err := item.Exact(tx, itemID); err != nil {
+		return fmt.Errorf("load item %d: %w", itemID, err)
+	}
+	if item.RevokedAt != nil {
+		return nil
+	}
+	var payer oauth.User
+	if err := payer.Exact(tx, order.PayerID); err != nil {
+		return fmt.Errorf("load payer %d: %w", order.PayerID, err)
+	}
+	if err := proj.ReturnReservation(ctx, payer.Username, itemID); err != nil {
 		return fmt.Errorf("return item %d to project %s stock: %w", itemID, projectID, err)
 	}
 	if err := proj.ResetCompletedStatusIfHasStock(ctx, tx); err != nil {
@@ -49,6 +66,19 @@ func returnReservedItem(ctx context.Context, tx *gorm.DB, projectID string, item
 	return nil
 }
 
+// refillWaitlist 在归还库存的事务提交后为等候用户预留 item。
+// 失败仅记录日志,归还的 item 仍在库存中可被直接领取。
+func refillWaitlist(ctx context.Context, projectID string) {
+	var proj project.Project
+	err := db.DB(ctx).Where("id = ?", projectID).First(&proj).Error
+	if err == nil {
+		err = proj.RefillFromWaitlist(ctx)
+	}
+	if err != nil {
+		logger.WarnF(ctx, "payment: refill waitlist for project %s failed: %v", projectID, err)
+	}
+}
+
 func markOrderRefundedAndReturnItem(ctx context.Context, order *PaymentOrder, updates map[string]any, expectedStatus OrderStatus) (bool, error) {
 	processed := false
 	err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
@@ -62,12 +92,19 @@ func markOrderRefundedAndReturnItem(ctx context.Context, order *PaymentOrder, up
 			return nil
 		}
 
-		if err := returnReservedItem(ctx, tx, order.ProjectID, order.ItemID); err != nil {
+		if err := returnReservedItem(ctx, tx, order); err != nil {
 			return err
 		}
+		if err := releaseCouponUse(tx, order); err != nil {
+			return err
+		}
+		notifyOrderRefunded(ctx, tx, order)
 
 		processed = true
 		return nil
 	})
+	if processed && err == nil {
+		refillWaitlist(ctx, order.ProjectID)
+	}
 	return processed, err
 }
@@ -38,11 +38,57 @@ const (
 	AlreadyReported    = "已举报过当前项目"
 	RequirementsFailed = "未达到项目发起者设置的条件"
 	TooManyRequests    = "创建项目太频繁，请稍后再试"
+	// Lottery 相关
+	TopicRequired        = "请提供抽奖话题"
+	WinnersRequired      = "请提供中奖用户名单"
+	WinnersItemsMismatch = "中奖用户数量(%d)与奖品数量(%d)不符"
+	CandidatesNotEnough  = "抽奖候选人数量(%d)少于奖品数量(%d)"
+	ClaimEndTimeRequired = "报名抽奖需要设置晚于结束时间的领取截止时间"
+	NotScheduledDraw     = "该项目不是报名抽奖项目"
+	DrawSeedMismatch     = "开奖种子与创建时公开的种子哈希不符"
+	RegistrationClosed   = "不在报名时间内"
+	AlreadyRegistered    = "已报名当前抽奖"
+	LotteryNotDrawn      = "尚未开奖"
+	// Waitlist 相关
+	WaitlistNotSupported = "该项目不支持等候补货"
+	StockAvailable       = "当前有库存，请直接领取"
+	// Release 相关
+	ReleaseOnlyOneForEach = "仅一码一用分发支持分批发放"
+	ReleaseTimeInvalid    = "发放时间需晚于当前时间且早于结束时间"
+	ReleaseItemsExceeded  = "分批发放数量(%d)超过物品数量(%d)"
+	ReleaseAfterEndTime   = "结束时间不能早于尚未发放的批次"
+	// Quota 相关
+	QuotaOnlyOneForEach = "仅一码一用分发支持每人领取多个"
+	ClaimQuotaExceeded  = "已达到领取上限(%d)"
+	// Import 相关
+	ImportOnlyOneForEach  = "仅一码一用分发支持导入文件"
+	ImportFileRequired    = "请上传导入文件"
+	ImportFileTypeInvalid = "仅支持 .txt 或 .csv 文件"
+	// Revoke 相关
+	ItemNotFound                = "物品不存在"
+	ItemNotReceived             = "物品尚未被领取"
+	ItemAlreadyRevoked          = "物品已作废"
+	RevokeRefundNotPaid         = "仅付费项目支持退款"
+	RevokeReleaseOnlyOneForEach = "仅一码一用分发支持释放领取资格"
+	RevokeReleaseRequiresRefund = "付费项目释放领取资格需同时退款"
+	RevokeReplacementRequired   = "抽奖与邀请项目替换时需提供新内容"
+	// Item 加密相关
+	ItemEncryptionKeyMissing = "服务端未配置物品加密密钥"
+	// Invite 相关
+	NotInvited                 = "未受邀参与该项目"
+	InviteesRequired           = "邀请制项目需要至少一名受邀用户"
+	InviteesItemsMismatch      = "受邀用户数量(%d)与物品数量(%d)不符"
+	InviteeNotFound            = "受邀用户不存在: %d"
+	InviteeAlreadyReceived     = "受邀用户 %s 已领取，不可移除"
+	InviteItemsRequireInvitees = "追加物品时需同时提供受邀用户名单"
 	// Payment 相关
 	InvalidPrice         = "金额必须大于等于 0"
 	InvalidPriceDecimals = "金额最多保留 2 位小数"
 	PriceTooLarge        = "金额超出允许范围"
-	PriceOnlyOneForEach  = "仅一码一用分发支持设置金额"
+	InvalidPriceRange    = "价格区间不合法"
 	PaymentDisabled      = "平台支付功能未启用"
 	CreatorNotConfigured = "请先在账户设置中配置支付凭据"
+	// Follow 相关
+	CannotFollowSelf = "不能关注自己"
+	CreatorNotFound  = "创建者不存在"
 )
@@ -0,0 +1,440 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"github.com/linux-do/cdk/internal/utils"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// exploreCandidatesKey 广场候选项目缓存:所有未结束、未完成、正常且未隐藏的项目
+	exploreCandidatesKey = "explore:candidates"
+	// exploreCandidatesVersionKey 候选集版本号,每次失效递增
+	exploreCandidatesVersionKey = "explore:candidates:version"
+	// exploreReceivedKeyFormat 用户在各项目中有效领取数量的哈希
+	exploreReceivedKeyFormat = "explore:received:%d:counts"
+	// exploreReceivedVersionKeyFormat 用户领取数量的版本号,每次失效递增
+	exploreReceivedVersionKeyFormat = "explore:received:%d:version"
+	// exploreMetricsKey 缓存命中统计,跨实例累加
+	exploreMetricsKey = "explore:metrics"
+	// exploreReceivedTTL 领取数量的缓存时长,领取与作废时主动失效
+	exploreReceivedTTL = 10 * time.Minute
+	// exploreReceivedPlaceholder 哈希占位字段,区分"未缓存"与"从未领取"
+	exploreReceivedPlaceholder = "-"
+	// defaultExploreCacheSeconds 候选集默认缓存时长
+	defaultExploreCacheSeconds = 60
+)
+
+// 命中统计字段
+const (
+	exploreMetricCandidatesHit  = "candidates_hit"
+	exploreMetricCandidatesMiss = "candidates_miss"
+	exploreMetricReceivedHit    = "received_hit"
+	exploreMetricReceivedMiss   = "received_miss"
+)
+
+// exploreCandidatesSql 候选集只包含与用户无关的条件,按广场默认顺序排列
+const exploreCandidatesSql = `SELECT
+    			p.id,p.name,p.description,p.distribution_type,p.total_items,
+       			p.start_time,p.end_time,p.minimum_trust_level,p.allow_same_ip,p.risk_level,p.price,p.created_at,
+				p.claim_quota,p.trust_level_quotas,
+				IF(COUNT(pt.tag) = 0, NULL, JSON_ARRAYAGG(pt.tag)) AS tags
+			FROM projects p
+			LEFT JOIN project_tags pt ON p.id = pt.project_id
+			WHERE p.end_time > ? AND p.is_completed = false AND p.status = ? AND p.hide_from_explore = false
+			GROUP BY p.id ORDER BY p.end_time ASC, p.id ASC`
+
+// 重建缓存时先读取版本号再查库,写入时版本号已变化说明期间发生过失效,丢弃本次结果,
+// 避免查库与失效交错时把失效前的数据写回缓存
+var (
+	cacheExploreCandidatesScript = redis.NewScript(`
+if (redis.call('GET', KEYS[2]) or '') ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1`)
+	cacheExploreReceivedScript = redis.NewScript(`
+if (redis.call('GET', KEYS[2]) or '') ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+for i = 3, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1`)
+)
+
+// exploreCandidate 候选集缓存项,附带按用户过滤所需的领取上限
+type exploreCandidate struct {
+	ListProjectsResponseDataResult
+	ClaimQuota       int64   `json:"claim_quota"`
+	TrustLevelQuotas []int64 `json:"trust_level_quotas" gorm:"serializer:json"`
+}
+
+// claimQuotaFor 与 Project.ClaimQuotaFor 一致
+func (c *exploreCandidate) claimQuotaFor(level oauth.TrustLevel) int64 {
+	p := Project{ClaimQuota: c.ClaimQuota, TrustLevelQuotas: c.TrustLevelQuotas}
+	return p.ClaimQuotaFor(level)
+}
+
+// exploreCandidatesGroup 合并同一实例内并发的候选集重建
+var exploreCandidatesGroup singleflight.Group
+
+// exploreCacheTTL 候选集缓存时长,事件失效遗漏时最多延迟这么久
+func exploreCacheTTL() time.Duration {
+	seconds := config.Config.ProjectApp.ExploreCacheSeconds
+	if seconds <= 0 {
+		seconds = defaultExploreCacheSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// InvalidateExploreCache 使候选集失效并递增版本号。
+// 在事务提交前调用时,并发请求可能用旧数据重建缓存,由 TTL 兜底。
+func InvalidateExploreCache(ctx context.Context) {
+	pipe := db.Redis.TxPipeline()
+	pipe.Incr(ctx, exploreCandidatesVersionKey)
+	pipe.Del(ctx, exploreCandidatesKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.WarnF(ctx, "[Explore] invalidate candidates failed: %v", err)
+	}
+}
+
+// inExplore 项目是否属于候选集,条件与 exploreCandidatesSql 一致
+func (p *Project) inExplore(now time.Time) bool {
+	return p.Status == ProjectStatusNormal && !p.HideFromExplore && !p.IsCompleted && p.EndTime.After(now)
+}
+
+// InvalidateExploreCacheFor 仅当项目变更前或变更后的状态属于候选集时使候选集失效,
+// 不在广场展示的项目(已隐藏、已结束、已完成或非正常状态)的变更不影响缓存
+func InvalidateExploreCacheFor(ctx context.Context, states ...*Project) {
+	now := time.Now()
+	for _, p := range states {
+		if p != nil && p.inExplore(now) {
+			InvalidateExploreCache(ctx)
+			return
+		}
+	}
+}
+
+// invalidateExploreCompletion 项目完成状态或库存变化后,仅当项目在未完成时会出现在广场中才使候选集失效
+func invalidateExploreCompletion(ctx context.Context, p *Project) {
+	listed := *p
+	listed.IsCompleted = false
+	InvalidateExploreCacheFor(ctx, &listed)
+}
+
+// invalidateReceivedCache 用户领取或 item 被作废后使其领取数量失效并递增版本号
+func invalidateReceivedCache(ctx context.Context, userID uint64) {
+	versionKey := fmt.Sprintf(exploreReceivedVersionKeyFormat, userID)
+	pipe := db.Redis.TxPipeline()
+	pipe.Incr(ctx, versionKey)
+	pipe.Expire(ctx, versionKey, exploreReceivedTTL)
+	pipe.Del(ctx, fmt.Sprintf(exploreReceivedKeyFormat, userID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.WarnF(ctx, "[Explore] invalidate received counts of user %d failed: %v", userID, err)
+	}
+}
+
+// cacheVersion 读取缓存版本号,不存在时为空
+func cacheVersion(ctx context.Context, key string) (string, error) {
+	version, err := db.Redis.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return version, err
+}
+
+// recordExploreMetric 累加命中统计,失败不影响请求
+func recordExploreMetric(ctx context.Context, field string) {
+	db.Redis.HIncrBy(ctx, exploreMetricsKey, field, 1)
+}
+
+// loadExploreCandidates 读取候选集,未命中时查库重建,同一实例内的并发重建只查一次库。
+// 返回的切片在调用方之间共享,不可修改。
+func loadExploreCandidates(ctx context.Context) ([]exploreCandidate, error) {
+	var candidates []exploreCandidate
+	cached, err := db.Redis.Get(ctx, exploreCandidatesKey).Bytes()
+	if err == nil {
+		if err := json.Unmarshal(cached, &candidates); err == nil {
+			recordExploreMetric(ctx, exploreMetricCandidatesHit)
+			return candidates, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	recordExploreMetric(ctx, exploreMetricCandidatesMiss)
+	// 重建结果由多个请求共享,不随发起请求的取消而中断
+	result, err, _ := exploreCandidatesGroup.Do(exploreCandidatesKey, func() (interface{}, error) {
+		return rebuildExploreCandidates(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]exploreCandidate), nil
+}
+
+// rebuildExploreCandidates 查库重建候选集,期间发生失效时不写入缓存
+func rebuildExploreCandidates(ctx context.Context) ([]exploreCandidate, error) {
+	version, err := cacheVersion(ctx, exploreCandidatesVersionKey)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []exploreCandidate
+	if err := db.DB(ctx).Raw(exploreCandidatesSql, time.Now(), ProjectStatusNormal).Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(candidates)
+	if err != nil {
+		return nil, err
+	}
+	if err := cacheExploreCandidatesScript.Run(ctx, db.Redis,
+		[]string{exploreCandidatesKey, exploreCandidatesVersionKey},
+		version, payload, exploreCacheTTL().Milliseconds()).Err(); err != nil {
+		logger.WarnF(ctx, "[Explore] cache candidates failed: %v", err)
+	}
+	return candidates, nil
+}
+
+// loadReceivedCounts 读取用户在各项目中的有效领取数量,未命中时查库重建
+func loadReceivedCounts(ctx context.Context, userID uint64) (map[string]int64, error) {
+	key := fmt.Sprintf(exploreReceivedKeyFormat, userID)
+	cached, err := db.Redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	received := make(map[string]int64, len(cached))
+	if len(cached) > 0 {
+		recordExploreMetric(ctx, exploreMetricReceivedHit)
+		for id, value := range cached {
+			if id == exploreReceivedPlaceholder {
+				continue
+			}
+			count, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			received[id] = count
+		}
+		return received, nil
+	}
+
+	recordExploreMetric(ctx, exploreMetricReceivedMiss)
+	versionKey := fmt.Sprintf(exploreReceivedVersionKeyFormat, userID)
+	version, err := cacheVersion(ctx, versionKey)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ProjectID string
+		Count     int64
+	}
+	if err := db.DB(ctx).Model(&ProjectItem{}).
+		Select("project_id, COUNT(*) AS count").
+		Where("receiver_id = ? AND revoked_at IS NULL", userID).
+		Group("project_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, 2*len(rows)+4)
+	args = append(args, version, exploreReceivedTTL.Milliseconds(), exploreReceivedPlaceholder, 0)
+	for _, row := range rows {
+		received[row.ProjectID] = row.Count
+		args = append(args, row.ProjectID, row.Count)
+	}
+	if err := cacheExploreReceivedScript.Run(ctx, db.Redis, []string{key, versionKey}, args...).Err(); err != nil {
+		logger.WarnF(ctx, "[Explore] cache received counts of user %d failed: %v", userID, err)
+	}
+	return received, nil
+}
+
+// invitedProjectIDs 返回候选邀请制项目中邀请了该用户的项目
+func invitedProjectIDs(ctx context.Context, username string, candidates []exploreCandidate) (map[string]struct{}, error) {
+	var inviteIDs []string
+	for i := range candidates {
+		if candidates[i].DistributionType == DistributionTypeInvite {
+			inviteIDs = append(inviteIDs, candidates[i].ID)
+		}
+	}
+	invited := make(map[string]struct{})
+	if len(inviteIDs) == 0 {
+		return invited, nil
+	}
+	var ids []string
+	if err := db.DB(ctx).Model(&ProjectInvitee{}).
+		Where("username = ? AND project_id IN ?", username, inviteIDs).
+		Pluck("project_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		invited[id] = struct{}{}
+	}
+	return invited, nil
+}
+
+// ListExploreProjects 广场项目列表:候选集与领取数量走缓存,用户相关的资格过滤在内存中完成,
+// 结果与 ListProjectsWithTags 一致(按标签筛选时只返回命中的标签)。缓存不可用时回退到 SQL 查询。
+func ListExploreProjects(ctx context.Context, offset, limit int, tags []string, currentUser *oauth.User, page *utils.CursorPagination) (*ListProjectsResponseData, error) {
+	candidates, err := loadExploreCandidates(ctx)
+	if err != nil {
+		logger.WarnF(ctx, "[Explore] load candidates failed, fallback to sql: %v", err)
+		return ListProjectsWithTags(ctx, offset, limit, tags, currentUser, page)
+	}
+	received, err := loadReceivedCounts(ctx, currentUser.ID)
+	if err != nil {
+		logger.WarnF(ctx, "[Explore] load received counts failed, fallback to sql: %v", err)
+		return ListProjectsWithTags(ctx, offset, limit, tags, currentUser, page)
+	}
+	invited, err := invitedProjectIDs(ctx, currentUser.Username, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	riskLevel := currentUser.RiskLevel()
+	matched := make([]ListProjectsResponseDataResult, 0, len(candidates))
+	for _, c := range candidates {
+		if !c.EndTime.After(now) || c.MinimumTrustLevel > currentUser.TrustLevel || c.RiskLevel < riskLevel {
+			continue
+		}
+		if received[c.ID] >= c.claimQuotaFor(currentUser.TrustLevel) {
+			continue
+		}
+		if c.DistributionType == DistributionTypeInvite {
+			if _, ok := invited[c.ID]; !ok {
+				continue
+			}
+		}
+		if len(tags) > 0 {
+			// 与 SQL 的 pt.tag IN (?) 一致,只保留命中的标签
+			c.Tags = slices.DeleteFunc(slices.Clone(c.Tags), func(tag string) bool { return !slices.Contains(tags, tag) })
+			if len(c.Tags) == 0 {
+				continue
+			}
+		}
+		matched = append(matched, c.ListProjectsResponseDataResult)
+	}
+
+	// 总数在内存中得到,无需额外查询
+	total := int64(len(matched))
+	if total == 0 {
+		return &ListProjectsResponseData{
+			Total:   0,
+			Results: nil,
+		}, nil
+	}
+
+	// 游标定位,候选集已按 (end_time, id) 升序
+	var (
+		cursorEndTime time.Time
+		cursorID      string
+	)
+	start := offset
+	if ok, err := page.DecodeCursor(&cursorEndTime, &cursorID); err != nil {
+		return nil, err
+	} else if ok {
+		start = slices.IndexFunc(matched, func(c ListProjectsResponseDataResult) bool {
+			return c.EndTime.After(cursorEndTime) || (c.EndTime.Equal(cursorEndTime) && c.ID > cursorID)
+		})
+		if start < 0 {
+			start = len(matched)
+		}
+	} else if page.CursorMode() {
+		start = 0
+	}
+	queryLimit, _ := page.Window(offset, limit)
+	start = min(start, len(matched))
+	end := min(start+queryLimit, len(matched))
+
+	results := slices.Clone(matched[start:end])
+	results, nextCursor, err := utils.TrimCursorPage(page, results, limit,
+		func(r *ListProjectsResponseDataResult) []interface{} { return []interface{}{r.EndTime, r.ID} })
+	if err != nil {
+		return nil, err
+	}
+	return &ListProjectsResponseData{
+		Total:      total,
+		Results:    &results,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// ExploreCacheStats 广场缓存命中统计
+type ExploreCacheStats struct {
+	CandidatesHit     int64   `json:"candidates_hit"`
+	CandidatesMiss    int64   `json:"candidates_miss"`
+	CandidatesHitRate float64 `json:"candidates_hit_rate"`
+	ReceivedHit       int64   `json:"received_hit"`
+	ReceivedMiss      int64   `json:"received_miss"`
+	ReceivedHitRate   float64 `json:"received_hit_rate"`
+}
+
+// GetExploreCacheStats 读取累计命中统计
+func GetExploreCacheStats(ctx context.Context) (*ExploreCacheStats, error) {
+	values, err := db.Redis.HGetAll(ctx, exploreMetricsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	counter := func(field string) int64 {
+		n, _ := strconv.ParseInt(values[field], 10, 64)
+		return n
+	}
+	rate := func(hit, miss int64) float64 {
+		if hit+miss == 0 {
+			return 0
+		}
+		return float64(hit) / float64(hit+miss)
+	}
+	stats := &ExploreCacheStats{
+		CandidatesHit:  counter(exploreMetricCandidatesHit),
+		CandidatesMiss: counter(exploreMetricCandidatesMiss),
+		ReceivedHit:    counter(exploreMetricReceivedHit),
+		ReceivedMiss:   counter(exploreMetricReceivedMiss),
+	}
+	stats.CandidatesHitRate = rate(stats.CandidatesHit, stats.CandidatesMiss)
+	stats.ReceivedHitRate = rate(stats.ReceivedHit, stats.ReceivedMiss)
+	return stats, nil
+}
+
+// ResetExploreCacheStats 清零命中统计
+func ResetExploreCacheStats(ctx context.Context) error {
+	return db.Redis.Del(ctx, exploreMetricsKey).Err()
+}
@@ -0,0 +1,348 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"github.com/linux-do/cdk/internal/utils"
+	"gorm.io/gorm"
+)
+
+// setupExplore 准备广场数据:user 为当前用户,各项目覆盖一种过滤条件
+func setupExplore(t *testing.T) (*miniredis.Miniredis, *oauth.User) {
+	t.Helper()
+	mr := dbtest.Setup(t, &oauth.User{}, &Project{}, &ProjectItem{}, &ProjectTag{}, &ProjectInvitee{})
+	tx := db.DB(context.Background())
+
+	user := &oauth.User{ID: 1, Username: "alice", TrustLevel: oauth.TrustLevelNewUser + 1, Score: oauth.BaseUserScore}
+	if err := tx.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	projects := []Project{
+		{ID: "listed", Name: "listed", EndTime: now.Add(time.Hour)},
+		{ID: "quota", Name: "quota", EndTime: now.Add(90 * time.Minute), ClaimQuota: 2},
+		{ID: "tagged", Name: "tagged", EndTime: now.Add(2 * time.Hour)},
+		{ID: "received", Name: "received", EndTime: now.Add(3 * time.Hour)},
+		{ID: "tiered", Name: "tiered", EndTime: now.Add(210 * time.Minute), ClaimQuota: 3, TrustLevelQuotas: []int64{3, 1, 3, 3, 3}},
+		{ID: "invited", Name: "invited", EndTime: now.Add(4 * time.Hour), DistributionType: DistributionTypeInvite},
+		{ID: "uninvited", Name: "uninvited", EndTime: now.Add(5 * time.Hour), DistributionType: DistributionTypeInvite},
+		{ID: "trust", Name: "trust", EndTime: now.Add(6 * time.Hour), MinimumTrustLevel: oauth.TrustLevelNewUser + 2},
+		{ID: "hidden", Name: "hidden", EndTime: now.Add(7 * time.Hour), HideFromExplore: true},
+		{ID: "completed", Name: "completed", EndTime: now.Add(8 * time.Hour), IsCompleted: true},
+		{ID: "ended", Name: "ended", EndTime: now.Add(-time.Hour)},
+	}
+	if err := tx.Create(&projects).Error; err != nil {
+		t.Fatal(err)
+	}
+	tags := []ProjectTag{
+		{ProjectID: "tagged", Tag: "x"},
+		{ProjectID: "tagged", Tag: "y"},
+		{ProjectID: "listed", Tag: "y"},
+	}
+	if err := tx.Create(&tags).Error; err != nil {
+		t.Fatal(err)
+	}
+	// quota 仍有剩余领取次数;tiered 对当前信任等级的上限为 1;revoked 的作废领取不计入上限
+	revokedAt := now
+	items := []ProjectItem{
+		{ProjectID: "received", Content: "code", ReceiverID: &user.ID},
+		{ProjectID: "quota", Content: "code", ReceiverID: &user.ID},
+		{ProjectID: "tiered", Content: "code", ReceiverID: &user.ID},
+		{ProjectID: "listed", Content: "code", ReceiverID: &user.ID, RevokedAt: &revokedAt},
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&ProjectInvitee{ProjectID: "invited", Username: user.Username}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return mr, user
+}
+
+// onQuery 在查询或 Raw 扫描完成后对匹配的语句执行 fn
+func onQuery(t *testing.T, match func(*gorm.Statement) bool, fn func()) {
+	t.Helper()
+	hook := func(tx *gorm.DB) {
+		if match(tx.Statement) {
+			fn()
+		}
+	}
+	callbacks := db.DB(context.Background()).Callback()
+	if err := callbacks.Query().After("gorm:query").Register("test:explore_query", hook); err != nil {
+		t.Fatal(err)
+	}
+	if err := callbacks.Row().After("gorm:row").Register("test:explore_row", hook); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func isCandidatesQuery(stmt *gorm.Statement) bool {
+	return strings.Contains(stmt.SQL.String(), "hide_from_explore = false") && !strings.Contains(stmt.SQL.String(), "receiver_id")
+}
+
+func isReceivedQuery(stmt *gorm.Statement) bool {
+	return stmt.Table == "project_items" && strings.Contains(stmt.SQL.String(), "receiver_id")
+}
+
+func resultIDs(data *ListProjectsResponseData) []string {
+	if data.Results == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(*data.Results))
+	for _, r := range *data.Results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestListExploreProjectsMatchesSQL(t *testing.T) {
+	_, user := setupExplore(t)
+	ctx := context.Background()
+
+	for _, tags := range [][]string{nil, {"x"}, {"y"}, {"z"}} {
+		t.Run(fmt.Sprint(tags), func(t *testing.T) {
+			cached, err := ListExploreProjects(ctx, 0, 10, tags, user, &utils.CursorPagination{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			direct, err := ListProjectsWithTags(ctx, 0, 10, tags, user, &utils.CursorPagination{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cached.Total != direct.Total || !slices.Equal(resultIDs(cached), resultIDs(direct)) {
+				t.Fatalf("cache path %d %v, sql path %d %v", cached.Total, resultIDs(cached), direct.Total, resultIDs(direct))
+			}
+			if cached.Results == nil {
+				return
+			}
+			for i, r := range *cached.Results {
+				got, want := slices.Sorted(slices.Values(r.Tags)), slices.Sorted(slices.Values((*direct.Results)[i].Tags))
+				if !slices.Equal(got, want) {
+					t.Errorf("project %s: cache path tags %v, sql path tags %v", r.ID, got, want)
+				}
+			}
+		})
+	}
+
+	all, err := ListExploreProjects(ctx, 0, 10, nil, user, &utils.CursorPagination{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"listed", "quota", "tagged", "invited"}; !slices.Equal(resultIDs(all), want) {
+		t.Errorf("got %v, want %v", resultIDs(all), want)
+	}
+	// 按标签筛选只返回命中的标签
+	byTag, err := ListExploreProjects(ctx, 0, 10, []string{"x"}, user, &utils.CursorPagination{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := resultIDs(byTag); !slices.Equal(ids, []string{"tagged"}) || !slices.Equal((*byTag.Results)[0].Tags, utils.StringArray{"x"}) {
+		t.Errorf("tag filter: got %v %v", ids, (*byTag.Results)[0].Tags)
+	}
+}
+
+func TestLoadExploreCandidatesCoalescesRebuilds(t *testing.T) {
+	mr, _ := setupExplore(t)
+	ctx := context.Background()
+
+	const callers = 8
+	var queries atomic.Int32
+	release := make(chan struct{})
+	onQuery(t, isCandidatesQuery, func() {
+		if queries.Add(1) == 1 {
+			<-release
+		}
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			candidates, err := loadExploreCandidates(ctx)
+			if err == nil && len(candidates) != 8 {
+				err = fmt.Errorf("got %d candidates, want 8", len(candidates))
+			}
+			errs <- err
+		}()
+	}
+	// 所有调用方都已未命中缓存后再放行首次查库
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.HGet(exploreMetricsKey, exploreMetricCandidatesMiss) != fmt.Sprint(callers) {
+		if time.Now().After(deadline) {
+			t.Fatal("callers did not reach the rebuild")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := queries.Load(); n != 1 {
+		t.Errorf("candidates queried %d times, want 1", n)
+	}
+	if !mr.Exists(exploreCandidatesKey) {
+		t.Error("candidates not cached")
+	}
+}
+
+func TestLoadExploreCandidatesSkipsStaleWrite(t *testing.T) {
+	mr, _ := setupExplore(t)
+	ctx := context.Background()
+
+	invalidate := true
+	onQuery(t, isCandidatesQuery, func() {
+		// 模拟查库期间有项目变更
+		if invalidate {
+			invalidate = false
+			InvalidateExploreCache(ctx)
+		}
+	})
+	candidates, err := loadExploreCandidates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 8 {
+		t.Errorf("got %d candidates, want 8", len(candidates))
+	}
+	if mr.Exists(exploreCandidatesKey) {
+		t.Fatal("stale candidates written after invalidation")
+	}
+
+	if _, err := loadExploreCandidates(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists(exploreCandidatesKey) {
+		t.Error("candidates not cached by the next rebuild")
+	}
+}
+
+func TestLoadReceivedCountsSkipsStaleWrite(t *testing.T) {
+	mr, user := setupExplore(t)
+	ctx := context.Background()
+	key := fmt.Sprintf(exploreReceivedKeyFormat, user.ID)
+
+	invalidate := true
+	onQuery(t, isReceivedQuery, func() {
+		// 模拟查库后、写缓存前用户领取了新项目
+		if invalidate {
+			invalidate = false
+			invalidateReceivedCache(ctx, user.ID)
+		}
+	})
+	want := map[string]int64{"received": 1, "quota": 1, "tiered": 1}
+	received, err := loadReceivedCounts(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(received, want) {
+		t.Errorf("got %v, want %v", received, want)
+	}
+	if mr.Exists(key) {
+		t.Fatal("stale received counts written after invalidation")
+	}
+
+	if _, err := loadReceivedCounts(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := loadReceivedCounts(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(cached, want) || mr.HGet(key, "received") != "1" {
+		t.Errorf("received counts not cached by the next rebuild: %v", cached)
+	}
+	if ttl := mr.TTL(key); ttl <= 0 {
+		t.Errorf("received counts have no ttl: %v", ttl)
+	}
+}
+
+func TestInvalidateExploreCacheFor(t *testing.T) {
+	mr, _ := setupExplore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	listed := &Project{EndTime: now.Add(time.Hour)}
+	cases := []struct {
+		name       string
+		states     []*Project
+		invalidate bool
+	}{
+		{"listed", []*Project{listed}, true},
+		{"hidden from explore", []*Project{{EndTime: now.Add(time.Hour), HideFromExplore: true}}, false},
+		{"completed", []*Project{{EndTime: now.Add(time.Hour), IsCompleted: true}}, false},
+		{"ended", []*Project{{EndTime: now.Add(-time.Hour)}}, false},
+		{"reported", []*Project{{EndTime: now.Add(time.Hour), Status: ProjectStatusHidden}}, false},
+		{"unhidden", []*Project{{EndTime: now.Add(time.Hour), Status: ProjectStatusHidden}, listed}, true},
+		{"nil", []*Project{nil}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := mr.Set(exploreCandidatesKey, "[]"); err != nil {
+				t.Fatal(err)
+			}
+			InvalidateExploreCacheFor(ctx, tc.states...)
+			if got := !mr.Exists(exploreCandidatesKey); got != tc.invalidate {
+				t.Errorf("invalidated = %v, want %v", got, tc.invalidate)
+			}
+		})
+	}
+
+	// 完成状态变化以未完成时是否展示为准
+	if err := mr.Set(exploreCandidatesKey, "[]"); err != nil {
+		t.Fatal(err)
+	}
+	invalidateExploreCompletion(ctx, &Project{EndTime: now.Add(time.Hour), IsCompleted: true})
+	if mr.Exists(exploreCandidatesKey) {
+		t.Error("completing a listed project should invalidate")
+	}
+	if err := mr.Set(exploreCandidatesKey, "[]"); err != nil {
+		t.Fatal(err)
+	}
+	invalidateExploreCompletion(ctx, &Project{EndTime: now.Add(time.Hour), IsCompleted: true, HideFromExplore: true})
+	if !mr.Exists(exploreCandidatesKey) {
+		t.Error("completing a project hidden from explore should not invalidate")
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/utils"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatXLSX ExportFormat = "xlsx"
+)
+
+// exportFlushRows 每写出多少行推送一次响应
+const exportFlushRows = 500
+
+// 导出的 item 状态
+const (
+	ExportItemStatusReceived   = "received"
+	ExportItemStatusUnclaimed  = "unclaimed"
+	ExportItemStatusUnreleased = "unreleased"
+	ExportItemStatusRevoked    = "revoked"
+)
+
+var exportHeader = []string{"item_id", "content", "status", "receiver_username", "received_at", "out_trade_no"}
+
+// exportWriter 导出文件的逐行写入器
+type exportWriter interface {
+	Write(record []string) error
+	Flush() error
+	Close() error
+}
+
+type csvExportWriter struct {
+	*csv.Writer
+}
+
+// Write 写入一行,对公式前缀转义
+func (w csvExportWriter) Write(record []string) error {
+	return w.Writer.Write(utils.EscapeFormulaRecord(record))
+}
+
+func (w csvExportWriter) Flush() error {
+	w.Writer.Flush()
+	return w.Writer.Error()
+}
+
+func (w csvExportWriter) Close() error {
+	return w.Flush()
+}
+
+// NewExportWriter 按格式创建导出写入器
+func NewExportWriter(w io.Writer, format ExportFormat) (exportWriter, error) {
+	if format == ExportFormatXLSX {
+		return utils.NewXLSXWriter(w)
+	}
+	// 写入 BOM 便于 Excel 正确识别 UTF-8
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return nil, err
+	}
+	return csvExportWriter{csv.NewWriter(w)}, nil
+}
+
+type projectItemExportRow struct {
+	ID               uint64
+	Content          string
+	Encrypted        bool
+	KeyVersion       int8
+	ReceiverUsername *string
+	ReceivedAt       *time.Time
+	Unreleased       bool
+	RevokedAt        *time.Time
+	OutTradeNo       *string
+}
+
+func (r *projectItemExportRow) record() ([]string, error) {
+	content, err := DecryptItemContent(r.Content, r.Encrypted, r.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+	status := ExportItemStatusUnclaimed
+	if r.RevokedAt != nil {
+		status = ExportItemStatusRevoked
+	} else if r.ReceivedAt != nil {
+		status = ExportItemStatusReceived
+	} else if r.Unreleased {
+		status = ExportItemStatusUnreleased
+	}
+	record := []string{strconv.FormatUint(r.ID, 10), content, status, "", "", ""}
+	if r.ReceiverUsername != nil {
+		record[3] = *r.ReceiverUsername
+	}
+	if r.ReceivedAt != nil {
+		record[4] = r.ReceivedAt.Format(time.DateTime)
+	}
+	if r.OutTradeNo != nil {
+		record[5] = *r.OutTradeNo
+	}
+	return record, nil
+}
+
+// ExportItems 逐行读取项目全部 item 并写出,afterFlush 在每次推送后调用。
+// orderStatuses 为需关联订单号的订单状态,由 payment 包按其 OrderStatus 常量传入。
+func (p *Project) ExportItems(ctx context.Context, w exportWriter, orderStatuses []int8, afterFlush func()) error {
+	rows, err := db.DB(ctx).Raw(`
+		SELECT
+			pi.id,
+			pi.content,
+			pi.encrypted,
+			pi.key_version,
+			u.username AS receiver_username,
+			pi.received_at,
+			(pr.id IS NOT NULL AND pr.released_at IS NULL) AS unreleased,
+			pi.revoked_at,
+			(
+				SELECT po.out_trade_no FROM payment_orders po
+				WHERE po.item_id = pi.id AND po.payer_id = pi.receiver_id AND po.status IN ?
+				ORDER BY po.id DESC LIMIT 1
+			) AS out_trade_no
+		FROM project_items pi
+		LEFT JOIN users u ON u.id = pi.receiver_id
+		LEFT JOIN project_releases pr ON pr.id = pi.release_id
+		WHERE pi.project_id = ?
+		ORDER BY pi.id ASC`,
+		orderStatuses, p.ID,
+	).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := w.Write(exportHeader); err != nil {
+		return err
+	}
+	count := 0
+	for rows.Next() {
+		var row projectItemExportRow
+		if err := db.DB(ctx).ScanRows(rows, &row); err != nil {
+			return err
+		}
+		record, err := row.record()
+		if err != nil {
+			return err
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushRows == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			afterFlush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return w.Close()
+}
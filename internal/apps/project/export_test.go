@@ -0,0 +1,110 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+)
+
+func TestExportItems(t *testing.T) {
+	dbtest.Setup(t, &oauth.User{}, &Project{}, &ProjectItem{}, &ProjectRelease{})
+	ctx := context.Background()
+	tx := db.DB(ctx)
+	// payment 包引用 project 包,此处仅建出导出查询用到的列
+	if err := tx.Exec(`CREATE TABLE payment_orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, item_id INTEGER, payer_id INTEGER, status INTEGER, out_trade_no TEXT)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	receiver := oauth.User{ID: 7, Username: "alice"}
+	if err := tx.Create(&receiver).Error; err != nil {
+		t.Fatal(err)
+	}
+	project := &Project{ID: "export-project", Name: "export", EndTime: time.Now().Add(time.Hour)}
+	if err := tx.Create(project).Error; err != nil {
+		t.Fatal(err)
+	}
+	release := &ProjectRelease{ProjectID: project.ID, ReleaseAt: time.Now().Add(time.Hour), ItemCount: 1}
+	if err := tx.Create(release).Error; err != nil {
+		t.Fatal(err)
+	}
+	receivedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+	items := []ProjectItem{
+		{ProjectID: project.ID, Content: "code-1", ReceiverID: &receiver.ID, ReceivedAt: &receivedAt},
+		{ProjectID: project.ID, Content: "=HYPERLINK(\"x\")"},
+		{ProjectID: project.ID, Content: "code-3", ReleaseID: &release.ID},
+		{ProjectID: project.ID, Content: "code-4", ReceiverID: &receiver.ID, ReceivedAt: &receivedAt, RevokedAt: &receivedAt},
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 仅已成交状态的订单号会被导出
+	if err := tx.Exec(`INSERT INTO payment_orders (item_id, payer_id, status, out_trade_no) VALUES (?, ?, 2, 'T1'), (?, ?, 0, 'T2')`,
+		items[0].ID, receiver.ID, items[3].ID, receiver.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewExportWriter(&buf, ExportFormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flushes := 0
+	if err := project.ExportItems(ctx, w, []int8{2}, func() { flushes++ }); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(buf.Bytes(), []byte(utf8BOM)))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		exportHeader,
+		{"1", "code-1", ExportItemStatusReceived, "alice", receivedAt.Format(time.DateTime), "T1"},
+		{"2", "'=HYPERLINK(\"x\")", ExportItemStatusUnclaimed, "", "", ""},
+		{"3", "code-3", ExportItemStatusUnreleased, "", "", ""},
+		{"4", "code-4", ExportItemStatusRevoked, "alice", receivedAt.Format(time.DateTime), ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %v", len(records), len(want), records)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("record %d col %d: got %q, want %q", i, j, records[i][j], want[i][j])
+			}
+		}
+	}
+	if flushes != 0 {
+		t.Errorf("expected no intermediate flushes, got %d", flushes)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProjectFavorite 用户收藏的项目,未开始的项目也可收藏
+type ProjectFavorite struct {
+	ID        uint64 `json:"id" gorm:"primaryKey,autoIncrement"`
+	UserID    uint64 `json:"user_id" gorm:"uniqueIndex:idx_user_favorite"`
+	ProjectID string `json:"project_id" gorm:"size:64;index;uniqueIndex:idx_user_favorite"`
+	// StartNotified 是否已发送项目开始通知,收藏时项目已开始则直接置为 true
+	StartNotified bool      `json:"-" gorm:"default:false;index"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// CreatorFollow 用户关注的创建者
+type CreatorFollow struct {
+	ID         uint64    `json:"id" gorm:"primaryKey,autoIncrement"`
+	FollowerID uint64    `json:"follower_id" gorm:"uniqueIndex:idx_follower_creator"`
+	CreatorID  uint64    `json:"creator_id" gorm:"index;uniqueIndex:idx_follower_creator"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Favorite 收藏项目,重复收藏无副作用
+func (p *Project) Favorite(tx *gorm.DB, userID uint64) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&ProjectFavorite{
+			UserID:        userID,
+			ProjectID:     p.ID,
+			StartNotified: !time.Now().Before(p.StartTime),
+		}).Error
+}
+
+// Unfavorite 取消收藏
+func (p *Project) Unfavorite(tx *gorm.DB, userID uint64) error {
+	return tx.Where("user_id = ? AND project_id = ?", userID, p.ID).Delete(&ProjectFavorite{}).Error
+}
+
+// IsFavoritedBy 用户是否已收藏该项目
+func (p *Project) IsFavoritedBy(tx *gorm.DB, userID uint64) (bool, error) {
+	var count int64
+	if err := tx.Model(&ProjectFavorite{}).
+		Where("user_id = ? AND project_id = ?", userID, p.ID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AddCreatorFollow 关注创建者,不能关注自己,重复关注无副作用
+func AddCreatorFollow(tx *gorm.DB, follower *oauth.User, creatorID uint64) error {
+	if follower.ID == creatorID {
+		return errors.New(CannotFollowSelf)
+	}
+	var creator oauth.User
+	if err := creator.Exact(tx, creatorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(CreatorNotFound)
+		}
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&CreatorFollow{FollowerID: follower.ID, CreatorID: creatorID}).Error
+}
+
+// RemoveCreatorFollow 取消关注
+func RemoveCreatorFollow(tx *gorm.DB, followerID, creatorID uint64) error {
+	return tx.Where("follower_id = ? AND creator_id = ?", followerID, creatorID).Delete(&CreatorFollow{}).Error
+}
+
+// IsFollowing 是否已关注该创建者
+func IsFollowing(tx *gorm.DB, followerID, creatorID uint64) (bool, error) {
+	var count int64
+	if err := tx.Model(&CreatorFollow{}).
+		Where("follower_id = ? AND creator_id = ?", followerID, creatorID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+type ListFavoriteProjectsResult struct {
+	ListProjectsResponseDataResult
+	FavoritedAt time.Time `json:"favorited_at"`
+	FavoriteID  uint64    `json:"-"`
+}
+
+type ListFavoriteProjectsResponseData struct {
+	Total      int64                         `json:"total"`
+	Results    *[]ListFavoriteProjectsResult `json:"results"`
+	NextCursor string                        `json:"next_cursor,omitempty"`
+}
+
+// ListFavoriteProjectsWithTags 查询我收藏的项目及其标签,已结束的项目同样返回,键集按收藏的 (created_at, id) 降序定位
+func ListFavoriteProjectsWithTags(ctx context.Context, userID uint64, offset, limit int, tags []string, page *utils.CursorPagination) (*ListFavoriteProjectsResponseData, error) {
+	getTotalCountSql := `SELECT COUNT(DISTINCT p.id) as total
+			FROM project_favorites f
+			JOIN projects p ON p.id = f.project_id
+			LEFT JOIN project_tags pt ON p.id = pt.project_id
+			WHERE f.user_id = ? AND p.status = ?`
+
+	getFavoriteProjectWithTagsSql := `SELECT
+				p.id,p.name,p.description,p.distribution_type,p.total_items,
+				p.start_time,p.end_time,p.minimum_trust_level,p.allow_same_ip,p.risk_level,p.price,p.created_at,
+				IF(COUNT(pt.tag) = 0, NULL, JSON_ARRAYAGG(pt.tag)) AS tags,
+				f.created_at AS favorited_at, f.id AS favorite_id
+			FROM project_favorites f
+			JOIN projects p ON p.id = f.project_id
+			LEFT JOIN project_tags pt ON p.id = pt.project_id
+			WHERE f.user_id = ? AND p.status = ?`
+
+	var parameters = []interface{}{userID, ProjectStatusNormal}
+	if len(tags) > 0 {
+		getTotalCountSql += ` AND pt.tag IN (?)`
+		getFavoriteProjectWithTagsSql += ` AND pt.tag IN (?)`
+		parameters = append(parameters, tags)
+	}
+
+	// 查询总数
+	total := utils.UncountedTotal
+	if page.NeedCount() {
+		if err := db.DB(ctx).Raw(getTotalCountSql, parameters...).Count(&total).Error; err != nil {
+			return nil, err
+		}
+
+		// 如果没有符合条件的项目，返回空结果
+		if total == 0 {
+			return &ListFavoriteProjectsResponseData{
+				Total:   0,
+				Results: nil,
+			}, nil
+		}
+	}
+
+	// 游标定位
+	var (
+		cursorFavoritedAt time.Time
+		cursorFavoriteID  uint64
+	)
+	if ok, err := page.DecodeCursor(&cursorFavoritedAt, &cursorFavoriteID); err != nil {
+		return nil, err
+	} else if ok {
+		getFavoriteProjectWithTagsSql += ` AND (f.created_at < ? OR (f.created_at = ? AND f.id < ?))`
+		parameters = append(parameters, cursorFavoritedAt, cursorFavoritedAt, cursorFavoriteID)
+	}
+
+	// 查询项目列表及其标签
+	queryLimit, queryOffset := page.Window(offset, limit)
+	getFavoriteProjectWithTagsSql += ` GROUP BY f.id, p.id ORDER BY f.created_at DESC, f.id DESC LIMIT ? OFFSET ?`
+	parameters = append(parameters, queryLimit, queryOffset)
+	var results []ListFavoriteProjectsResult
+	if err := db.DB(ctx).
+		Raw(getFavoriteProjectWithTagsSql, parameters...).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	results, nextCursor, err := utils.TrimCursorPage(page, results, limit,
+		func(r *ListFavoriteProjectsResult) []interface{} { return []interface{}{r.FavoritedAt, r.FavoriteID} })
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListFavoriteProjectsResponseData{
+		Total:      total,
+		Results:    &results,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// followedCreatorsClause 限定为当前用户关注的创建者发布的项目
+const followedCreatorsClause = ` AND p.creator_id IN (SELECT cf.creator_id FROM creator_follows cf WHERE cf.follower_id = ?)`
+
+// ListFollowingProjectsWithTags 关注的创建者发布的新项目,资格条件与广场一致,键集按 (created_at, id) 降序定位
+func ListFollowingProjectsWithTags(ctx context.Context, offset, limit int, tags []string, currentUser *oauth.User, page *utils.CursorPagination) (*ListProjectsResponseData, error) {
+	getTotalCountSql := `SELECT COUNT(DISTINCT p.id) as total
+			FROM projects p
+			LEFT JOIN project_tags pt ON p.id = pt.project_id
+			WHERE ` + exploreEligibilityClause + followedCreatorsClause
+
+	getProjectWithTagsSql := `SELECT
+				p.id,p.name,p.description,p.distribution_type,p.total_items,
+				p.start_time,p.end_time,p.minimum_trust_level,p.allow_same_ip,p.risk_level,p.price,p.created_at,
+				IF(COUNT(pt.tag) = 0, NULL, JSON_ARRAYAGG(pt.tag)) AS tags
+			FROM projects p
+			LEFT JOIN project_tags pt ON p.id = pt.project_id
+			WHERE ` + exploreEligibilityClause + followedCreatorsClause
+
+	var parameters = append(exploreEligibilityArgs(time.Now(), currentUser), currentUser.ID)
+	if len(tags) > 0 {
+		getTotalCountSql += ` AND pt.tag IN (?)`
+		getProjectWithTagsSql += ` AND pt.tag IN (?)`
+		parameters = append(parameters, tags)
+	}
+
+	// 查询总数
+	total := utils.UncountedTotal
+	if page.NeedCount() {
+		if err := db.DB(ctx).Raw(getTotalCountSql, parameters...).Count(&total).Error; err != nil {
+			return nil, err
+		}
+
+		// 如果没有符合条件的项目，返回空结果
+		if total == 0 {
+			return &ListProjectsResponseData{
+				Total:   0,
+				Results: nil,
+			}, nil
+		}
+	}
+
+	// 游标定位
+	var (
+		cursorCreatedAt time.Time
+		cursorID        string
+	)
+	if ok, err := page.DecodeCursor(&cursorCreatedAt, &cursorID); err != nil {
+		return nil, err
+	} else if ok {
+		getProjectWithTagsSql += ` AND (p.created_at < ? OR (p.created_at = ? AND p.id < ?))`
+		parameters = append(parameters, cursorCreatedAt, cursorCreatedAt, cursorID)
+	}
+
+	// 查询项目列表及其标签
+	queryLimit, queryOffset := page.Window(offset, limit)
+	getProjectWithTagsSql += ` GROUP BY p.id ORDER BY p.created_at DESC, p.id DESC LIMIT ? OFFSET ?`
+	parameters = append(parameters, queryLimit, queryOffset)
+	var listProjectsResponseDataResult []ListProjectsResponseDataResult
+	if err := db.DB(ctx).
+		Raw(getProjectWithTagsSql, parameters...).Scan(&listProjectsResponseDataResult).Error; err != nil {
+		return nil, err
+	}
+	listProjectsResponseDataResult, nextCursor, err := utils.TrimCursorPage(page, listProjectsResponseDataResult, limit,
+		func(r *ListProjectsResponseDataResult) []interface{} { return []interface{}{r.CreatedAt, r.ID} })
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListProjectsResponseData{
+		Total:      total,
+		Results:    &listProjectsResponseDataResult,
+		NextCursor: nextCursor,
+	}, nil
+}
@@ -0,0 +1,326 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/notification"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"github.com/linux-do/cdk/internal/utils"
+)
+
+// setupFavorites 准备收藏者 alice 与创建者 bob、carol 及其项目,alice 仅关注 bob
+func setupFavorites(t *testing.T) *oauth.User {
+	t.Helper()
+	dbtest.Setup(t, &oauth.User{}, &Project{}, &ProjectItem{}, &ProjectTag{}, &ProjectInvitee{},
+		&ProjectFavorite{}, &CreatorFollow{}, &notification.Notification{})
+	tx := db.DB(context.Background())
+
+	user := &oauth.User{ID: 1, Username: "alice", TrustLevel: oauth.TrustLevelNewUser + 1, Score: oauth.BaseUserScore}
+	users := []oauth.User{*user, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"}}
+	if err := tx.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	projects := []Project{
+		{ID: "bob-new", Name: "bob-new", CreatorID: 2, StartTime: now.Add(-time.Minute), EndTime: now.Add(time.Hour), CreatedAt: now},
+		{ID: "bob-old", Name: "bob-old", CreatorID: 2, StartTime: now.Add(-time.Minute), EndTime: now.Add(time.Hour), CreatedAt: now.Add(-time.Hour)},
+		{ID: "bob-upcoming", Name: "bob-upcoming", CreatorID: 2, StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "bob-trust", Name: "bob-trust", CreatorID: 2, EndTime: now.Add(time.Hour), MinimumTrustLevel: oauth.TrustLevelNewUser + 2, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "bob-ended", Name: "bob-ended", CreatorID: 2, EndTime: now.Add(-time.Hour), CreatedAt: now.Add(-4 * time.Hour)},
+		{ID: "bob-hidden", Name: "bob-hidden", CreatorID: 2, EndTime: now.Add(time.Hour), Status: ProjectStatusViolation, CreatedAt: now.Add(-5 * time.Hour)},
+		{ID: "carol", Name: "carol", CreatorID: 3, EndTime: now.Add(time.Hour), CreatedAt: now},
+	}
+	if err := tx.Create(&projects).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&ProjectTag{ProjectID: "bob-old", Tag: "t"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := AddCreatorFollow(tx, user, 2); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// favoriteAll 依次收藏项目,收藏时间递增以固定排序
+func favoriteAll(t *testing.T, userID uint64, projectIDs ...string) {
+	t.Helper()
+	tx := db.DB(context.Background())
+	base := time.Now().Add(-time.Hour)
+	for i, id := range projectIDs {
+		var p Project
+		if err := tx.Where("id = ?", id).First(&p).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Favorite(tx, userID); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Model(&ProjectFavorite{}).Where("user_id = ? AND project_id = ?", userID, id).
+			Update("created_at", base.Add(time.Duration(i)*time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func favoriteIDs(data *ListFavoriteProjectsResponseData) []string {
+	if data.Results == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(*data.Results))
+	for _, r := range *data.Results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestFavoriteProject(t *testing.T) {
+	user := setupFavorites(t)
+	tx := db.DB(context.Background())
+
+	cases := []struct {
+		project      string
+		wantNotified bool
+	}{
+		{"bob-new", true},
+		{"bob-upcoming", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.project, func(t *testing.T) {
+			var p Project
+			if err := tx.Where("id = ?", tc.project).First(&p).Error; err != nil {
+				t.Fatal(err)
+			}
+			// 重复收藏无副作用
+			for range 2 {
+				if err := p.Favorite(tx, user.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var favorites []ProjectFavorite
+			if err := tx.Where("user_id = ? AND project_id = ?", user.ID, p.ID).Find(&favorites).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(favorites) != 1 || favorites[0].StartNotified != tc.wantNotified {
+				t.Fatalf("favorites = %+v, want one with start_notified %v", favorites, tc.wantNotified)
+			}
+			if ok, err := p.IsFavoritedBy(tx, user.ID); err != nil || !ok {
+				t.Fatalf("IsFavoritedBy = %v, %v", ok, err)
+			}
+			if err := p.Unfavorite(tx, user.ID); err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := p.IsFavoritedBy(tx, user.ID); err != nil || ok {
+				t.Fatalf("IsFavoritedBy after unfavorite = %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestAddCreatorFollow(t *testing.T) {
+	user := setupFavorites(t)
+	tx := db.DB(context.Background())
+
+	cases := []struct {
+		name      string
+		creatorID uint64
+		wantErr   string
+	}{
+		{"self", user.ID, CannotFollowSelf},
+		{"missing creator", 99, CreatorNotFound},
+		{"repeat follow", 2, ""},
+		{"new follow", 3, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := AddCreatorFollow(tx, user, tc.creatorID)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := IsFollowing(tx, user.ID, tc.creatorID); err != nil || !ok {
+				t.Fatalf("IsFollowing = %v, %v", ok, err)
+			}
+		})
+	}
+
+	var count int64
+	if err := tx.Model(&CreatorFollow{}).Where("follower_id = ?", user.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("follows = %d, want 2", count)
+	}
+	if err := RemoveCreatorFollow(tx, user.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := IsFollowing(tx, user.ID, 3); err != nil || ok {
+		t.Fatalf("IsFollowing after remove = %v, %v", ok, err)
+	}
+}
+
+func TestListFavoriteProjects(t *testing.T) {
+	user := setupFavorites(t)
+	ctx := context.Background()
+	// 已结束的项目仍返回,违规下架的项目不返回,其他用户的收藏不可见
+	favoriteAll(t, user.ID, "bob-ended", "bob-old", "bob-hidden", "carol", "bob-upcoming")
+	favoriteAll(t, 2, "bob-new")
+
+	cases := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"all", nil, []string{"bob-upcoming", "carol", "bob-old", "bob-ended"}},
+		{"tag", []string{"t"}, []string{"bob-old"}},
+		{"unknown tag", []string{"none"}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := ListFavoriteProjectsWithTags(ctx, user.ID, 0, 10, tc.tags, &utils.CursorPagination{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := favoriteIDs(data); !slices.Equal(got, tc.want) || data.Total != int64(len(tc.want)) {
+				t.Fatalf("got %v (total %d), want %v", got, data.Total, tc.want)
+			}
+		})
+	}
+
+	// 游标模式逐页翻完,不重复不遗漏
+	var (
+		got    []string
+		cursor string
+	)
+	for range 5 {
+		page := &utils.CursorPagination{Cursor: &cursor}
+		data, err := ListFavoriteProjectsWithTags(ctx, user.ID, 0, 3, nil, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.Total != utils.UncountedTotal {
+			t.Fatalf("total = %d, want uncounted", data.Total)
+		}
+		got = append(got, favoriteIDs(data)...)
+		if cursor = data.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if want := cases[0].want; !slices.Equal(got, want) {
+		t.Fatalf("cursor pages = %v, want %v", got, want)
+	}
+}
+
+func TestListFollowingProjects(t *testing.T) {
+	user := setupFavorites(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		// 仅返回关注的 bob 且满足广场资格的项目:排除信任等级不足、已结束与违规的项目
+		{"all", nil, []string{"bob-new", "bob-old", "bob-upcoming"}},
+		{"tag", []string{"t"}, []string{"bob-old"}},
+		{"unknown tag", []string{"none"}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := ListFollowingProjectsWithTags(ctx, 0, 10, tc.tags, user, &utils.CursorPagination{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultIDs(data); !slices.Equal(got, tc.want) || data.Total != int64(len(tc.want)) {
+				t.Fatalf("got %v (total %d), want %v", got, data.Total, tc.want)
+			}
+		})
+	}
+
+	// 取消关注后动态为空
+	if err := RemoveCreatorFollow(db.DB(ctx), user.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ListFollowingProjectsWithTags(ctx, 0, 10, nil, user, &utils.CursorPagination{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(data); len(got) != 0 || data.Total != 0 {
+		t.Fatalf("after unfollow: got %v (total %d)", got, data.Total)
+	}
+}
+
+func TestNotifyFavoritesStarted(t *testing.T) {
+	user := setupFavorites(t)
+	ctx := context.Background()
+	tx := db.DB(ctx)
+	favoriteAll(t, user.ID, "bob-upcoming", "bob-ended")
+	// bob-upcoming 收藏时未开始,开始后才通知;bob-ended 即使未通知也因已结束而跳过
+	if err := tx.Model(&ProjectFavorite{}).Where("project_id = ?", "bob-ended").
+		Update("start_notified", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"not started", time.Now(), 0},
+		{"started", time.Now().Add(90 * time.Minute), 1},
+		{"already notified", time.Now().Add(90 * time.Minute), 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := notifyFavoritesStarted(ctx, tx, tc.now, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tc.want {
+				t.Fatalf("notified = %d, want %d", n, tc.want)
+			}
+		})
+	}
+
+	var notifications []notification.Notification
+	if err := tx.Where("user_id = ?", user.ID).Find(&notifications).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 || notifications[0].ProjectID != "bob-upcoming" ||
+		notifications[0].Type != notification.NotificationTypeFavoriteStarted {
+		t.Fatalf("notifications = %+v", notifications)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/linux-do/cdk/internal/db"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImportFileSize 单次导入文件的大小上限
+	maxImportFileSize = 64 << 20
+	// maxImportIssues 导入摘要中最多返回的问题行数
+	maxImportIssues = 1000
+	// maxItemContentLength 单个 item 明文内容的字符上限
+	maxItemContentLength = 1024
+	// utf8BOM Windows 记事本等工具保存的文件头
+	utf8BOM = "\uFEFF"
+)
+
+type ImportIssueReason string
+
+const (
+	ImportIssueDuplicate ImportIssueReason = "duplicate"
+	ImportIssueTooLong   ImportIssueReason = "too_long"
+	ImportIssueEmpty     ImportIssueReason = "empty"
+)
+
+// ImportLineIssue 未被导入的行及原因
+type ImportLineIssue struct {
+	Line   int               `json:"line"`
+	Reason ImportIssueReason `json:"reason"`
+}
+
+// ImportItemsSummary 导入结果摘要
+type ImportItemsSummary struct {
+	Total           int               `json:"total"`
+	Accepted        int               `json:"accepted"`
+	Duplicate       int               `json:"duplicate"`
+	TooLong         int               `json:"too_long"`
+	Empty           int               `json:"empty"`
+	Issues          []ImportLineIssue `json:"issues"`
+	IssuesTruncated bool              `json:"issues_truncated"`
+}
+
+func (s *ImportItemsSummary) addIssue(line int, reason ImportIssueReason) {
+	switch reason {
+	case ImportIssueDuplicate:
+		s.Duplicate++
+	case ImportIssueTooLong:
+		s.TooLong++
+	case ImportIssueEmpty:
+		s.Empty++
+	}
+	if len(s.Issues) >= maxImportIssues {
+		s.IssuesTruncated = true
+		return
+	}
+	s.Issues = append(s.Issues, ImportLineIssue{Line: line, Reason: reason})
+}
+
+// importLine 导入文件中的一行
+type importLine struct {
+	Line    int
+	Content string
+	TooLong bool
+}
+
+// ItemLineReader 逐行读取导入文件,读取完毕时返回 io.EOF
+type ItemLineReader interface {
+	Next() (importLine, error)
+}
+
+// txtLineReader 每行一个 item,超长行只保留前缀以限制内存占用
+type txtLineReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func NewTXTLineReader(r io.Reader) ItemLineReader {
+	return &txtLineReader{reader: bufio.NewReader(r)}
+}
+
+func (r *txtLineReader) Next() (importLine, error) {
+	var (
+		buf      []byte
+		overflow bool
+	)
+	for {
+		chunk, isPrefix, err := r.reader.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) && (len(buf) > 0 || overflow) {
+				break
+			}
+			return importLine{}, err
+		}
+		// utf8 单字符最多 4 字节,超出后只需知道该行过长
+		if len(buf)+len(chunk) > maxItemContentLength*utf8.UTFMax {
+			overflow = true
+		} else {
+			buf = append(buf, chunk...)
+		}
+		if !isPrefix {
+			break
+		}
+	}
+	r.line++
+	if r.line == 1 {
+		buf = bytes.TrimPrefix(buf, []byte(utf8BOM))
+	}
+	return newImportLine(r.line, string(buf), overflow), nil
+}
+
+// csvLineReader 每条记录取第一列作为 item
+type csvLineReader struct {
+	reader *csv.Reader
+}
+
+func NewCSVLineReader(r io.Reader) ItemLineReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	return &csvLineReader{reader: reader}
+}
+
+func (r *csvLineReader) Next() (importLine, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return importLine{}, err
+	}
+	line, _ := r.reader.FieldPos(0)
+	content := record[0]
+	if line == 1 {
+		content = strings.TrimPrefix(content, utf8BOM)
+	}
+	return newImportLine(line, content, false), nil
+}
+
+func newImportLine(line int, content string, overflow bool) importLine {
+	content = strings.TrimSpace(content)
+	return importLine{
+		Line:    line,
+		Content: content,
+		TooLong: overflow || utf8.RuneCountInString(content) > maxItemContentLength,
+	}
+}
+
+// ImportItems 流式导入 item:逐行校验长度,按内容哈希与文件内及项目已有 item 去重,
+// 每满一批即在独立事务中写入并推入库存。出错时返回已处理部分的摘要。
+func (p *Project) ImportItems(ctx context.Context, reader ItemLineReader) (*ImportItemsSummary, error) {
+	summary := &ImportItemsSummary{Issues: []ImportLineIssue{}}
+	seen := make(map[string]struct{})
+
+	var (
+		contents []string
+		lines    []int
+	)
+	flush := func() error {
+		if len(contents) == 0 {
+			return nil
+		}
+		existing, err := p.ExistingContents(db.DB(ctx), contents)
+		if err != nil {
+			return err
+		}
+		accepted := make([]string, 0, len(contents))
+		for i, content := range contents {
+			if existing[i] {
+				summary.addIssue(lines[i], ImportIssueDuplicate)
+				continue
+			}
+			accepted = append(accepted, content)
+		}
+		contents, lines = contents[:0], lines[:0]
+		// 整批重复时库存未变,不应重置完成状态
+		if len(accepted) == 0 {
+			return nil
+		}
+		if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := p.CreateItems(ctx, tx, accepted, nil); err != nil {
+				return err
+			}
+			return tx.Model(&Project{}).
+				Where("id = ?", p.ID).
+				Updates(map[string]interface{}{
+					"total_items":  gorm.Expr("total_items + ?", len(accepted)),
+					"is_completed": false,
+				}).Error
+		}); err != nil {
+			return err
+		}
+		summary.Accepted += len(accepted)
+		return nil
+	}
+
+	for {
+		line, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return summary, err
+		}
+		summary.Total++
+
+		switch {
+		case line.TooLong:
+			summary.addIssue(line.Line, ImportIssueTooLong)
+			continue
+		case line.Content == "":
+			summary.addIssue(line.Line, ImportIssueEmpty)
+			continue
+		}
+
+		hash := HashItemContent(line.Content)
+		if _, ok := seen[hash]; ok {
+			summary.addIssue(line.Line, ImportIssueDuplicate)
+			continue
+		}
+		seen[hash] = struct{}{}
+
+		contents = append(contents, line.Content)
+		lines = append(lines, line.Line)
+		if len(contents) >= projectItemInsertBatchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+	return summary, flush()
+}
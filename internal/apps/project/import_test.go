@@ -0,0 +1,163 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"gorm.io/gorm"
+)
+
+func readAllLines(t *testing.T, reader ItemLineReader) []importLine {
+	t.Helper()
+	var lines []importLine
+	for {
+		line, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestTXTLineReader(t *testing.T) {
+	input := utf8BOM + "code-1\r\n\n  code-2  \n" + strings.Repeat("x", maxItemContentLength+1) + "\n" + strings.Repeat("啊", maxItemContentLength) + "\ncode-3"
+	lines := readAllLines(t, NewTXTLineReader(strings.NewReader(input)))
+
+	expected := []importLine{
+		{Line: 1, Content: "code-1"},
+		{Line: 2, Content: ""},
+		{Line: 3, Content: "code-2"},
+		{Line: 4, TooLong: true},
+		{Line: 5, Content: strings.Repeat("啊", maxItemContentLength)},
+		{Line: 6, Content: "code-3"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i, want := range expected {
+		got := lines[i]
+		if got.Line != want.Line || got.TooLong != want.TooLong || (!want.TooLong && got.Content != want.Content) {
+			t.Errorf("line %d: expected %+v, got %+v", i+1, want, got)
+		}
+	}
+}
+
+func TestTXTLineReaderVeryLongLine(t *testing.T) {
+	input := strings.Repeat("y", 1<<20) + "\nnext"
+	lines := readAllLines(t, NewTXTLineReader(strings.NewReader(input)))
+	if len(lines) != 2 || !lines[0].TooLong || lines[1].Content != "next" {
+		t.Fatalf("unexpected lines: %d", len(lines))
+	}
+}
+
+func TestCSVLineReader(t *testing.T) {
+	input := utf8BOM + "code-1,note\n\"code,2\",other\n ,\ncode-3\n"
+	lines := readAllLines(t, NewCSVLineReader(strings.NewReader(input)))
+
+	expected := []string{"code-1", "code,2", "", "code-3"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i, want := range expected {
+		if lines[i].Content != want || lines[i].Line != i+1 {
+			t.Errorf("line %d: expected %q, got %+v", i+1, want, lines[i])
+		}
+	}
+}
+
+func TestImportItemsSummaryIssues(t *testing.T) {
+	summary := &ImportItemsSummary{}
+	for i := 0; i < maxImportIssues+1; i++ {
+		summary.addIssue(i+1, ImportIssueEmpty)
+	}
+	summary.addIssue(0, ImportIssueDuplicate)
+	summary.addIssue(0, ImportIssueTooLong)
+	if summary.Empty != maxImportIssues+1 || summary.Duplicate != 1 || summary.TooLong != 1 {
+		t.Fatalf("unexpected counters: %+v", summary)
+	}
+	if len(summary.Issues) != maxImportIssues || !summary.IssuesTruncated {
+		t.Fatalf("expected issues truncated at %d", maxImportIssues)
+	}
+}
+
+func TestImportItemsCompletedStatus(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		accepted  int
+		completed bool
+	}{
+		{"only duplicates keep completed", "existing\n\nexisting\n", 0, true},
+		{"new items reopen project", "existing\nnew-1\nnew-2\n", 2, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbtest.Setup(t, &Project{}, &ProjectItem{})
+			ctx := context.Background()
+			p := &Project{ID: "import-project", Name: "import", DistributionType: DistributionTypeOneForEach, EndTime: time.Now().Add(time.Hour), TotalItems: 1, IsCompleted: true}
+			if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(p).Error; err != nil {
+					return err
+				}
+				return p.CreateItems(ctx, tx, []string{"existing"}, nil)
+			}); err != nil {
+				t.Fatal(err)
+			}
+			// 已有 item 已被领取
+			if err := db.Redis.Del(ctx, p.ItemsKey()).Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			summary, err := p.ImportItems(ctx, NewTXTLineReader(strings.NewReader(tc.input)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Accepted != tc.accepted {
+				t.Errorf("accepted = %d, want %d", summary.Accepted, tc.accepted)
+			}
+			var stored Project
+			if err := db.DB(ctx).First(&stored, "id = ?", p.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.IsCompleted != tc.completed || stored.TotalItems != int64(1+tc.accepted) {
+				t.Errorf("completed = %v total = %d, want %v %d", stored.IsCompleted, stored.TotalItems, tc.completed, 1+tc.accepted)
+			}
+			if stock, _ := p.Stock(ctx); stock != int64(tc.accepted) {
+				t.Errorf("stock = %d, want %d", stock, tc.accepted)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"gorm.io/gorm"
+)
+
+// ProjectInvitee 邀请制项目的受邀用户,每个受邀用户绑定一个预留的 item
+type ProjectInvitee struct {
+	ID        uint64    `json:"id" gorm:"primaryKey,autoIncrement"`
+	ProjectID string    `json:"project_id" gorm:"size:64;index;uniqueIndex:idx_project_invitee"`
+	Username  string    `json:"username" gorm:"size:255;index;uniqueIndex:idx_project_invitee"`
+	ItemID    uint64    `json:"item_id" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ProjectInviteRequest 邀请名单,可同时传用户名与用户 ID,两者合并去重
+type ProjectInviteRequest struct {
+	InviteUsernames []string `json:"invite_usernames" binding:"omitempty,dive,min=1,max=255"`
+	InviteUserIDs   []uint64 `json:"invite_user_ids" binding:"omitempty,dive,gt=0"`
+}
+
+// inviteError 邀请名单与物品不匹配等输入错误,接口以 400 返回
+type inviteError string
+
+func (e inviteError) Error() string {
+	return string(e)
+}
+
+// IsProvided 请求中是否携带了邀请名单
+func (r *ProjectInviteRequest) IsProvided() bool {
+	return r.InviteUsernames != nil || r.InviteUserIDs != nil
+}
+
+// ResolveUsernames 将用户名与用户 ID 合并为去重后的用户名列表,保持传入顺序。
+// 用户 ID 必须对应已登录过本站的用户,否则返回错误。
+func (r *ProjectInviteRequest) ResolveUsernames(tx *gorm.DB) ([]string, error) {
+	usernames := make([]string, 0, len(r.InviteUsernames)+len(r.InviteUserIDs))
+	for _, username := range r.InviteUsernames {
+		username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+		if username != "" && !slices.Contains(usernames, username) {
+			usernames = append(usernames, username)
+		}
+	}
+
+	if len(r.InviteUserIDs) > 0 {
+		var users []oauth.User
+		if err := tx.Select("id, username").Where("id IN ?", r.InviteUserIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		userMap := make(map[uint64]string, len(users))
+		for _, user := range users {
+			userMap[user.ID] = user.Username
+		}
+		for _, id := range r.InviteUserIDs {
+			username, ok := userMap[id]
+			if !ok {
+				return nil, inviteError(fmt.Sprintf(InviteeNotFound, id))
+			}
+			if !slices.Contains(usernames, username) {
+				usernames = append(usernames, username)
+			}
+		}
+	}
+	return usernames, nil
+}
+
+// isPerUserDistribution 抽奖与邀请制项目在 Redis 中以 username -> itemID 的 hash 存储库存
+func (p *Project) isPerUserDistribution() bool {
+	return p.DistributionType == DistributionTypeLottery || p.DistributionType == DistributionTypeInvite
+}
+
+// IsInvitee 判断用户是否在邀请名单内
+func (p *Project) IsInvitee(tx *gorm.DB, username string) (bool, error) {
+	var count int64
+	if err := tx.Model(&ProjectInvitee{}).
+		Where("project_id = ? AND username = ?", p.ID, username).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsVisibleTo 邀请制项目仅对创建者与受邀用户可见
+func (p *Project) IsVisibleTo(tx *gorm.DB, user *oauth.User) (bool, error) {
+	if p.DistributionType != DistributionTypeInvite || p.CreatorID == user.ID {
+		return true, nil
+	}
+	return p.IsInvitee(tx, user.Username)
+}
+
+// CreateInviteItems 为每个受邀用户创建一个 item,并以 username -> itemID 写入 Redis
+func (p *Project) CreateInviteItems(ctx context.Context, tx *gorm.DB, items []string, invitees []string) error {
+	if len(invitees) == 0 {
+		return inviteError(InviteesRequired)
+	}
+	if len(invitees) != len(items) {
+		return inviteError(fmt.Sprintf(InviteesItemsMismatch, len(invitees), len(items)))
+	}
+
+	projectItems, err := newProjectItems(p.ID, items)
+	if err != nil {
+		return err
+	}
+	if err := tx.CreateInBatches(&projectItems, projectItemInsertBatchSize).Error; err != nil {
+		return err
+	}
+
+	projectInvitees := make([]ProjectInvitee, len(invitees))
+	itemUserMap := make(map[string]interface{}, len(invitees))
+	for i, username := range invitees {
+		projectInvitees[i] = ProjectInvitee{ProjectID: p.ID, Username: username, ItemID: projectItems[i].ID}
+		itemUserMap[username] = projectItems[i].ID
+	}
+	if err := tx.CreateInBatches(&projectInvitees, projectItemInsertBatchSize).Error; err != nil {
+		return err
+	}
+
+	// push items to redis
+	return db.Redis.HSet(ctx, p.ItemsKey(), itemUserMap).Err()
+}
+
+// RefreshInvitees 以 invitees 为完整名单更新邀请制项目:
+//   - 新增的受邀用户依次绑定 items 中的内容,数量必须一致
+//   - 被移除且尚未领取的受邀用户,其预留 item 一并删除
+//   - 已领取的受邀用户不允许移除
+//
+// 调用方需在同一事务中随后保存 Project 以持久化 TotalItems 与 IsCompleted。
+func (p *Project) RefreshInvitees(ctx context.Context, tx *gorm.DB, invitees []string, items []string) error {
+	if len(invitees) == 0 {
+		return inviteError(InviteesRequired)
+	}
+
+	var existing []ProjectInvitee
+	if err := tx.Where("project_id = ?", p.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	var (
+		removedItemIDs   []uint64
+		removedUsernames []string
+	)
+	kept := make(map[string]bool, len(existing))
+	for _, invitee := range existing {
+		if slices.Contains(invitees, invitee.Username) {
+			kept[invitee.Username] = true
+			continue
+		}
+		removedItemIDs = append(removedItemIDs, invitee.ItemID)
+		removedUsernames = append(removedUsernames, invitee.Username)
+	}
+
+	added := make([]string, 0, len(invitees))
+	for _, username := range invitees {
+		if !kept[username] {
+			added = append(added, username)
+		}
+	}
+	if len(added) != len(items) {
+		return inviteError(fmt.Sprintf(InviteesItemsMismatch, len(added), len(items)))
+	}
+
+	if len(removedItemIDs) > 0 {
+		var received ProjectInvitee
+		err := tx.Table("project_invitees").
+			Select("project_invitees.username").
+			Joins("INNER JOIN project_items ON project_items.id = project_invitees.item_id").
+			Where("project_invitees.project_id = ? AND project_invitees.item_id IN ? AND project_items.receiver_id IS NOT NULL", p.ID, removedItemIDs).
+			First(&received).Error
+		if err == nil {
+			return inviteError(fmt.Sprintf(InviteeAlreadyReceived, received.Username))
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Where("project_id = ? AND username IN ?", p.ID, removedUsernames).Delete(&ProjectInvitee{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ? AND id IN ?", p.ID, removedItemIDs).Delete(&ProjectItem{}).Error; err != nil {
+			return err
+		}
+		if err := db.Redis.HDel(ctx, p.ItemsKey(), removedUsernames...).Err(); err != nil {
+			return err
+		}
+	}
+
+	if len(added) > 0 {
+		if err := p.CreateInviteItems(ctx, tx, items, added); err != nil {
+			return err
+		}
+	}
+
+	p.TotalItems += int64(len(added) - len(removedItemIDs))
+	hasStock, err := p.HasStock(ctx)
+	if err != nil {
+		return err
+	}
+	p.IsCompleted = !hasStock
+	return nil
+}
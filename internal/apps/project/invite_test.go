@@ -0,0 +1,206 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+)
+
+// setupInvite 准备已登录过的用户 alice(1)、bob(2) 与一个由 creator(9) 创建的邀请制项目
+func setupInvite(t *testing.T) (*miniredis.Miniredis, *Project) {
+	t.Helper()
+	mr := dbtest.Setup(t, &oauth.User{}, &Project{}, &ProjectItem{}, &ProjectInvitee{})
+	tx := db.DB(context.Background())
+	users := []oauth.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 9, Username: "creator"}}
+	if err := tx.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	p := &Project{ID: "invite-project", Name: "invite", CreatorID: 9, DistributionType: DistributionTypeInvite, EndTime: time.Now().Add(time.Hour)}
+	if err := tx.Create(p).Error; err != nil {
+		t.Fatal(err)
+	}
+	return mr, p
+}
+
+func TestResolveUsernames(t *testing.T) {
+	setupInvite(t)
+	tx := db.DB(context.Background())
+
+	cases := []struct {
+		name string
+		req  ProjectInviteRequest
+		want []string
+		err  string
+	}{
+		{"usernames trimmed and deduplicated", ProjectInviteRequest{InviteUsernames: []string{" @alice ", "alice", "", "carol"}}, []string{"alice", "carol"}, ""},
+		{"user ids", ProjectInviteRequest{InviteUserIDs: []uint64{2, 1}}, []string{"bob", "alice"}, ""},
+		{"usernames and ids merged", ProjectInviteRequest{InviteUsernames: []string{"alice"}, InviteUserIDs: []uint64{1, 2}}, []string{"alice", "bob"}, ""},
+		{"unknown id", ProjectInviteRequest{InviteUserIDs: []uint64{1, 404}}, nil, fmt.Sprintf(InviteeNotFound, 404)},
+		{"empty", ProjectInviteRequest{}, []string{}, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.req.ResolveUsernames(tx)
+			if tc.err != "" {
+				if _, ok := err.(inviteError); !ok || err.Error() != tc.err {
+					t.Fatalf("got error %v, want invite error %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsVisibleTo(t *testing.T) {
+	_, p := setupInvite(t)
+	ctx := context.Background()
+	tx := db.DB(ctx)
+	if err := p.CreateInviteItems(ctx, tx, []string{"code-a"}, []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	public := &Project{ID: "public", CreatorID: 9, DistributionType: DistributionTypeOneForEach}
+
+	cases := []struct {
+		name    string
+		project *Project
+		user    *oauth.User
+		visible bool
+	}{
+		{"public project", public, &oauth.User{ID: 2, Username: "bob"}, true},
+		{"creator", p, &oauth.User{ID: 9, Username: "creator"}, true},
+		{"invitee", p, &oauth.User{ID: 1, Username: "alice"}, true},
+		{"not invited", p, &oauth.User{ID: 2, Username: "bob"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			visible, err := tc.project.IsVisibleTo(tx, tc.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if visible != tc.visible {
+				t.Errorf("visible = %v, want %v", visible, tc.visible)
+			}
+		})
+	}
+}
+
+func TestCreateInviteItems(t *testing.T) {
+	mr, p := setupInvite(t)
+	ctx := context.Background()
+	tx := db.DB(ctx)
+
+	cases := []struct {
+		name     string
+		items    []string
+		invitees []string
+		err      string
+	}{
+		{"no invitees", []string{"code-a"}, nil, InviteesRequired},
+		{"count mismatch", []string{"code-a"}, []string{"alice", "bob"}, fmt.Sprintf(InviteesItemsMismatch, 2, 1)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.CreateInviteItems(ctx, tx, tc.items, tc.invitees)
+			if _, ok := err.(inviteError); !ok || err.Error() != tc.err {
+				t.Fatalf("got error %v, want invite error %q", err, tc.err)
+			}
+		})
+	}
+
+	if err := p.CreateInviteItems(ctx, tx, []string{"code-a", "code-b"}, []string{"alice", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	var invitees []ProjectInvitee
+	if err := tx.Where("project_id = ?", p.ID).Order("id").Find(&invitees).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(invitees) != 2 {
+		t.Fatalf("got %d invitees, want 2", len(invitees))
+	}
+	for _, invitee := range invitees {
+		if got := mr.HGet(p.ItemsKey(), invitee.Username); got != fmt.Sprint(invitee.ItemID) {
+			t.Errorf("reservation of %s = %q, want item %d", invitee.Username, got, invitee.ItemID)
+		}
+	}
+}
+
+func TestRefreshInvitees(t *testing.T) {
+	mr, p := setupInvite(t)
+	ctx := context.Background()
+	tx := db.DB(ctx)
+	if err := p.CreateInviteItems(ctx, tx, []string{"code-a", "code-b"}, []string{"alice", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	p.TotalItems = 2
+
+	// 替换未领取的 bob 为 carol
+	if err := p.RefreshInvitees(ctx, tx, []string{"alice", "carol"}, []string{"code-c"}); err != nil {
+		t.Fatal(err)
+	}
+	if mr.HGet(p.ItemsKey(), "bob") != "" || mr.HGet(p.ItemsKey(), "carol") == "" {
+		keys, _ := mr.HKeys(p.ItemsKey())
+		t.Errorf("reservations not refreshed: %v", keys)
+	}
+	var bobItems int64
+	if err := tx.Model(&ProjectInvitee{}).Where("project_id = ? AND username = ?", p.ID, "bob").Count(&bobItems).Error; err != nil {
+		t.Fatal(err)
+	}
+	if bobItems != 0 || p.TotalItems != 2 || p.IsCompleted {
+		t.Errorf("bob invitees %d, total %d, completed %v", bobItems, p.TotalItems, p.IsCompleted)
+	}
+
+	// 已领取的受邀用户不可移除
+	var alice ProjectInvitee
+	if err := tx.Where("project_id = ? AND username = ?", p.ID, "alice").First(&alice).Error; err != nil {
+		t.Fatal(err)
+	}
+	receiverID := uint64(1)
+	if err := tx.Model(&ProjectItem{}).Where("id = ?", alice.ItemID).Update("receiver_id", receiverID).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := p.RefreshInvitees(ctx, tx, []string{"carol"}, nil)
+	if want := fmt.Sprintf(InviteeAlreadyReceived, "alice"); err == nil || err.Error() != want {
+		t.Fatalf("got %v, want %q", err, want)
+	}
+
+	if err := p.RefreshInvitees(ctx, tx, []string{"alice", "carol"}, []string{"extra"}); err == nil {
+		t.Fatal("expected mismatch error when no invitee is added")
+	}
+}
@@ -0,0 +1,293 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// itemContentPrefixLength 前缀盲索引覆盖的字符数
+	itemContentPrefixLength = 6
+	// itemBlindIndexLabel 从加密密钥派生盲索引密钥时使用的标签
+	itemBlindIndexLabel = "cdk:project-item:blind-index"
+	// itemEncryptionInfo HKDF 派生 item 加密密钥时使用的用途标识
+	itemEncryptionInfo = "cdk:project-item:content"
+)
+
+// ValidateItemEncryptionKey 校验已配置的 item 加密密钥强度,未配置时视为明文存储
+func ValidateItemEncryptionKey() error {
+	raw := config.Config.ProjectApp.ItemEncryptionKey
+	if raw == "" {
+		return nil
+	}
+	return utils.ValidateEncryptionKey(raw)
+}
+
+// item 密钥版本,决定加密密钥与盲索引密钥的派生方式
+const (
+	// itemKeyVersionLegacy 旧版派生(utils.DeriveLegacyAESKey),仅用于解密与匹配历史数据
+	itemKeyVersionLegacy int8 = 0
+	// itemKeyVersionHKDF HKDF-SHA256 派生,新写入的 item 均使用该版本
+	itemKeyVersionHKDF int8 = 1
+)
+
+// itemEncryptionKey 返回 item 内容的 AES-256 密钥,未配置时返回 nil 表示明文存储
+func itemEncryptionKey() []byte {
+	return itemEncryptionKeyFor(itemKeyVersionHKDF)
+}
+
+// itemEncryptionKeyFor 按密钥版本派生 item 加密密钥
+func itemEncryptionKeyFor(version int8) []byte {
+	raw := config.Config.ProjectApp.ItemEncryptionKey
+	if raw == "" {
+		return nil
+	}
+	// 非空 key 总能派生成功
+	if version == itemKeyVersionLegacy {
+		key, _ := utils.DeriveLegacyAESKey(raw)
+		return key
+	}
+	key, _ := utils.DeriveHKDFKey(raw, nil, itemEncryptionInfo)
+	return key
+}
+
+// itemBlindIndexKey 返回盲索引密钥,与加密密钥分离,避免同一密钥用于两种用途
+func itemBlindIndexKey() []byte {
+	return itemBlindIndexKeyFor(itemKeyVersionHKDF)
+}
+
+// itemBlindIndexKeyFor 按密钥版本派生盲索引密钥,历史 item 的索引在重新加密前仍由旧版密钥计算
+func itemBlindIndexKeyFor(version int8) []byte {
+	key := itemEncryptionKeyFor(version)
+	if key == nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(itemBlindIndexLabel))
+	return mac.Sum(nil)
+}
+
+// HashItemContent 计算 item 内容的去重索引。
+// 启用加密时为 HMAC-SHA256 盲索引,否则为 SHA-256 摘要。
+func HashItemContent(content string) string {
+	return hashItemContent(itemBlindIndexKey(), "full:", content)
+}
+
+// itemContentHashes 返回内容在各密钥版本下的去重索引,用于匹配尚未重新加密的历史 item
+func itemContentHashes(content string) []string {
+	current := HashItemContent(content)
+	legacyKey := itemBlindIndexKeyFor(itemKeyVersionLegacy)
+	if legacyKey == nil {
+		return []string{current}
+	}
+	return []string{current, hashItemContent(legacyKey, "full:", content)}
+}
+
+// hashItemContentPrefix 计算内容前 itemContentPrefixLength 个字符的盲索引,未启用加密时返回空
+func hashItemContentPrefix(content string) string {
+	return hashItemContentPrefixWith(itemBlindIndexKey(), content)
+}
+
+func hashItemContentPrefixWith(key []byte, content string) string {
+	if key == nil {
+		return ""
+	}
+	runes := []rune(content)
+	if len(runes) > itemContentPrefixLength {
+		runes = runes[:itemContentPrefixLength]
+	}
+	return hashItemContent(key, "prefix:", string(runes))
+}
+
+func hashItemContent(key []byte, scope, content string) string {
+	if key == nil {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(scope))
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetContent 写入 item 内容及索引,启用加密时 Content 保存密文
+func (i *ProjectItem) SetContent(content string) error {
+	i.ContentHash = HashItemContent(content)
+	i.ContentPrefixHash = hashItemContentPrefix(content)
+	i.KeyVersion = itemKeyVersionHKDF
+	key := itemEncryptionKey()
+	if key == nil {
+		i.Content = content
+		i.Encrypted = false
+		return nil
+	}
+	encrypted, err := utils.EncryptAESGCM(key, content)
+	if err != nil {
+		return err
+	}
+	i.Content = encrypted
+	i.Encrypted = true
+	return nil
+}
+
+// PlainContent 返回 item 明文内容,仅在发放给领取者或创建者时调用
+func (i *ProjectItem) PlainContent() (string, error) {
+	return DecryptItemContent(i.Content, i.Encrypted, i.KeyVersion)
+}
+
+// DecryptItemContent 解密按行查询得到的 item 内容,仅使用 keyVersion 对应的派生密钥
+func DecryptItemContent(content string, encrypted bool, keyVersion int8) (string, error) {
+	if !encrypted {
+		return content, nil
+	}
+	key := itemEncryptionKeyFor(keyVersion)
+	if key == nil {
+		return "", errors.New(ItemEncryptionKeyMissing)
+	}
+	return utils.DecryptAESGCM(key, content)
+}
+
+func newProjectItem(projectID, content string) (ProjectItem, error) {
+	item := ProjectItem{ProjectID: projectID}
+	if err := item.SetContent(content); err != nil {
+		return ProjectItem{}, err
+	}
+	return item, nil
+}
+
+func newProjectItems(projectID string, contents []string) ([]ProjectItem, error) {
+	items := make([]ProjectItem, len(contents))
+	for i, content := range contents {
+		item, err := newProjectItem(projectID, content)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// itemContentSearchClause 构造 item 内容搜索条件:
+// 明文 item 使用 LIKE 模糊匹配;加密 item 只能通过盲索引匹配。
+// 长度恰为 itemContentPrefixLength 的查询按前缀盲索引匹配,更长的查询视为完整兑换码,
+// 仅比较完整内容的盲索引,避免前缀相同的其他兑换码被一并返回。
+func itemContentSearchClause(search, likePattern string) (string, []interface{}) {
+	clause := "(project_items.encrypted = ? AND project_items.content LIKE ?) OR project_items.content_hash IN ?"
+	args := []interface{}{false, likePattern, itemContentHashes(search)}
+	if prefixHash := hashItemContentPrefix(search); prefixHash != "" && len([]rune(search)) == itemContentPrefixLength {
+		legacyPrefixHash := hashItemContentPrefixWith(itemBlindIndexKeyFor(itemKeyVersionLegacy), search)
+		clause += " OR project_items.content_prefix_hash IN ?"
+		args = append(args, []string{prefixHash, legacyPrefixHash})
+	}
+	return clause, args
+}
+
+// BackfillItemContentHashes 回填历史明文 item 的内容哈希,与未配置加密密钥时的 HashItemContent 一致,返回回填数量。
+// 启用加密后由 EncryptExistingItems 重写为盲索引
+func BackfillItemContentHashes(ctx context.Context) (int64, error) {
+	result := db.DB(ctx).Exec(
+		"UPDATE project_items SET content_hash = SHA2(content, 256) WHERE encrypted = false AND (content_hash = '' OR content_hash IS NULL)",
+	)
+	return result.RowsAffected, result.Error
+}
+
+// EncryptExistingItems 分批加密存量明文 item,并以盲索引重建去重索引,返回加密数量
+func EncryptExistingItems(ctx context.Context) (int64, error) {
+	if itemEncryptionKey() == nil {
+		return 0, errors.New(ItemEncryptionKeyMissing)
+	}
+	return rewriteItems(ctx, "encrypted = ?", []interface{}{false})
+}
+
+// ReencryptLegacyItems 分批将旧版派生密钥加密的 item 以当前密钥重新加密并重建盲索引,返回处理数量
+func ReencryptLegacyItems(ctx context.Context) (int64, error) {
+	if itemEncryptionKey() == nil {
+		return 0, errors.New(ItemEncryptionKeyMissing)
+	}
+	return rewriteItems(ctx, "encrypted = ? AND key_version = ?", []interface{}{true, itemKeyVersionLegacy})
+}
+
+// rewriteItems 按 id 顺序分批读取满足条件的 item,解密后以当前密钥重写内容与索引。
+// 更新时再次校验条件,避免覆盖并发写入的数据。
+func rewriteItems(ctx context.Context, condition string, args []interface{}) (int64, error) {
+	var (
+		total  int64
+		lastID uint64
+	)
+	for {
+		var items []ProjectItem
+		if err := db.DB(ctx).
+			Where("id > ?", lastID).
+			Where(condition, args...).
+			Order("id ASC").
+			Limit(projectItemInsertBatchSize).
+			Find(&items).Error; err != nil {
+			return total, err
+		}
+		if len(items) == 0 {
+			return total, nil
+		}
+
+		if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, item := range items {
+				content, err := item.PlainContent()
+				if err != nil {
+					return err
+				}
+				if err := item.SetContent(content); err != nil {
+					return err
+				}
+				if err := tx.Model(&ProjectItem{}).
+					Where("id = ?", item.ID).
+					Where(condition, args...).
+					Updates(map[string]interface{}{
+						"content":             item.Content,
+						"encrypted":           item.Encrypted,
+						"key_version":         item.KeyVersion,
+						"content_hash":        item.ContentHash,
+						"content_prefix_hash": item.ContentPrefixHash,
+					}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return total, err
+		}
+
+		total += int64(len(items))
+		lastID = items[len(items)-1].ID
+	}
+}
@@ -0,0 +1,249 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"github.com/linux-do/cdk/internal/utils"
+)
+
+func withItemEncryptionKey(t *testing.T, key string) {
+	t.Helper()
+	old := config.Config.ProjectApp.ItemEncryptionKey
+	config.Config.ProjectApp.ItemEncryptionKey = key
+	t.Cleanup(func() { config.Config.ProjectApp.ItemEncryptionKey = old })
+}
+
+func TestProjectItemSetContentPlaintext(t *testing.T) {
+	withItemEncryptionKey(t, "")
+	item, err := newProjectItem("p1", "CODE-123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Encrypted || item.Content != "CODE-123456" || item.ContentPrefixHash != "" {
+		t.Fatalf("unexpected plaintext item: %+v", item)
+	}
+}
+
+func TestProjectItemSetContentEncrypted(t *testing.T) {
+	withItemEncryptionKey(t, "item-secret")
+	item, err := newProjectItem("p1", "CODE-123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !item.Encrypted || item.Content == "CODE-123456" {
+		t.Fatalf("content should be encrypted: %+v", item)
+	}
+	plain, err := item.PlainContent()
+	if err != nil || plain != "CODE-123456" {
+		t.Fatalf("decrypt: got %q, %v", plain, err)
+	}
+	if item.ContentHash != HashItemContent("CODE-123456") {
+		t.Fatal("content hash should be deterministic")
+	}
+	if item.ContentPrefixHash != hashItemContentPrefix("CODE-1") {
+		t.Fatal("prefix hash should match the first runes")
+	}
+
+	withItemEncryptionKey(t, "")
+	if _, err := item.PlainContent(); err == nil {
+		t.Fatal("decrypt without key should fail")
+	}
+}
+
+func TestItemContentSearchClauseEncrypted(t *testing.T) {
+	withItemEncryptionKey(t, "item-secret")
+	dbtest.Setup(t, &ProjectItem{})
+	tx := db.DB(context.Background())
+
+	items, err := newProjectItems("p1", []string{"CODE-123456", "CODE-123999", "CODE-999999"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query string) []uint64 {
+		clause, args := itemContentSearchClause(query, "%"+query+"%")
+		var ids []uint64
+		if err := tx.Model(&ProjectItem{}).Where(clause, args...).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+
+	cases := map[string][]uint64{
+		// 完整兑换码只命中自身,不因前缀相同命中其他兑换码
+		"CODE-123456": {items[0].ID},
+		"CODE-123457": nil,
+		// 恰为前缀长度时按前缀盲索引匹配
+		"CODE-1": {items[0].ID, items[1].ID},
+		// 更短的查询无法通过盲索引匹配
+		"CODE-": nil,
+	}
+	for query, want := range cases {
+		if got := search(query); !slices.Equal(got, want) {
+			t.Errorf("search %q: got %v, want %v", query, got, want)
+		}
+	}
+}
+
+// newLegacyItem 按旧版密钥派生构造历史 item,模拟升级前写入的数据
+func newLegacyItem(t *testing.T, projectID, content string) ProjectItem {
+	t.Helper()
+	legacyKey, err := utils.DeriveLegacyAESKey(config.Config.ProjectApp.ItemEncryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := utils.EncryptAESGCM(legacyKey, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey := itemBlindIndexKeyFor(itemKeyVersionLegacy)
+	return ProjectItem{
+		ProjectID:         projectID,
+		Content:           encrypted,
+		Encrypted:         true,
+		KeyVersion:        itemKeyVersionLegacy,
+		ContentHash:       hashItemContent(blindKey, "full:", content),
+		ContentPrefixHash: hashItemContentPrefixWith(blindKey, content),
+	}
+}
+
+func TestDecryptItemContentLegacy(t *testing.T) {
+	withItemEncryptionKey(t, "item-secret")
+	item := newLegacyItem(t, "p1", "CODE-123456")
+	plain, err := item.PlainContent()
+	if err != nil || plain != "CODE-123456" {
+		t.Fatalf("decrypt legacy: got %q, %v", plain, err)
+	}
+	if item.ContentHash == HashItemContent("CODE-123456") {
+		t.Fatal("legacy and current blind index should differ")
+	}
+}
+
+func TestDecryptItemContentUsesKeyVersion(t *testing.T) {
+	withItemEncryptionKey(t, "item-secret")
+	current, err := newProjectItem("p1", "CODE-654321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := newLegacyItem(t, "p1", "CODE-123456")
+
+	cases := []struct {
+		name    string
+		item    ProjectItem
+		version int8
+		want    string
+	}{
+		{"current item with current key", current, itemKeyVersionHKDF, "CODE-654321"},
+		{"legacy item with legacy key", legacy, itemKeyVersionLegacy, "CODE-123456"},
+		{"current item with legacy key", current, itemKeyVersionLegacy, ""},
+		{"legacy item with current key", legacy, itemKeyVersionHKDF, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plain, err := DecryptItemContent(tc.item.Content, tc.item.Encrypted, tc.version)
+			if tc.want == "" {
+				if err == nil {
+					t.Fatalf("decrypt with mismatched key version should fail, got %q", plain)
+				}
+				return
+			}
+			if err != nil || plain != tc.want {
+				t.Fatalf("got %q, %v; want %q", plain, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLegacyItemsMatchAndReencrypt(t *testing.T) {
+	withItemEncryptionKey(t, "item-secret")
+	dbtest.Setup(t, &ProjectItem{})
+	ctx := context.Background()
+	tx := db.DB(ctx)
+
+	legacy := newLegacyItem(t, "p1", "CODE-123456")
+	if err := tx.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	current, err := newProjectItem("p1", "CODE-654321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&current).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	assertMatched := func(stage string) {
+		t.Helper()
+		p := &Project{ID: "p1"}
+		existing, err := p.ExistingContents(tx, []string{"CODE-123456", "CODE-654321", "CODE-000000"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(existing, []bool{true, true, false}) {
+			t.Fatalf("%s: dedupe got %v", stage, existing)
+		}
+		for query, want := range map[string]uint64{"CODE-123456": legacy.ID, "CODE-1": legacy.ID, "CODE-654321": current.ID} {
+			clause, args := itemContentSearchClause(query, "%"+query+"%")
+			var ids []uint64
+			if err := tx.Model(&ProjectItem{}).Where(clause, args...).Pluck("id", &ids).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, []uint64{want}) {
+				t.Fatalf("%s: search %q got %v, want %d", stage, query, ids, want)
+			}
+		}
+	}
+	assertMatched("legacy")
+
+	count, err := ReencryptLegacyItems(ctx)
+	if err != nil || count != 1 {
+		t.Fatalf("reencrypt: count=%d err=%v", count, err)
+	}
+	var migrated ProjectItem
+	if err := migrated.Exact(tx, legacy.ID); err != nil {
+		t.Fatal(err)
+	}
+	if migrated.KeyVersion != itemKeyVersionHKDF || migrated.ContentHash != HashItemContent("CODE-123456") {
+		t.Fatalf("item not migrated: %+v", migrated)
+	}
+	if plain, err := utils.DecryptAESGCM(itemEncryptionKey(), migrated.Content); err != nil || plain != "CODE-123456" {
+		t.Fatalf("migrated content should use current key: %q, %v", plain, err)
+	}
+	assertMatched("migrated")
+
+	if count, err := ReencryptLegacyItems(ctx); err != nil || count != 0 {
+		t.Fatalf("second run should be a no-op: count=%d err=%v", count, err)
+	}
+}
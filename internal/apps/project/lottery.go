@@ -0,0 +1,502 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/cdk/internal/apps/notification"
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/task"
+	"github.com/linux-do/cdk/internal/task/schedule"
+	"github.com/linux-do/cdk/internal/utils"
+	"gorm.io/gorm"
+)
+
+type WinnerSourceType int8
+
+const (
+	// WinnerSourceDiscourseBot 从 Discourse 话题中抽奖机器人的开奖回复解析中奖者
+	WinnerSourceDiscourseBot WinnerSourceType = iota
+	// WinnerSourceManual 创建者直接提交中奖者名单
+	WinnerSourceManual
+	// WinnerSourceDraw 由本服务基于公开种子执行可验证抽奖
+	WinnerSourceDraw
+	// WinnerSourceScheduledDraw 用户在 StartTime-EndTime 报名,EndTime 由定时任务开奖
+	WinnerSourceScheduledDraw
+)
+
+const defaultDiscourseBotUsername = "lottery_bot"
+
+// WinnerSource 抽奖项目的中奖者来源。
+// Winners 返回与奖品一一对应的中奖用户名列表,同一用户可出现多次以领取多份奖品。
+type WinnerSource interface {
+	Winners(ctx context.Context, p *Project, itemCount int) ([]string, error)
+}
+
+type TopicResponse struct {
+	UserID     uint64     `json:"user_id"`
+	Tags       []TopicTag `json:"tags"`
+	Closed     bool       `json:"closed"`
+	PostStream struct {
+		Posts []struct {
+			Username string `json:"username"`
+			Raw      string `json:"raw"`
+		} `json:"posts"`
+	} `json:"post_stream"`
+}
+
+type TopicTag struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+var (
+	lotteryBotWinnerSectionRegex = regexp.MustCompile(`### 以下为中奖佬友及对应楼层：\n((?s).+)`)
+	lotteryBotWinnerRegex        = regexp.MustCompile(`@(\S+)`)
+)
+
+// DiscourseBotWinnerSource 解析 Discourse 话题内抽奖机器人的开奖回复
+type DiscourseBotWinnerSource struct {
+	BaseURL            string
+	ApiKey             string
+	ApiUsername        string
+	BotUsername        string
+	WinnerSectionRegex *regexp.Regexp
+	TopicID            uint64
+}
+
+// NewDiscourseBotWinnerSource 使用配置的 Discourse 站点与 lottery_bot 的默认配置
+func NewDiscourseBotWinnerSource(topicID uint64) *DiscourseBotWinnerSource {
+	return &DiscourseBotWinnerSource{
+		BaseURL:            notification.DiscourseBaseURL(),
+		ApiKey:             config.Config.LinuxDo.ApiKey,
+		ApiUsername:        config.Config.LinuxDo.ApiUsername,
+		BotUsername:        defaultDiscourseBotUsername,
+		WinnerSectionRegex: lotteryBotWinnerSectionRegex,
+		TopicID:            topicID,
+	}
+}
+
+func (s *DiscourseBotWinnerSource) Winners(ctx context.Context, p *Project, itemCount int) ([]string, error) {
+	if s.TopicID == 0 {
+		return nil, errors.New(TopicRequired)
+	}
+
+	headers := map[string]string{
+		"Api-Key":      s.ApiKey,
+		"Api-Username": s.ApiUsername,
+	}
+
+	// 获取话题基本信息
+	url := fmt.Sprintf("%s/t/%d.json?username_filters=%s&include_raw=true", strings.TrimRight(s.BaseURL, "/"), s.TopicID, s.BotUsername)
+	topicResp, errRequest := utils.Request(ctx, http.MethodGet, url, nil, headers, nil)
+	if errRequest != nil {
+		return nil, errRequest
+	}
+	defer topicResp.Body.Close()
+
+	if topicResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取话题信息失败，状态码: %d", topicResp.StatusCode)
+	}
+
+	var response TopicResponse
+	if errDecode := json.NewDecoder(topicResp.Body).Decode(&response); errDecode != nil {
+		return nil, fmt.Errorf("解析话题信息失败: %w", errDecode)
+	}
+
+	hasLotteryTag := slices.ContainsFunc(response.Tags, func(tag TopicTag) bool {
+		return tag.Slug == "lottery"
+	})
+
+	if !hasLotteryTag {
+		return nil, errors.New("话题未添加抽奖标签，无法创建抽奖项目")
+	}
+
+	if !response.Closed {
+		return nil, errors.New("抽奖还未结束，无法创建抽奖项目")
+	}
+
+	var content string
+	for i := len(response.PostStream.Posts) - 1; i >= 0; i-- {
+		post := response.PostStream.Posts[i]
+		if post.Username == s.BotUsername {
+			content = post.Raw
+			break
+		}
+	}
+
+	if content == "" {
+		return nil, errors.New("未找到抽奖机器人的开奖回复")
+	}
+
+	// 校验当前创建项目的人，是不是该帖子的真实楼主
+	if response.UserID != p.CreatorID {
+		return nil, errors.New("非话题作者，无法创建抽奖项目")
+	}
+
+	winnerSection := s.WinnerSectionRegex.FindStringSubmatch(content)
+	if len(winnerSection) < 2 {
+		return nil, errors.New("未找到中奖用户部分")
+	}
+
+	// 提取所有中奖用户名
+	winnersMatches := lotteryBotWinnerRegex.FindAllStringSubmatch(winnerSection[1], -1)
+	if len(winnersMatches) == 0 {
+		return nil, errors.New("未找到任何中奖用户")
+	}
+
+	if len(winnersMatches) != itemCount {
+		return nil, fmt.Errorf(WinnersItemsMismatch, len(winnersMatches), itemCount)
+	}
+
+	winners := make([]string, 0, len(winnersMatches))
+	for _, match := range winnersMatches {
+		if len(match) > 1 {
+			winners = append(winners, match[1])
+		}
+	}
+	return winners, nil
+}
+
+// ManualWinnerSource 创建者提交的中奖者名单,按顺序与奖品对应
+type ManualWinnerSource struct {
+	Usernames []string
+}
+
+func (s *ManualWinnerSource) Winners(_ context.Context, _ *Project, itemCount int) ([]string, error) {
+	winners := make([]string, 0, len(s.Usernames))
+	for _, username := range s.Usernames {
+		if username = strings.TrimPrefix(strings.TrimSpace(username), "@"); username != "" {
+			winners = append(winners, username)
+		}
+	}
+	if len(winners) == 0 {
+		return nil, errors.New(WinnersRequired)
+	}
+	if len(winners) != itemCount {
+		return nil, fmt.Errorf(WinnersItemsMismatch, len(winners), itemCount)
+	}
+	return winners, nil
+}
+
+// DrawWinnerSource 基于种子的可验证抽奖:
+// 候选人去重后按字典序排列,以 SHA-256(seed:i) 驱动 Fisher-Yates 洗牌,取前 itemCount 名。
+// 种子哈希在开奖前公开,开奖后公开种子,任何人可用 DrawWinners 复现结果。
+type DrawWinnerSource struct {
+	Candidates []string
+	Seed       string
+}
+
+// NewDrawWinnerSource 使用随机种子创建抽奖来源
+func NewDrawWinnerSource(candidates []string) (*DrawWinnerSource, error) {
+	seed, err := GenerateDrawSeed()
+	if err != nil {
+		return nil, err
+	}
+	return &DrawWinnerSource{Candidates: candidates, Seed: seed}, nil
+}
+
+func (s *DrawWinnerSource) Winners(_ context.Context, _ *Project, itemCount int) ([]string, error) {
+	candidates := normalizeDrawCandidates(s.Candidates)
+	if len(candidates) < itemCount {
+		return nil, fmt.Errorf(CandidatesNotEnough, len(candidates), itemCount)
+	}
+	return DrawWinners(s.Seed, candidates, itemCount), nil
+}
+
+// GenerateDrawSeed 生成 32 字节随机种子(十六进制)
+func GenerateDrawSeed() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// DrawSeedHash 种子承诺值,开奖前公开
+func DrawSeedHash(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// DrawWinners 确定性抽取 count 名中奖者,结果仅取决于 seed 与候选人集合
+func DrawWinners(seed string, candidates []string, count int) []string {
+	pool := normalizeDrawCandidates(candidates)
+	for i := len(pool) - 1; i > 0; i-- {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", seed, i)))
+		j := int(binary.BigEndian.Uint64(sum[:8]) % uint64(i+1))
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+	if count > len(pool) {
+		count = len(pool)
+	}
+	return pool[:count]
+}
+
+// normalizeDrawCandidates 去重并按字典序排列,保证洗牌输入与提交顺序无关
+func normalizeDrawCandidates(candidates []string) []string {
+	pool := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "@"); candidate != "" {
+			pool = append(pool, candidate)
+		}
+	}
+	slices.Sort(pool)
+	return slices.Compact(pool)
+}
+
+// SaveLotteryEntries 保存内置抽奖的候选人名单,供开奖后复核
+func (p *Project) SaveLotteryEntries(tx *gorm.DB, candidates []string) error {
+	pool := normalizeDrawCandidates(candidates)
+	if len(pool) == 0 {
+		return nil
+	}
+	entries := make([]ProjectLotteryEntry, len(pool))
+	for i, username := range pool {
+		entries[i] = ProjectLotteryEntry{ProjectID: p.ID, Username: username}
+	}
+	return tx.CreateInBatches(&entries, projectItemInsertBatchSize).Error
+}
+
+// LotteryRequest 抽奖项目的中奖者来源参数
+type LotteryRequest struct {
+	TopicId           uint64           `json:"topic_id" binding:"omitempty,gt=0"`
+	WinnerSource      WinnerSourceType `json:"winner_source" binding:"oneof=0 1 2 3"`
+	Winners           []string         `json:"winners" binding:"omitempty,dive,min=1,max=255"`
+	LotteryCandidates []string         `json:"lottery_candidates" binding:"omitempty,dive,min=1,max=255"`
+	ClaimEndTime      *time.Time       `json:"claim_end_time"`
+}
+
+// NewWinnerSource 按请求构造中奖者来源
+func (r *LotteryRequest) NewWinnerSource() (WinnerSource, error) {
+	switch r.WinnerSource {
+	case WinnerSourceManual:
+		return &ManualWinnerSource{Usernames: r.Winners}, nil
+	case WinnerSourceDraw:
+		return NewDrawWinnerSource(r.LotteryCandidates)
+	case WinnerSourceScheduledDraw:
+		// 报名抽奖在开奖任务中选出中奖者
+		return nil, nil
+	default:
+		return NewDiscourseBotWinnerSource(r.TopicId), nil
+	}
+}
+
+// ProjectLotteryEntry 内置抽奖的候选人,开奖后用于公开复核
+type ProjectLotteryEntry struct {
+	ID        uint64 `json:"id" gorm:"primaryKey,autoIncrement"`
+	ProjectID string `json:"project_id" gorm:"size:64;index;uniqueIndex:idx_project_entrant"`
+	Username  string `json:"username" gorm:"size:255;uniqueIndex:idx_project_entrant"`
+	// WinningItemID 报名抽奖开奖时绑定的奖品,未中奖为空
+	WinningItemID *uint64   `json:"winning_item_id" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// RegisterEntry 报名抽奖。报名时间与开奖状态在 INSERT ... SELECT 中与项目行一并校验:
+// 开奖事务先写 drawn_at 并持有行锁,报名要么在开奖读取名单前提交,要么在开奖后因条件不满足而失败。
+func (p *Project) RegisterEntry(tx *gorm.DB, username string, now time.Time) error {
+	result := tx.Exec(`INSERT INTO project_lottery_entries (project_id, username, created_at)
+			SELECT id, ?, ? FROM projects
+			WHERE id = ? AND status = ? AND drawn_at IS NULL AND start_time <= ? AND end_time > ?`,
+		username, now, p.ID, ProjectStatusNormal, now, now)
+	if result.Error != nil {
+		if strings.Contains(result.Error.Error(), "Duplicate") || strings.Contains(result.Error.Error(), "UNIQUE") {
+			return errors.New(AlreadyRegistered)
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(RegistrationClosed)
+	}
+	return nil
+}
+
+// DrawnWinners 返回开奖结果:优先读取开奖时保存的中奖者,未保存时按种子与开奖奖品数复现
+func (p *Project) DrawnWinners(tx *gorm.DB, entrants []string) ([]string, error) {
+	if p.DrawnAt == nil {
+		return nil, nil
+	}
+	var winners []string
+	if err := tx.Model(&ProjectLotteryEntry{}).
+		Where("project_id = ? AND winning_item_id IS NOT NULL", p.ID).
+		Order("winning_item_id ASC").
+		Pluck("username", &winners).Error; err != nil {
+		return nil, err
+	}
+	if len(winners) > 0 {
+		return winners, nil
+	}
+	return DrawWinners(p.DrawSeed, entrants, int(p.DrawnItemCount())), nil
+}
+
+// DrawnItemCount 开奖时参与抽取的奖品数量,早期项目未记录时取 TotalItems
+func (p *Project) DrawnItemCount() int64 {
+	if p.DrawItemCount > 0 {
+		return p.DrawItemCount
+	}
+	return p.TotalItems
+}
+
+// EnqueueDraw 下发在 EndTime 执行的开奖任务,任务本身幂等,重复下发无副作用
+func (p *Project) EnqueueDraw(ctx context.Context) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"project_id": p.ID,
+	})
+	if _, err := schedule.AsynqClient.EnqueueContext(ctx, asynq.NewTask(task.DrawLotteryTask, payload), asynq.ProcessAt(p.EndTime), asynq.MaxRetry(10)); err != nil {
+		return fmt.Errorf("下发项目[%s]开奖任务失败: %w", p.ID, err)
+	}
+	return nil
+}
+
+// DrawResult 一次开奖的中奖者及其按顺序绑定的奖品,未中奖时为空
+type DrawResult struct {
+	Winners []string
+	ItemIDs []uint64
+}
+
+// Draw 对报名抽奖执行开奖:以创建时承诺的种子从报名者中抽取中奖者,中奖者按顺序绑定奖品;
+// 报名人数不足时剩余奖品不分配。通过 drawn_at IS NULL 的 CAS 保证只开奖一次,
+// 已开奖时返回 nil。Redis 预留与中奖通知在事务提交后由 PublishDraw 完成。
+func (p *Project) Draw(tx *gorm.DB, now time.Time) (*DrawResult, error) {
+	// 种子须与创建时公开的哈希一致,否则结果不可验证
+	if p.DrawSeedHash == "" || DrawSeedHash(p.DrawSeed) != p.DrawSeedHash {
+		return nil, errors.New(DrawSeedMismatch)
+	}
+
+	var itemIDs []uint64
+	if err := tx.Model(&ProjectItem{}).
+		Where("project_id = ? AND receiver_id IS NULL", p.ID).
+		Order("id ASC").
+		Pluck("id", &itemIDs).Error; err != nil {
+		return nil, err
+	}
+
+	// 同时记录参与抽取的奖品数量,GetLottery 以此复现开奖结果
+	result := tx.Model(&Project{}).
+		Where("id = ? AND drawn_at IS NULL", p.ID).
+		Updates(map[string]interface{}{
+			"drawn_at":        now,
+			"draw_item_count": len(itemIDs),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	p.DrawnAt = &now
+	p.DrawItemCount = int64(len(itemIDs))
+
+	// drawn_at 写入后再读取名单,之后的报名会被 RegisterEntry 拒绝
+	var entrants []string
+	if err := tx.Model(&ProjectLotteryEntry{}).
+		Where("project_id = ?", p.ID).
+		Pluck("username", &entrants).Error; err != nil {
+		return nil, err
+	}
+
+	winners := DrawWinners(p.DrawSeed, entrants, len(itemIDs))
+	if len(winners) == 0 {
+		p.IsCompleted = true
+		return &DrawResult{}, tx.Model(&Project{}).Where("id = ?", p.ID).Update("is_completed", true).Error
+	}
+
+	for i, winner := range winners {
+		if err := tx.Model(&ProjectLotteryEntry{}).
+			Where("project_id = ? AND username = ?", p.ID, winner).
+			Update("winning_item_id", itemIDs[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &DrawResult{Winners: winners, ItemIDs: itemIDs[:len(winners)]}, nil
+}
+
+// PublishDraw 在开奖事务提交后写入中奖者的奖品预留并通知中奖者,无人中奖时使广场缓存失效。
+// 写入失败时由开奖任务重试,经 RestoreDrawReservations 补写。
+func (p *Project) PublishDraw(ctx context.Context, r *DrawResult) error {
+	if len(r.Winners) == 0 {
+		invalidateExploreCompletion(ctx, p)
+		return nil
+	}
+	itemUserMap := make(map[string]interface{}, len(r.Winners))
+	for i, winner := range r.Winners {
+		itemUserMap[winner] = r.ItemIDs[i]
+	}
+	// push items to redis
+	if err := db.Redis.HSet(ctx, p.ItemsKey(), itemUserMap).Err(); err != nil {
+		return err
+	}
+	return p.notifyLotteryWinners(ctx, db.DB(ctx), r.Winners)
+}
+
+// RestoreDrawReservations 按已持久化的开奖结果为尚未领取且缺少预留的中奖者补写预留并补发通知,
+// 已存在的预留不受影响,领取截止后不再补写
+func (p *Project) RestoreDrawReservations(ctx context.Context, now time.Time) error {
+	if p.ClaimEndTime != nil && !now.Before(*p.ClaimEndTime) {
+		return nil
+	}
+	var rows []struct {
+		Username      string
+		WinningItemID uint64
+	}
+	if err := db.DB(ctx).Table("project_lottery_entries e").
+		Select("e.username, e.winning_item_id").
+		Joins("INNER JOIN project_items i ON i.id = e.winning_item_id").
+		Where("e.project_id = ? AND i.receiver_id IS NULL", p.ID).
+		Order("e.winning_item_id ASC").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	var restored []string
+	for _, row := range rows {
+		ok, err := db.Redis.HSetNX(ctx, p.ItemsKey(), row.Username, row.WinningItemID).Result()
+		if err != nil {
+			return err
+		}
+		if ok {
+			restored = append(restored, row.Username)
+		}
+	}
+	if len(restored) == 0 {
+		return nil
+	}
+	return p.notifyLotteryWinners(ctx, db.DB(ctx), restored)
+}
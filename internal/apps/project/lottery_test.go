@@ -0,0 +1,359 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/cdk/internal/apps/notification"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"github.com/linux-do/cdk/internal/task"
+	"gorm.io/gorm"
+)
+
+// newTopicServer 模拟 Discourse 的 /t/{id}.json 接口
+func newTopicServer(t *testing.T, topic map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/t/42.json" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Api-Key") != "KEY" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(topic)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func lotteryTopic(closed bool, creatorID uint64, botRaw string) map[string]any {
+	return map[string]any{
+		"user_id": creatorID,
+		"closed":  closed,
+		"tags":    []map[string]any{{"id": 1, "name": "抽奖", "slug": "lottery"}},
+		"post_stream": map[string]any{
+			"posts": []map[string]any{
+				{"username": "alice", "raw": "我要参加"},
+				{"username": "lottery_bot", "raw": botRaw},
+			},
+		},
+	}
+}
+
+func newTestDiscourseSource(baseURL string) *DiscourseBotWinnerSource {
+	return &DiscourseBotWinnerSource{
+		BaseURL:            baseURL,
+		ApiKey:             "KEY",
+		ApiUsername:        "system",
+		BotUsername:        "lottery_bot",
+		WinnerSectionRegex: lotteryBotWinnerSectionRegex,
+		TopicID:            42,
+	}
+}
+
+func TestDiscourseBotWinnerSource(t *testing.T) {
+	raw := "开奖啦\n### 以下为中奖佬友及对应楼层：\n- @alice 2楼\n- @bob 5楼\n- @alice 9楼\n"
+	srv := newTopicServer(t, lotteryTopic(true, 7, raw))
+
+	winners, err := newTestDiscourseSource(srv.URL).Winners(context.Background(), &Project{CreatorID: 7}, 3)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !slices.Equal(winners, []string{"alice", "bob", "alice"}) {
+		t.Fatalf("unexpected winners: %v", winners)
+	}
+}
+
+func TestDiscourseBotWinnerSourceRejects(t *testing.T) {
+	raw := "### 以下为中奖佬友及对应楼层：\n- @alice 2楼\n"
+	cases := []struct {
+		name      string
+		topic     map[string]any
+		creatorID uint64
+		itemCount int
+	}{
+		{"not closed", lotteryTopic(false, 7, raw), 7, 1},
+		{"not author", lotteryTopic(true, 8, raw), 7, 1},
+		{"count mismatch", lotteryTopic(true, 7, raw), 7, 2},
+		{"no winner section", lotteryTopic(true, 7, "还没开奖"), 7, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTopicServer(t, tc.topic)
+			if _, err := newTestDiscourseSource(srv.URL).Winners(context.Background(), &Project{CreatorID: tc.creatorID}, tc.itemCount); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestManualWinnerSource(t *testing.T) {
+	source := &ManualWinnerSource{Usernames: []string{"@alice", " bob ", ""}}
+	winners, err := source.Winners(context.Background(), &Project{}, 2)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !slices.Equal(winners, []string{"alice", "bob"}) {
+		t.Fatalf("unexpected winners: %v", winners)
+	}
+	if _, err := source.Winners(context.Background(), &Project{}, 3); err == nil {
+		t.Fatal("count mismatch should fail")
+	}
+}
+
+func TestDrawWinnersDeterministic(t *testing.T) {
+	seed := "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+	a := DrawWinners(seed, []string{"carol", "alice", "bob", "dave", "erin"}, 3)
+	b := DrawWinners(seed, []string{"erin", "dave", "bob", "alice", "carol", "alice"}, 3)
+	if !slices.Equal(a, b) {
+		t.Fatalf("draw must not depend on candidate order: %v != %v", a, b)
+	}
+	if len(a) != 3 || len(slices.Compact(slices.Sorted(slices.Values(a)))) != 3 {
+		t.Fatalf("winners must be distinct: %v", a)
+	}
+	pool := []string{"carol", "alice", "bob", "dave", "erin"}
+	if slices.Equal(DrawWinners(seed, pool, 5), DrawWinners(seed+"x", pool, 5)) {
+		t.Fatal("different seed should yield a different permutation")
+	}
+}
+
+func TestDrawWinnerSource(t *testing.T) {
+	source, err := NewDrawWinnerSource([]string{"alice", "bob"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(DrawSeedHash(source.Seed)) != 64 {
+		t.Fatalf("seed hash should be sha256 hex")
+	}
+	if _, err := source.Winners(context.Background(), &Project{}, 3); err == nil {
+		t.Fatal("not enough candidates should fail")
+	}
+	winners, err := source.Winners(context.Background(), &Project{}, 2)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !slices.Equal(winners, DrawWinners(source.Seed, []string{"bob", "alice"}, 2)) {
+		t.Fatalf("winners should be reproducible from the seed: %v", winners)
+	}
+}
+
+// setupScheduledDraw 准备已截止报名的报名抽奖:2 个奖品,entrants 为报名者
+func setupScheduledDraw(t *testing.T, entrants ...string) (*miniredis.Miniredis, *Project) {
+	t.Helper()
+	mr := dbtest.Setup(t, &oauth.User{}, &Project{}, &ProjectItem{}, &ProjectLotteryEntry{}, &notification.Notification{})
+	tx := db.DB(context.Background())
+	seed, err := GenerateDrawSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimEnd := time.Now().Add(time.Hour)
+	p := &Project{
+		ID: "draw-project", Name: "draw", DistributionType: DistributionTypeLottery, WinnerSource: WinnerSourceScheduledDraw,
+		EndTime: time.Now().Add(-time.Minute), ClaimEndTime: &claimEnd, DrawSeed: seed, DrawSeedHash: DrawSeedHash(seed), TotalItems: 2,
+	}
+	if err := tx.Create(p).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&[]ProjectItem{{ProjectID: p.ID, Content: "prize-1"}, {ProjectID: p.ID, Content: "prize-2"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, username := range entrants {
+		if err := tx.Create(&oauth.User{ID: uint64(i + 1), Username: username}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Create(&ProjectLotteryEntry{ProjectID: p.ID, Username: username}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mr, p
+}
+
+func countNotifications(t *testing.T) int64 {
+	t.Helper()
+	var n int64
+	if err := db.DB(context.Background()).Model(&notification.Notification{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDrawPublishesOnlyAfterCommit(t *testing.T) {
+	mr, p := setupScheduledDraw(t, "alice", "bob", "carol")
+	ctx := context.Background()
+
+	// 事务回滚时既不写入预留也不通知,下次开奖仍可进行
+	rollback := errors.New("rollback")
+	err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := p.Draw(tx, time.Now()); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("got %v, want rollback", err)
+	}
+	if mr.Exists(p.ItemsKey()) || countNotifications(t) != 0 {
+		t.Fatal("rolled back draw leaked reservations or notifications")
+	}
+
+	fresh := &Project{}
+	if err := db.DB(ctx).First(fresh, "id = ?", p.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if fresh.DrawnAt != nil {
+		t.Fatal("drawn_at persisted after rollback")
+	}
+	var result *DrawResult
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = fresh.Draw(tx, time.Now())
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := DrawWinners(fresh.DrawSeed, []string{"alice", "bob", "carol"}, 2); !slices.Equal(result.Winners, want) {
+		t.Fatalf("winners %v, want %v", result.Winners, want)
+	}
+	if mr.Exists(p.ItemsKey()) {
+		t.Fatal("reservations written before publish")
+	}
+	if err := fresh.PublishDraw(ctx, result); err != nil {
+		t.Fatal(err)
+	}
+	for i, winner := range result.Winners {
+		if got := mr.HGet(p.ItemsKey(), winner); got != strconv.FormatUint(result.ItemIDs[i], 10) {
+			t.Errorf("reservation of %s = %q, want %d", winner, got, result.ItemIDs[i])
+		}
+	}
+	if n := countNotifications(t); n != 2 {
+		t.Errorf("got %d notifications, want 2", n)
+	}
+
+	// 重复开奖不会改变结果
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		again, err := fresh.Draw(tx, time.Now())
+		if again != nil {
+			t.Error("second draw should be a no-op")
+		}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrawWithoutEntrants(t *testing.T) {
+	mr, p := setupScheduledDraw(t)
+	ctx := context.Background()
+	var result *DrawResult
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = p.Draw(tx, time.Now())
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Winners) != 0 || !p.IsCompleted {
+		t.Fatalf("winners %v, completed %v", result.Winners, p.IsCompleted)
+	}
+	if err := p.PublishDraw(ctx, result); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(p.ItemsKey()) {
+		t.Error("no reservation expected without winners")
+	}
+}
+
+func TestDrawRejectsSeedMismatch(t *testing.T) {
+	cases := []struct {
+		name string
+		hash string
+	}{
+		{"missing commitment", ""},
+		{"tampered seed", DrawSeedHash("other")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, p := setupScheduledDraw(t, "alice")
+			p.DrawSeedHash = tc.hash
+			_, err := p.Draw(db.DB(context.Background()), time.Now())
+			if err == nil || err.Error() != DrawSeedMismatch {
+				t.Fatalf("got %v, want %q", err, DrawSeedMismatch)
+			}
+		})
+	}
+}
+
+func TestHandleDrawLotteryRestoresReservations(t *testing.T) {
+	mr, p := setupScheduledDraw(t, "alice", "bob", "carol")
+	ctx := context.Background()
+
+	// 开奖已提交但发布失败
+	var result *DrawResult
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = p.Draw(tx, time.Now())
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 其中一名中奖者已通过其他途径领取,不应补写
+	claimed := result.Winners[0]
+	receiverID := uint64(99)
+	if err := db.DB(ctx).Model(&ProjectItem{}).Where("id = ?", result.ItemIDs[0]).Update("receiver_id", receiverID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	payload, _ := json.Marshal(map[string]string{"project_id": p.ID})
+	drawTask := asynq.NewTask(task.DrawLotteryTask, payload)
+	for range 2 {
+		if err := HandleDrawLottery(ctx, drawTask); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mr.HGet(p.ItemsKey(), claimed) != "" {
+		t.Errorf("claimed winner %s restored", claimed)
+	}
+	if mr.HGet(p.ItemsKey(), result.Winners[1]) == "" {
+		t.Errorf("winner %s not restored", result.Winners[1])
+	}
+	if n := countNotifications(t); n != 1 {
+		t.Errorf("got %d notifications, want 1", n)
+	}
+}
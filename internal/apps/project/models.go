@@ -26,19 +26,12 @@ package project
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
-	"regexp"
 	"slices"
 	"strconv"
 	"time"
 
-	"github.com/linux-do/cdk/internal/config"
-
-	"github.com/linux-do/cdk/internal/utils"
-
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/db"
 	"github.com/redis/go-redis/v9"
@@ -48,8 +41,8 @@ import (
 
 type Project struct {
 	ID                string           `json:"id" gorm:"primaryKey;size:64"`
-	Name              string           `json:"name" gorm:"size:32"`
-	Description       string           `json:"description" gorm:"size:1024"`
+	Name              string           `json:"name" gorm:"size:32;index:idx_projects_fulltext,class:FULLTEXT,option:WITH PARSER ngram,priority:1"`
+	Description       string           `json:"description" gorm:"size:1024;index:idx_projects_fulltext,class:FULLTEXT,option:WITH PARSER ngram,priority:2"`
 	DistributionType  DistributionType `json:"distribution_type"`
 	TotalItems        int64            `json:"total_items"`
 	StartTime         time.Time        `json:"start_time"`
@@ -63,6 +56,14 @@ type Project struct {
 	ReportCount       uint8            `json:"report_count" gorm:"default:0"`
 	HideFromExplore   bool             `json:"hide_from_explore" gorm:"default:false"`
 	Price             decimal.Decimal  `json:"price" gorm:"type:decimal(10,2);default:0;not null"`
+	WinnerSource      WinnerSourceType `json:"winner_source" gorm:"default:0"`
+	DrawSeedHash      string           `json:"draw_seed_hash" gorm:"size:64"`
+	DrawSeed          string           `json:"-" gorm:"size:64"`
+	DrawnAt           *time.Time       `json:"drawn_at"`
+	DrawItemCount     int64            `json:"draw_item_count" gorm:"default:0"`
+	ClaimEndTime      *time.Time       `json:"claim_end_time"`
+	ClaimQuota        int64            `json:"claim_quota" gorm:"default:1"`
+	TrustLevelQuotas  []int64          `json:"trust_level_quotas" gorm:"type:varchar(64);serializer:json"`
 	Creator           oauth.User       `json:"-" gorm:"foreignKey:CreatorID"`
 	CreatedAt         time.Time        `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt         time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
@@ -124,180 +125,101 @@ func (p *Project) GetTags(tx *gorm.DB) ([]string, error) {
 	return tags, nil
 }
 
-type TopicResponse struct {
-	UserID     uint64     `json:"user_id"`
-	Tags       []TopicTag `json:"tags"`
-	Closed     bool       `json:"closed"`
-	PostStream struct {
-		Posts []struct {
-			Username string `json:"username"`
-			Raw      string `json:"raw"`
-		} `json:"posts"`
-	} `json:"post_stream"`
-}
-
-type TopicTag struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
-	Slug string `json:"slug"`
-}
-
-var (
-	lotteryBotWinnerSectionRegex = regexp.MustCompile(`### 以下为中奖佬友及对应楼层：\n((?s).+)`)
-	lotteryBotWinnerRegex        = regexp.MustCompile(`@(\S+)`)
-)
-
-func (p *Project) CreateItems(ctx context.Context, tx *gorm.DB, items []string, topicId uint64) error {
+func (p *Project) CreateItems(ctx context.Context, tx *gorm.DB, items []string, source WinnerSource) error {
 	// skip create
 	if len(items) <= 0 {
 		return nil
 	}
 
 	if p.DistributionType == DistributionTypeLottery {
-		headers := map[string]string{
-			"Api-Key":      config.Config.LinuxDo.ApiKey,
-			"Api-Username": config.Config.LinuxDo.ApiUsername,
-		}
-
-		// 获取话题基本信息
-		url := fmt.Sprintf("https://linux.do/t/%d.json?username_filters=lottery_bot&include_raw=true", topicId)
-		topicResp, errRequest := utils.Request(ctx, http.MethodGet, url, nil, headers, nil)
-		if errRequest != nil {
-			return errRequest
-		}
-		defer topicResp.Body.Close()
-
-		if topicResp.StatusCode != http.StatusOK {
-			return fmt.Errorf("获取话题信息失败，状态码: %d", topicResp.StatusCode)
-		}
-
-		var response TopicResponse
-		if errDecode := json.NewDecoder(topicResp.Body).Decode(&response); errDecode != nil {
-			return fmt.Errorf("解析话题信息失败: %w", errDecode)
-		}
-
-		hasLotteryTag := slices.ContainsFunc(response.Tags, func(tag TopicTag) bool {
-			return tag.Slug == "lottery"
-		})
-
-		if !hasLotteryTag {
-			return errors.New("话题未添加抽奖标签，无法创建抽奖项目")
-		}
-
-		if !response.Closed {
-			return errors.New("抽奖还未结束，无法创建抽奖项目")
-		}
-
-		var content string
-		for i := len(response.PostStream.Posts) - 1; i >= 0; i-- {
-			post := response.PostStream.Posts[i]
-			if post.Username == "lottery_bot" {
-				content = post.Raw
-				break
+		// 报名抽奖在开奖时才将奖品分配给中奖者
+		if p.WinnerSource == WinnerSourceScheduledDraw {
+			projectItems, err := newProjectItems(p.ID, items)
+			if err != nil {
+				return err
 			}
+			return tx.CreateInBatches(&projectItems, projectItemInsertBatchSize).Error
 		}
-
-		if content == "" {
-			return errors.New("未找到抽奖机器人的开奖回复")
+		if source == nil {
+			return errors.New(WinnersRequired)
 		}
-
-		// 校验当前创建项目的人，是不是该帖子的真实楼主
-		if response.UserID != p.CreatorID {
-			return errors.New("非话题作者，无法创建抽奖项目")
+		winners, err := source.Winners(ctx, p, len(items))
+		if err != nil {
+			return err
 		}
+		return p.createWinnerItems(ctx, tx, items, winners)
+	}
 
-		winnerSection := lotteryBotWinnerSectionRegex.FindStringSubmatch(content)
-		if len(winnerSection) < 2 {
-			return errors.New("未找到中奖用户部分")
-		}
+	// create items
+	projectItems, err := newProjectItems(p.ID, items)
+	if err != nil {
+		return err
+	}
 
-		// 提取所有中奖用户名
-		winnersMatches := lotteryBotWinnerRegex.FindAllStringSubmatch(winnerSection[1], -1)
-		if len(winnersMatches) == 0 {
-			return errors.New("未找到任何中奖用户")
-		}
+	if err := tx.CreateInBatches(&projectItems, projectItemInsertBatchSize).Error; err != nil {
+		return err
+	}
 
-		if len(winnersMatches) != len(items) {
-			return fmt.Errorf("中奖用户数量(%d)与奖品数量(%d)不符", len(winnersMatches), len(items))
-		}
+	itemIDs := make([]interface{}, len(projectItems))
+	for i, item := range projectItems {
+		itemIDs[i] = item.ID
+	}
+	// push items to redis
+	return db.Redis.RPush(ctx, p.ItemsKey(), itemIDs...).Err()
+}
 
-		// 抽奖中奖者
-		winners := make(map[string][]int)
-		for i, match := range winnersMatches {
-			if len(match) > 1 {
-				winner := match[1]
-				winners[winner] = append(winners[winner], i)
-			}
-		}
+// createWinnerItems 将奖品按顺序分配给中奖者,同一中奖者的多份奖品合并为一个 item
+func (p *Project) createWinnerItems(ctx context.Context, tx *gorm.DB, items []string, winnerList []string) error {
+	// 抽奖中奖者
+	winners := make(map[string][]int)
+	for i, winner := range winnerList {
+		winners[winner] = append(winners[winner], i)
+	}
 
-		type winnerItem struct {
-			username string
-			item     ProjectItem
-		}
+	type winnerItem struct {
+		username string
+		item     ProjectItem
+	}
 
-		winnerItems := make([]winnerItem, 0, len(winners))
+	winnerItems := make([]winnerItem, 0, len(winners))
 
-		// 合并多个奖品内容，并保存对应的用户名
-		for winner, indices := range winners {
-			mergedContent := ""
-			for i, idx := range indices {
-				if i > 0 {
-					mergedContent += "$\n*"
-				}
-				mergedContent += fmt.Sprintf("中奖码%d: %s", i+1, items[idx])
-			}
-			item := ProjectItem{
-				ProjectID: p.ID,
-				Content:   mergedContent,
+	// 合并多个奖品内容，并保存对应的用户名
+	for winner, indices := range winners {
+		mergedContent := ""
+		for i, idx := range indices {
+			if i > 0 {
+				mergedContent += "$\n*"
 			}
-			winnerItems = append(winnerItems, winnerItem{
-				username: winner,
-				item:     item,
-			})
+			mergedContent += fmt.Sprintf("中奖码%d: %s", i+1, items[idx])
 		}
-
-		projectItems := make([]ProjectItem, len(winnerItems))
-		for i, wi := range winnerItems {
-			projectItems[i] = wi.item
-		}
-
-		if err := tx.CreateInBatches(&projectItems, projectItemInsertBatchSize).Error; err != nil {
+		item, err := newProjectItem(p.ID, mergedContent)
+		if err != nil {
 			return err
 		}
+		winnerItems = append(winnerItems, winnerItem{
+			username: winner,
+			item:     item,
+		})
+	}
 
-		itemUserMap := make(map[string]interface{}, len(winners))
-		for i, wi := range winnerItems {
-			itemUserMap[wi.username] = projectItems[i].ID
-		}
-		// push items to redis
-		if err := db.Redis.HSet(ctx, p.ItemsKey(), itemUserMap).Err(); err != nil {
-			return err
-		}
-	} else {
-		// create items
-		projectItems := make([]ProjectItem, len(items))
-
-		for i, content := range items {
-			projectItems[i] = ProjectItem{ProjectID: p.ID, Content: content}
-		}
+	projectItems := make([]ProjectItem, len(winnerItems))
+	for i, wi := range winnerItems {
+		projectItems[i] = wi.item
+	}
 
-		if err := tx.CreateInBatches(&projectItems, projectItemInsertBatchSize).Error; err != nil {
-			return err
-		}
+	if err := tx.CreateInBatches(&projectItems, projectItemInsertBatchSize).Error; err != nil {
+		return err
+	}
 
-		itemIDs := make([]interface{}, len(projectItems))
-		for i, item := range projectItems {
-			itemIDs[i] = item.ID
-		}
-		// push items to redis
-		if err := db.Redis.RPush(ctx, p.ItemsKey(), itemIDs...).Err(); err != nil {
-			return err
-		}
+	itemUserMap := make(map[string]interface{}, len(winners))
+	for i, wi := range winnerItems {
+		itemUserMap[wi.username] = projectItems[i].ID
 	}
-	return nil
+	// push items to redis
+	return db.Redis.HSet(ctx, p.ItemsKey(), itemUserMap).Err()
 }
 
-func (p *Project) CreateItemsWithFilter(ctx context.Context, tx *gorm.DB, items []string, enableFilter bool) error {
+func (p *Project) CreateItemsWithFilter(ctx context.Context, tx *gorm.DB, items []string, enableFilter bool, batches []ReleaseBatch) error {
 	// skip create
 	if len(items) <= 0 {
 		return nil
@@ -305,31 +227,14 @@ func (p *Project) CreateItemsWithFilter(ctx context.Context, tx *gorm.DB, items
 
 	filteredItems := items
 	if enableFilter {
-		// Get existing items for this project
-		var existingItems []string
-		if err := tx.Model(&ProjectItem{}).
-			Where("project_id = ?", p.ID).
-			Pluck("content", &existingItems).Error; err != nil {
+		var err error
+		if filteredItems, err = p.filterExistingItems(tx, items); err != nil {
 			return err
 		}
-
-		// Create a set of existing items for O(1) lookup
-		existingSet := make(map[string]bool)
-		for _, item := range existingItems {
-			existingSet[item] = true
-		}
-
-		// Filter out duplicates
-		filteredItems = make([]string, 0, len(items))
-		for _, item := range items {
-			if !existingSet[item] {
-				filteredItems = append(filteredItems, item)
-			}
-		}
 	}
 
-	// Use the original CreateItems method with filtered items
-	return p.CreateItems(ctx, tx, filteredItems, 0)
+	// Create filtered items following the release schedule
+	return p.CreateItemsWithRelease(ctx, tx, filteredItems, batches)
 }
 
 func (p *Project) GetFilteredItemsCount(ctx context.Context, tx *gorm.DB, items []string, enableFilter bool) (int64, error) {
@@ -342,38 +247,80 @@ func (p *Project) GetFilteredItemsCount(ctx context.Context, tx *gorm.DB, items
 		return int64(len(items)), nil
 	}
 
-	// Get existing items for this project
-	var existingItems []string
-	if err := tx.Model(&ProjectItem{}).
-		Where("project_id = ?", p.ID).
-		Pluck("content", &existingItems).Error; err != nil {
+	filteredItems, err := p.filterExistingItems(tx, items)
+	if err != nil {
 		return 0, err
 	}
+	return int64(len(filteredItems)), nil
+}
 
-	// Create a set of existing items for O(1) lookup
-	existingSet := make(map[string]bool)
-	for _, item := range existingItems {
-		existingSet[item] = true
+// filterExistingItems 通过内容哈希索引过滤掉项目中已存在的 item
+func (p *Project) filterExistingItems(tx *gorm.DB, items []string) ([]string, error) {
+	existing, err := p.ExistingContents(tx, items)
+	if err != nil {
+		return nil, err
 	}
 
-	// Count unique items
-	uniqueCount := int64(0)
-	for _, item := range items {
-		if !existingSet[item] {
-			uniqueCount++
+	filteredItems := make([]string, 0, len(items))
+	for i, item := range items {
+		if !existing[i] {
+			filteredItems = append(filteredItems, item)
 		}
 	}
+	return filteredItems, nil
+}
+
+// ExistingContents 返回各内容是否已存在于项目中,同时匹配各密钥版本的去重索引
+func (p *Project) ExistingContents(tx *gorm.DB, contents []string) ([]bool, error) {
+	contentHashes := make([][]string, len(contents))
+	hashes := make([]string, 0, len(contents))
+	for i, content := range contents {
+		contentHashes[i] = itemContentHashes(content)
+		hashes = append(hashes, contentHashes[i]...)
+	}
+	existingSet, err := p.ExistingContentHashes(tx, hashes)
+	if err != nil {
+		return nil, err
+	}
 
-	return uniqueCount, nil
+	existing := make([]bool, len(contents))
+	for i := range contents {
+		existing[i] = slices.ContainsFunc(contentHashes[i], func(hash string) bool { return existingSet[hash] })
+	}
+	return existing, nil
+}
+
+// ExistingContentHashes 分批查询项目中已存在的内容哈希
+func (p *Project) ExistingContentHashes(tx *gorm.DB, hashes []string) (map[string]bool, error) {
+	existingSet := make(map[string]bool)
+	for start := 0; start < len(hashes); start += projectItemInsertBatchSize {
+		end := min(start+projectItemInsertBatchSize, len(hashes))
+		var existing []string
+		if err := tx.Model(&ProjectItem{}).
+			Where("project_id = ? AND content_hash IN ?", p.ID, hashes[start:end]).
+			Pluck("content_hash", &existing).Error; err != nil {
+			return nil, err
+		}
+		for _, hash := range existing {
+			existingSet[hash] = true
+		}
+	}
+	return existingSet, nil
 }
 
 func (p *Project) PrepareReceive(ctx context.Context, userName string) (uint64, error) {
 	var val string
 	var err error
 
-	if p.DistributionType == DistributionTypeLottery {
+	if p.isPerUserDistribution() {
 		val, err = db.Redis.HGet(ctx, p.ItemsKey(), userName).Result()
 	} else {
+		// 等候补货的用户优先取走为其预留的 item
+		if itemID, taken, errTake := p.takeReservation(ctx, userName); errTake != nil {
+			return 0, errTake
+		} else if taken {
+			return itemID, nil
+		}
 		val, err = db.Redis.LPop(ctx, p.ItemsKey()).Result()
 	}
 
@@ -385,23 +332,36 @@ func (p *Project) PrepareReceive(ctx context.Context, userName string) (uint64,
 	return strconv.ParseUint(val, 10, 64)
 }
 
+// ReturnReservation 归还 PrepareReceive 预占的 item。
+// 抽奖与邀请模式的 HGet 不移除哈希项,HSet 写回是幂等的,也能恢复发放中途已被 HDel 的用户;
+// 其余模式 RPush 回队列。
+func (p *Project) ReturnReservation(ctx context.Context, userName string, itemID uint64) error {
+	if p.isPerUserDistribution() {
+		return db.Redis.HSet(ctx, p.ItemsKey(), userName, itemID).Err()
+	}
+	return db.Redis.RPush(ctx, p.ItemsKey(), itemID).Err()
+}
+
 func (p *Project) SameIPCacheKey(ip string) string {
 	return fmt.Sprintf("project:%s:receive:ip:%s", p.ID, ip)
 }
 
-func (p *Project) CheckSameIPReceived(ctx context.Context, ip string) (bool, error) {
+// CheckSameIPReceived 检查是否已有其他用户使用相同 IP 领取,同一用户在领取上限内可重复领取
+func (p *Project) CheckSameIPReceived(ctx context.Context, ip string, userID uint64) (bool, error) {
 	if p.AllowSameIP {
 		return false, nil
 	}
-	if count, err := db.Redis.Exists(ctx, p.SameIPCacheKey(ip)).Result(); err != nil {
+	receiverID, err := db.Redis.Get(ctx, p.SameIPCacheKey(ip)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	} else if err != nil {
 		return false, err
-	} else {
-		return count > 0, nil
 	}
+	return receiverID != strconv.FormatUint(userID, 10), nil
 }
 
 func (p *Project) Stock(ctx context.Context) (int64, error) {
-	if p.DistributionType == DistributionTypeLottery {
+	if p.isPerUserDistribution() {
 		return db.Redis.HLen(ctx, p.ItemsKey()).Result()
 	}
 	return db.Redis.LLen(ctx, p.ItemsKey()).Result()
@@ -425,28 +385,58 @@ func (p *Project) ValidateRequirement(user *oauth.User) error {
 	return nil
 }
 
+// ReceiveWindow 返回可领取的时间窗口。
+// 报名抽奖在 EndTime 截止报名并开奖,中奖者可在 EndTime 至 ClaimEndTime 之间领取。
+func (p *Project) ReceiveWindow() (time.Time, time.Time) {
+	if p.WinnerSource == WinnerSourceScheduledDraw && p.ClaimEndTime != nil {
+		return p.EndTime, *p.ClaimEndTime
+	}
+	return p.StartTime, p.EndTime
+}
+
 func (p *Project) IsReceivable(ctx context.Context, now time.Time, user *oauth.User, ip string) error {
 	// check time
-	if now.Before(p.StartTime) {
+	startTime, endTime := p.ReceiveWindow()
+	if now.Before(startTime) {
 		return errors.New(TimeTooEarly)
-	} else if p.EndTime.Before(now) {
+	} else if endTime.Before(now) {
 		return errors.New(TimeTooLate)
 	}
+	// check draw
+	if p.WinnerSource == WinnerSourceScheduledDraw && p.DrawnAt == nil {
+		return errors.New(LotteryNotDrawn)
+	}
 	// check requirements
 	if err := p.ValidateRequirement(user); err != nil {
 		return err
 	}
 	// check same ip
-	if sameIPReceived, err := p.CheckSameIPReceived(ctx, ip); err != nil {
+	if sameIPReceived, err := p.CheckSameIPReceived(ctx, ip, user.ID); err != nil {
 		return err
 	} else if sameIPReceived {
 		return errors.New(SameIPReceived)
 	}
+	// check quota
+	if err := p.CheckClaimQuota(ctx, user); err != nil {
+		return err
+	}
+	// check invitation
+	if p.DistributionType == DistributionTypeInvite {
+		if invited, err := p.IsInvitee(db.DB(ctx), user.Username); err != nil {
+			return err
+		} else if !invited {
+			return errors.New(NotInvited)
+		}
+	}
 	// check stock
 	if hasStock, err := p.HasStock(ctx); err != nil {
 		return err
 	} else if !hasStock {
-		return errors.New(NoStock)
+		if reserved, errReserved := p.HasReservation(ctx, user.Username); errReserved != nil {
+			return errReserved
+		} else if !reserved {
+			return errors.New(NoStock)
+		}
 	}
 	return nil
 }
@@ -459,15 +449,21 @@ type ProjectTag struct {
 }
 
 type ProjectItem struct {
-	ID         uint64      `json:"id" gorm:"primaryKey,autoIncrement"`
-	ProjectID  string      `json:"project_id" gorm:"size:64;index;uniqueIndex:idx_project_receiver"`
-	Project    Project     `json:"-" gorm:"foreignKey:ProjectID"`
-	ReceiverID *uint64     `json:"receiver_id" gorm:"index;uniqueIndex:idx_project_receiver"`
-	Receiver   *oauth.User `json:"-" gorm:"foreignKey:ReceiverID"`
-	Content    string      `json:"content" gorm:"size:1024"`
-	CreatedAt  time.Time   `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt  time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
-	ReceivedAt *time.Time  `json:"received_at" gorm:"index"`
+	ID                uint64      `json:"id" gorm:"primaryKey,autoIncrement"`
+	ProjectID         string      `json:"project_id" gorm:"size:64;index;index:idx_project_item_receiver;index:idx_project_item_content_hash,priority:1"`
+	Project           Project     `json:"-" gorm:"foreignKey:ProjectID"`
+	ReceiverID        *uint64     `json:"receiver_id" gorm:"index;index:idx_project_item_receiver"`
+	Receiver          *oauth.User `json:"-" gorm:"foreignKey:ReceiverID"`
+	Content           string      `json:"content" gorm:"type:text"`
+	Encrypted         bool        `json:"-" gorm:"default:false"`
+	KeyVersion        int8        `json:"-" gorm:"default:0"`
+	ContentHash       string      `json:"-" gorm:"size:64;index:idx_project_item_content_hash,priority:2"`
+	ContentPrefixHash string      `json:"-" gorm:"size:64;index"`
+	ReleaseID         *uint64     `json:"-" gorm:"index"`
+	RevokedAt         *time.Time  `json:"revoked_at" gorm:"index"`
+	CreatedAt         time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+	ReceivedAt        *time.Time  `json:"received_at" gorm:"index"`
 }
 
 func (p *ProjectItem) Exact(tx *gorm.DB, id uint64) error {
@@ -478,9 +474,13 @@ func (p *ProjectItem) Exact(tx *gorm.DB, id uint64) error {
 }
 
 // FulfillForReceiver 执行领取结算事务:将 item 标记为已领取、库存耗尽则标记项目完成、
-// 若不允许同 IP 领取则写 Redis SetNX 锁、抽奖模式从 Redis HDel 用户。
+// 若不允许同 IP 领取则写 Redis SetNX 锁、抽奖与邀请模式从 Redis HDel 用户。
 // 由免费领取与付费回调两条路径共用;失败时上游需决定是否回退 itemID。
 func (p *Project) FulfillForReceiver(ctx context.Context, tx *gorm.DB, item *ProjectItem, receiverID uint64, clientIP string) error {
+	if err := p.lockAndCheckClaimQuota(tx, receiverID); err != nil {
+		return err
+	}
+
 	now := time.Now()
 	item.ReceiverID = &receiverID
 	item.ReceivedAt = &now
@@ -491,20 +491,28 @@ func (p *Project) FulfillForReceiver(ctx context.Context, tx *gorm.DB, item *Pro
 	if hasStock, err := p.HasStock(ctx); err != nil {
 		return err
 	} else if !hasStock {
-		p.IsCompleted = true
-		if err := tx.Save(p).Error; err != nil {
+		// 仍有待发放批次时项目未完成
+		if pending, err := p.HasPendingRelease(tx); err != nil {
 			return err
+		} else if !pending {
+			p.IsCompleted = true
+			if err := tx.Save(p).Error; err != nil {
+				return err
+			}
+			invalidateExploreCompletion(ctx, p)
+			p.notifySoldOut(ctx, tx)
 		}
 	}
 
 	if !p.AllowSameIP && clientIP != "" {
-		if err := db.Redis.SetNX(ctx, p.SameIPCacheKey(clientIP), clientIP, p.EndTime.Sub(now)).Err(); err != nil {
+		_, endTime := p.ReceiveWindow()
+		if err := db.Redis.SetNX(ctx, p.SameIPCacheKey(clientIP), receiverID, endTime.Sub(now)).Err(); err != nil {
 			return err
 		}
 	}
 
-	if p.DistributionType == DistributionTypeLottery {
-		// 付费领取限定 OneForEach,此处保留仅为免费 Lottery 路径的兼容
+	if p.isPerUserDistribution() {
+		// 付费订单退款或超时时由 ReturnReservation 写回
 		var user oauth.User
 		if err := user.Exact(tx, receiverID); err != nil {
 			return err
@@ -514,19 +522,20 @@ func (p *Project) FulfillForReceiver(ctx context.Context, tx *gorm.DB, item *Pro
 		}
 	}
 
+	invalidateReceivedCache(ctx, receiverID)
 	return nil
 }
 
-func (p *Project) GetReceivedItem(ctx context.Context, userID uint64) (*ProjectItem, error) {
-	item := &ProjectItem{}
-	err := db.DB(ctx).Where("project_id = ? AND receiver_id = ?", p.ID, userID).First(item).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, nil
-	}
-	if err != nil {
+// GetReceivedItems 按领取时间返回用户在项目中领取的全部 item
+func (p *Project) GetReceivedItems(ctx context.Context, userID uint64) ([]ProjectItem, error) {
+	var items []ProjectItem
+	if err := db.DB(ctx).
+		Where("project_id = ? AND receiver_id = ? AND revoked_at IS NULL", p.ID, userID).
+		Order("received_at ASC, id ASC").
+		Find(&items).Error; err != nil {
 		return nil, err
 	}
-	return item, nil
+	return items, nil
 }
 
 // ResetCompletedStatusIfHasStock 在调用方归还库存时重置项目完成状态。
@@ -545,9 +554,16 @@ func (p *Project) ResetCompletedStatusIfHasStock(ctx context.Context, tx *gorm.D
 		return nil
 	}
 
-	return tx.Model(&Project{}).
+	result := tx.Model(&Project{}).
 		Where("id = ? AND is_completed = ?", p.ID, true).
-		Update("is_completed", false).Error
+		Update("is_completed", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		invalidateExploreCompletion(ctx, p)
+	}
+	return nil
 }
 
 type ProjectReport struct {
@@ -0,0 +1,128 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/notification"
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"gorm.io/gorm"
+)
+
+// reviewStatusText 审核结果在通知中的描述
+var reviewStatusText = map[ProjectStatus]string{
+	ProjectStatusNormal:    "已恢复正常展示",
+	ProjectStatusHidden:    "已被隐藏",
+	ProjectStatusViolation: "被判定为违规",
+}
+
+// NotifyReviewed 通知创建者项目状态变化,reason 为空时不附带原因
+func (p *Project) NotifyReviewed(ctx context.Context, tx *gorm.DB, status ProjectStatus, reason string) {
+	content := fmt.Sprintf("你创建的项目「%s」%s。", notification.EscapeMarkdown(p.Name), reviewStatusText[status])
+	if reason != "" {
+		content += "\n\n- 原因:" + notification.EscapeMarkdown(reason)
+	}
+	notification.Send(ctx, tx, &notification.Notification{
+		UserID:    p.CreatorID,
+		Type:      notification.NotificationTypeProjectReviewed,
+		Title:     fmt.Sprintf("CDK 项目「%s」%s", p.Name, reviewStatusText[status]),
+		Content:   content,
+		ProjectID: p.ID,
+	})
+}
+
+// notifySoldOut 通知创建者项目已领完
+func (p *Project) notifySoldOut(ctx context.Context, tx *gorm.DB) {
+	notification.Send(ctx, tx, &notification.Notification{
+		UserID:    p.CreatorID,
+		Type:      notification.NotificationTypeProjectSoldOut,
+		Title:     fmt.Sprintf("CDK 项目「%s」已领完", p.Name),
+		Content:   fmt.Sprintf("你创建的项目「%s」的 %d 个物品已全部领取。", notification.EscapeMarkdown(p.Name), p.TotalItems),
+		ProjectID: p.ID,
+	})
+}
+
+// notifyLotteryWinners 通知中奖者在领取截止前领取
+func (p *Project) notifyLotteryWinners(ctx context.Context, tx *gorm.DB, winners []string) error {
+	var winnerIDs []uint64
+	if err := tx.Model(&oauth.User{}).Where("username IN ?", winners).Pluck("id", &winnerIDs).Error; err != nil {
+		return err
+	}
+	_, claimEndTime := p.ReceiveWindow()
+	notification.SendToUsers(ctx, tx, winnerIDs, notification.Notification{
+		Type:      notification.NotificationTypeLotteryWon,
+		Title:     fmt.Sprintf("CDK 项目「%s」中奖通知", p.Name),
+		Content:   fmt.Sprintf("你在项目「%s」的报名抽奖中中奖,请在 %s 前领取。", notification.EscapeMarkdown(p.Name), claimEndTime.Format(time.DateTime)),
+		ProjectID: p.ID,
+	})
+	return nil
+}
+
+// favoriteStartedRow 待通知的收藏记录
+type favoriteStartedRow struct {
+	FavoriteID uint64
+	UserID     uint64
+	ProjectID  string
+	Name       string
+}
+
+// notifyFavoritesStarted 通知收藏者项目已开始,并标记收藏记录,返回本次通知的数量
+func notifyFavoritesStarted(ctx context.Context, tx *gorm.DB, now time.Time, limit int) (int, error) {
+	var rows []favoriteStartedRow
+	if err := tx.Raw(`SELECT f.id AS favorite_id, f.user_id, p.id AS project_id, p.name
+			FROM project_favorites f
+			JOIN projects p ON p.id = f.project_id
+			WHERE f.start_notified = false AND p.start_time <= ? AND p.end_time > ? AND p.status = ?
+			ORDER BY f.id ASC LIMIT ?`,
+		now, now, ProjectStatusNormal, limit).Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	favoriteIDs := make([]uint64, 0, len(rows))
+	notifications := make([]*notification.Notification, 0, len(rows))
+	for _, row := range rows {
+		favoriteIDs = append(favoriteIDs, row.FavoriteID)
+		notifications = append(notifications, &notification.Notification{
+			UserID:    row.UserID,
+			Type:      notification.NotificationTypeFavoriteStarted,
+			Title:     fmt.Sprintf("CDK 收藏的项目「%s」已开始", row.Name),
+			Content:   fmt.Sprintf("你收藏的项目「%s」已开始,快去领取吧。", notification.EscapeMarkdown(row.Name)),
+			ProjectID: row.ProjectID,
+		})
+	}
+	if err := tx.Model(&ProjectFavorite{}).
+		Where("id IN ?", favoriteIDs).
+		Update("start_notified", true).Error; err != nil {
+		return 0, err
+	}
+	notification.Send(ctx, tx, notifications...)
+	return len(rows), nil
+}
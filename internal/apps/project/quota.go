@@ -0,0 +1,135 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultClaimQuota 未设置领取上限时每个用户可领取的数量
+const defaultClaimQuota = 1
+
+// ProjectQuotaRequest 每用户领取上限。
+// TrustLevelQuotas 按信任等级 0-4 依次设置上限,提供时优先于 ClaimQuota。
+// 更新时未携带的字段保留项目原值。
+type ProjectQuotaRequest struct {
+	ClaimQuota       *int64  `json:"claim_quota" binding:"omitempty,min=1,max=100"`
+	TrustLevelQuotas []int64 `json:"trust_level_quotas" binding:"omitempty,len=5,dive,min=1,max=100"`
+}
+
+// Validate 仅一码一用的队列库存支持每用户领取多个
+func (r *ProjectQuotaRequest) Validate(distributionType DistributionType) error {
+	if distributionType == DistributionTypeOneForEach {
+		return nil
+	}
+	if r.ClaimQuota != nil && *r.ClaimQuota > defaultClaimQuota {
+		return errors.New(QuotaOnlyOneForEach)
+	}
+	for _, quota := range r.TrustLevelQuotas {
+		if quota > defaultClaimQuota {
+			return errors.New(QuotaOnlyOneForEach)
+		}
+	}
+	return nil
+}
+
+// ApplyTo 将领取上限写入项目,请求未携带的字段保留项目原值
+func (r *ProjectQuotaRequest) ApplyTo(p *Project) {
+	if r.ClaimQuota != nil {
+		p.ClaimQuota = *r.ClaimQuota
+	}
+	if p.ClaimQuota <= 0 {
+		p.ClaimQuota = defaultClaimQuota
+	}
+	if r.TrustLevelQuotas != nil {
+		p.TrustLevelQuotas = r.TrustLevelQuotas
+	}
+}
+
+// ClaimQuotaFor 返回指定信任等级用户的领取上限
+func (p *Project) ClaimQuotaFor(level oauth.TrustLevel) int64 {
+	if int(level) >= 0 && int(level) < len(p.TrustLevelQuotas) && p.TrustLevelQuotas[level] > 0 {
+		return p.TrustLevelQuotas[level]
+	}
+	if p.ClaimQuota > 0 {
+		return p.ClaimQuota
+	}
+	return defaultClaimQuota
+}
+
+// claimQuotaSql 项目 p 对指定信任等级的领取上限,取值规则与 ClaimQuotaFor 一致(1 即 defaultClaimQuota),
+// 参数为 trustLevelQuotaPath 返回的 JSON 路径
+const claimQuotaSql = `COALESCE(NULLIF(CAST(JSON_EXTRACT(p.trust_level_quotas, ?) AS UNSIGNED), 0), NULLIF(p.claim_quota, 0), 1)`
+
+// trustLevelQuotaPath 信任等级在 TrustLevelQuotas 中对应的 JSON 路径
+func trustLevelQuotaPath(level oauth.TrustLevel) string {
+	return fmt.Sprintf("$[%d]", level)
+}
+
+// CountReceivedItems 统计用户在项目中已领取的数量
+func (p *Project) CountReceivedItems(tx *gorm.DB, userID uint64) (int64, error) {
+	var count int64
+	if err := tx.Model(&ProjectItem{}).
+		Where("project_id = ? AND receiver_id = ? AND revoked_at IS NULL", p.ID, userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CheckClaimQuota 校验用户是否仍有剩余领取次数
+func (p *Project) CheckClaimQuota(ctx context.Context, user *oauth.User) error {
+	return p.checkClaimQuota(db.DB(ctx), user.ID, user.TrustLevel)
+}
+
+func (p *Project) checkClaimQuota(tx *gorm.DB, userID uint64, level oauth.TrustLevel) error {
+	count, err := p.CountReceivedItems(tx, userID)
+	if err != nil {
+		return err
+	}
+	if quota := p.ClaimQuotaFor(level); count >= quota {
+		return fmt.Errorf(ClaimQuotaExceeded, quota)
+	}
+	return nil
+}
+
+// lockAndCheckClaimQuota 锁定领取用户行以串行化同一用户的并发领取,再校验领取上限
+func (p *Project) lockAndCheckClaimQuota(tx *gorm.DB, receiverID uint64) error {
+	var receiver oauth.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "trust_level").
+		Where("id = ?", receiverID).
+		First(&receiver).Error; err != nil {
+		return err
+	}
+	return p.checkClaimQuota(tx, receiverID, receiver.TrustLevel)
+}
@@ -0,0 +1,63 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestProjectQuotaRequestApplyToKeepsOmittedFields(t *testing.T) {
+	p := &Project{ClaimQuota: 3, TrustLevelQuotas: []int64{1, 1, 2, 3, 5}}
+
+	var req ProjectQuotaRequest
+	if err := json.Unmarshal([]byte(`{}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	req.ApplyTo(p)
+	if p.ClaimQuota != 3 {
+		t.Fatalf("claim quota reset to %d, want 3", p.ClaimQuota)
+	}
+	if !slices.Equal(p.TrustLevelQuotas, []int64{1, 1, 2, 3, 5}) {
+		t.Fatalf("trust level quotas changed: %v", p.TrustLevelQuotas)
+	}
+
+	if err := json.Unmarshal([]byte(`{"claim_quota":2,"trust_level_quotas":[]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	req.ApplyTo(p)
+	if p.ClaimQuota != 2 || len(p.TrustLevelQuotas) != 0 {
+		t.Fatalf("got quota=%d levels=%v, want 2 and cleared", p.ClaimQuota, p.TrustLevelQuotas)
+	}
+}
+
+func TestProjectQuotaRequestApplyToDefault(t *testing.T) {
+	p := &Project{}
+	(&ProjectQuotaRequest{}).ApplyTo(p)
+	if p.ClaimQuota != defaultClaimQuota {
+		t.Fatalf("got %d, want default %d", p.ClaimQuota, defaultClaimQuota)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"gorm.io/gorm"
+)
+
+// ProjectRelease 分批发放批次,批次内的 item 在 ReleaseAt 到达前不进入 Redis 库存
+type ProjectRelease struct {
+	ID         uint64     `json:"id" gorm:"primaryKey,autoIncrement"`
+	ProjectID  string     `json:"project_id" gorm:"size:64;index"`
+	ReleaseAt  time.Time  `json:"release_at" gorm:"index:idx_release_pending,priority:2"`
+	ItemCount  int64      `json:"item_count"`
+	ReleasedAt *time.Time `json:"released_at" gorm:"index:idx_release_pending,priority:1"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ReleaseBatch 单个发放批次
+type ReleaseBatch struct {
+	ReleaseAt time.Time `json:"release_at" binding:"required"`
+	ItemCount int64     `json:"item_count" binding:"required,min=1"`
+}
+
+// ProjectReleaseRequest 分批发放计划。
+// 物品按顺序分配:未被批次覆盖的前部物品立即发放,其余按 ReleaseAt 先后依次划入各批次。
+type ProjectReleaseRequest struct {
+	ReleaseSchedule []ReleaseBatch `json:"release_schedule" binding:"omitempty,max=100,dive"`
+}
+
+// Validate 校验分批发放计划,itemCount 为本次提交的物品数量
+func (r *ProjectReleaseRequest) Validate(distributionType DistributionType, endTime time.Time, itemCount int) error {
+	if len(r.ReleaseSchedule) == 0 {
+		return nil
+	}
+	if distributionType != DistributionTypeOneForEach {
+		return errors.New(ReleaseOnlyOneForEach)
+	}
+	now := time.Now()
+	for _, batch := range r.ReleaseSchedule {
+		if !batch.ReleaseAt.After(now) || !batch.ReleaseAt.Before(endTime) {
+			return errors.New(ReleaseTimeInvalid)
+		}
+	}
+	return validateReleaseItemCount(r.ReleaseSchedule, itemCount)
+}
+
+func validateReleaseItemCount(batches []ReleaseBatch, itemCount int) error {
+	var total int64
+	for _, batch := range batches {
+		total += batch.ItemCount
+	}
+	if total > int64(itemCount) {
+		return fmt.Errorf(ReleaseItemsExceeded, total, itemCount)
+	}
+	return nil
+}
+
+// CreateItemsWithRelease 按分批发放计划创建物品,仅立即发放的部分推入 Redis
+func (p *Project) CreateItemsWithRelease(ctx context.Context, tx *gorm.DB, items []string, batches []ReleaseBatch) error {
+	if len(batches) == 0 {
+		return p.CreateItems(ctx, tx, items, nil)
+	}
+	// 过滤重复物品后数量可能变少,需再次校验
+	if err := validateReleaseItemCount(batches, len(items)); err != nil {
+		return err
+	}
+
+	batches = slices.Clone(batches)
+	slices.SortStableFunc(batches, func(a, b ReleaseBatch) int {
+		return a.ReleaseAt.Compare(b.ReleaseAt)
+	})
+
+	offset := len(items)
+	for _, batch := range batches {
+		offset -= int(batch.ItemCount)
+	}
+	if err := p.CreateItems(ctx, tx, items[:offset], nil); err != nil {
+		return err
+	}
+
+	for _, batch := range batches {
+		release := ProjectRelease{
+			ProjectID: p.ID,
+			ReleaseAt: batch.ReleaseAt,
+			ItemCount: batch.ItemCount,
+		}
+		if err := tx.Create(&release).Error; err != nil {
+			return err
+		}
+		batchItems := items[offset : offset+int(batch.ItemCount)]
+		projectItems, err := newProjectItems(p.ID, batchItems)
+		if err != nil {
+			return err
+		}
+		for i := range projectItems {
+			projectItems[i].ReleaseID = &release.ID
+		}
+		if err := tx.CreateInBatches(&projectItems, projectItemInsertBatchSize).Error; err != nil {
+			return err
+		}
+		offset += int(batch.ItemCount)
+	}
+	return nil
+}
+
+// GetReleases 按发放时间返回项目的全部发放批次
+func (p *Project) GetReleases(tx *gorm.DB) ([]ProjectRelease, error) {
+	var releases []ProjectRelease
+	if err := tx.Where("project_id = ?", p.ID).Order("release_at ASC").Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// HasPendingRelease 是否存在尚未发放的批次
+func (p *Project) HasPendingRelease(tx *gorm.DB) (bool, error) {
+	var count int64
+	if err := tx.Model(&ProjectRelease{}).
+		Where("project_id = ? AND released_at IS NULL", p.ID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Release 将批次内未领取的 item 推入 Redis 库存,released_at 作为 CAS 保证只发放一次
+func (r *ProjectRelease) Release(ctx context.Context) error {
+	var p Project
+	if err := db.DB(ctx).Where("id = ?", r.ProjectID).First(&p).Error; err != nil {
+		return err
+	}
+
+	released := false
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&ProjectRelease{}).
+			Where("id = ? AND released_at IS NULL", r.ID).
+			Update("released_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		var itemIDs []uint64
+		if err := tx.Model(&ProjectItem{}).
+			Where("release_id = ? AND receiver_id IS NULL", r.ID).
+			Pluck("id", &itemIDs).Error; err != nil {
+			return err
+		}
+		if len(itemIDs) > 0 {
+			values := make([]interface{}, len(itemIDs))
+			for i, id := range itemIDs {
+				values[i] = id
+			}
+			if err := db.Redis.RPush(ctx, p.ItemsKey(), values...).Err(); err != nil {
+				return err
+			}
+		}
+		released = true
+		return p.ResetCompletedStatusIfHasStock(ctx, tx)
+	}); err != nil {
+		return err
+	}
+
+	if !released {
+		return nil
+	}
+	logger.InfoF(ctx, "项目[%s]发放批次[%d],共 %d 个", p.ID, r.ID, r.ItemCount)
+	return p.RefillFromWaitlist(ctx)
+}
@@ -0,0 +1,180 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"gorm.io/gorm"
+)
+
+func TestProjectReleaseRequestValidate(t *testing.T) {
+	now := time.Now()
+	endTime := now.Add(24 * time.Hour)
+	batch := func(after time.Duration, count int64) ReleaseBatch {
+		return ReleaseBatch{ReleaseAt: now.Add(after), ItemCount: count}
+	}
+
+	cases := []struct {
+		name             string
+		distributionType DistributionType
+		schedule         []ReleaseBatch
+		itemCount        int
+		err              string
+	}{
+		{"no schedule", DistributionTypeLottery, nil, 0, ""},
+		{"valid schedule", DistributionTypeOneForEach, []ReleaseBatch{batch(time.Hour, 2), batch(2*time.Hour, 3)}, 5, ""},
+		{"only one for each", DistributionTypeInvite, []ReleaseBatch{batch(time.Hour, 1)}, 1, ReleaseOnlyOneForEach},
+		{"release in the past", DistributionTypeOneForEach, []ReleaseBatch{batch(-time.Minute, 1)}, 1, ReleaseTimeInvalid},
+		{"release after end time", DistributionTypeOneForEach, []ReleaseBatch{batch(48*time.Hour, 1)}, 1, ReleaseTimeInvalid},
+		{"more items than submitted", DistributionTypeOneForEach, []ReleaseBatch{batch(time.Hour, 2), batch(2*time.Hour, 2)}, 3, fmt.Sprintf(ReleaseItemsExceeded, 4, 3)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := ProjectReleaseRequest{ReleaseSchedule: tc.schedule}
+			err := req.Validate(tc.distributionType, endTime, tc.itemCount)
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err != "" && (err == nil || err.Error() != tc.err) {
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+// setupRelease 创建一码一用项目,5 个物品中 2 个立即发放,其余按计划分两批发放
+func setupRelease(t *testing.T) (*miniredis.Miniredis, *Project, []ProjectRelease) {
+	t.Helper()
+	mr := dbtest.Setup(t, &Project{}, &ProjectItem{}, &ProjectRelease{})
+	ctx := context.Background()
+	now := time.Now()
+	p := &Project{ID: "release-project", Name: "release", DistributionType: DistributionTypeOneForEach, EndTime: now.Add(24 * time.Hour)}
+	// 批次故意乱序提交,物品应按发放时间先后划分
+	batches := []ReleaseBatch{
+		{ReleaseAt: now.Add(2 * time.Hour), ItemCount: 1},
+		{ReleaseAt: now.Add(time.Hour), ItemCount: 2},
+	}
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		return p.CreateItemsWithRelease(ctx, tx, []string{"a", "b", "c", "d", "e"}, batches)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	releases, err := p.GetReleases(db.DB(ctx))
+	if err != nil || len(releases) != 2 {
+		t.Fatalf("releases = %v (%v)", releases, err)
+	}
+	return mr, p, releases
+}
+
+// stockIDs 返回 Redis 库存中的 item ID
+func stockIDs(t *testing.T, mr *miniredis.Miniredis, p *Project) []uint64 {
+	t.Helper()
+	values, _ := mr.List(p.ItemsKey())
+	ids := make([]uint64, len(values))
+	for i, v := range values {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+func TestCreateItemsWithRelease(t *testing.T) {
+	mr, p, releases := setupRelease(t)
+	ctx := context.Background()
+
+	if got := stockIDs(t, mr, p); !slices.Equal(got, []uint64{1, 2}) {
+		t.Errorf("immediate stock = %v, want [1 2]", got)
+	}
+	if pending, err := p.HasPendingRelease(db.DB(ctx)); err != nil || !pending {
+		t.Errorf("pending release = %v (%v)", pending, err)
+	}
+
+	var items []ProjectItem
+	if err := db.DB(ctx).Order("id").Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []*uint64{nil, nil, &releases[0].ID, &releases[0].ID, &releases[1].ID}
+	for i, item := range items {
+		if (item.ReleaseID == nil) != (want[i] == nil) || (item.ReleaseID != nil && *item.ReleaseID != *want[i]) {
+			t.Errorf("item %d release = %v, want %v", item.ID, item.ReleaseID, want[i])
+		}
+	}
+}
+
+func TestHandleReleaseDueItems(t *testing.T) {
+	mr, p, releases := setupRelease(t)
+	ctx := context.Background()
+
+	// 首批到期;其中一个 item 已在发放前被领取,不应再入库存
+	if err := db.DB(ctx).Model(&ProjectRelease{}).Where("id = ?", releases[0].ID).
+		Update("release_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB(ctx).Model(&ProjectItem{}).Where("id = ?", 3).Update("receiver_id", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB(ctx).Model(p).Update("is_completed", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := HandleReleaseDueItems(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := stockIDs(t, mr, p); !slices.Equal(got, []uint64{1, 2, 4}) {
+		t.Errorf("stock = %v, want [1 2 4]", got)
+	}
+
+	stored, err := p.GetReleases(db.DB(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored[0].ReleasedAt == nil || stored[1].ReleasedAt != nil {
+		t.Errorf("released_at = %v, %v; only the due batch should be released", stored[0].ReleasedAt, stored[1].ReleasedAt)
+	}
+	var project Project
+	if err := db.DB(ctx).First(&project, "id = ?", p.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if project.IsCompleted {
+		t.Error("release with stock should reopen a completed project")
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type RevokeAction string
+
+const (
+	// RevokeActionReplace 为领取者发放新的 item
+	RevokeActionReplace RevokeAction = "replace"
+	// RevokeActionRelease 释放领取者的领取资格,使其可以重新领取
+	RevokeActionRelease RevokeAction = "release"
+)
+
+// ProjectItemRevocation 作废 item 的审计记录
+type ProjectItemRevocation struct {
+	ID                uint64       `json:"id" gorm:"primaryKey,autoIncrement"`
+	ProjectID         string       `json:"project_id" gorm:"size:64;index"`
+	ItemID            uint64       `json:"item_id" gorm:"index"`
+	ReceiverID        uint64       `json:"receiver_id" gorm:"index"`
+	OperatorID        uint64       `json:"operator_id"`
+	Action            RevokeAction `json:"action" gorm:"size:16"`
+	ReplacementItemID *uint64      `json:"replacement_item_id"`
+	Reason            string       `json:"reason" gorm:"size:255"`
+	OutTradeNo        string       `json:"out_trade_no" gorm:"size:64"`
+	Refunded          bool         `json:"refunded"`
+	CreatedAt         time.Time    `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// RevokeItemRequest 作废已发放 item 的请求
+type RevokeItemRequest struct {
+	Action             RevokeAction `json:"action" binding:"required,oneof=replace release"`
+	Reason             string       `json:"reason" binding:"max=255"`
+	ReplacementContent string       `json:"replacement_content" binding:"max=1024"`
+	Refund             bool         `json:"refund"`
+}
+
+// Validate 校验作废方式是否适用于项目。
+// 抽奖与邀请的 item 与用户绑定,只能通过提供新内容替换;
+// 付费项目释放领取资格时必须退款,否则已完成订单会继续占用领取上限。
+func (r *RevokeItemRequest) Validate(p *Project) error {
+	if r.Refund && !p.IsPaid() {
+		return errors.New(RevokeRefundNotPaid)
+	}
+	switch r.Action {
+	case RevokeActionRelease:
+		if p.isPerUserDistribution() {
+			return errors.New(RevokeReleaseOnlyOneForEach)
+		}
+		if p.IsPaid() && !r.Refund {
+			return errors.New(RevokeReleaseRequiresRefund)
+		}
+	case RevokeActionReplace:
+		if r.ReplacementContent == "" && p.isPerUserDistribution() {
+			return errors.New(RevokeReplacementRequired)
+		}
+	}
+	return nil
+}
+
+// RevokeGuard 在作废事务内、标记作废之前执行,返回错误时放弃作废
+type RevokeGuard func(tx *gorm.DB) error
+
+// RevokeItem 将已发放的 item 标记为作废并写入审计记录。
+// 替换时优先使用请求中的新内容,否则从库存中取出一个 item 发放给原领取者;
+// 作废的 item 不再计入 TotalItems,也不会被归还到库存。
+// guard 可为 nil,payment 包以此在同一事务中校验争议并认领退款订单。
+func (p *Project) RevokeItem(ctx context.Context, itemID uint64, operatorID uint64, req *RevokeItemRequest, guard RevokeGuard) (*ProjectItemRevocation, error) {
+	var item ProjectItem
+	if err := db.DB(ctx).Where("id = ? AND project_id = ?", itemID, p.ID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(ItemNotFound)
+		}
+		return nil, err
+	}
+	if item.ReceiverID == nil {
+		return nil, errors.New(ItemNotReceived)
+	}
+	if item.RevokedAt != nil {
+		return nil, errors.New(ItemAlreadyRevoked)
+	}
+
+	// 从库存中取出替换 item,失败时需归还
+	var stockItemID uint64
+	if req.Action == RevokeActionReplace && req.ReplacementContent == "" {
+		val, err := db.Redis.LPop(ctx, p.ItemsKey()).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, errors.New(NoStock)
+		} else if err != nil {
+			return nil, err
+		}
+		if stockItemID, err = strconv.ParseUint(val, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	revocation := &ProjectItemRevocation{
+		ProjectID:  p.ID,
+		ItemID:     item.ID,
+		ReceiverID: *item.ReceiverID,
+		OperatorID: operatorID,
+		Action:     req.Action,
+		Reason:     req.Reason,
+	}
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if guard != nil {
+			if err := guard(tx); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		result := tx.Model(&ProjectItem{}).
+			Where("id = ? AND revoked_at IS NULL", item.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(ItemAlreadyRevoked)
+		}
+
+		// 作废的 item 不再计入总数,新内容替换时补回
+		totalDelta := -1
+		if req.Action == RevokeActionReplace {
+			var replacement ProjectItem
+			if stockItemID > 0 {
+				if err := replacement.Exact(tx, stockItemID); err != nil {
+					return err
+				}
+			} else {
+				var err error
+				if replacement, err = newProjectItem(p.ID, req.ReplacementContent); err != nil {
+					return err
+				}
+				totalDelta = 0
+			}
+			replacement.ReceiverID = item.ReceiverID
+			replacement.ReceivedAt = &now
+			if err := tx.Save(&replacement).Error; err != nil {
+				return err
+			}
+			revocation.ReplacementItemID = &replacement.ID
+		}
+
+		if totalDelta != 0 {
+			if err := tx.Model(&Project{}).
+				Where("id = ?", p.ID).
+				Update("total_items", gorm.Expr("total_items + ?", totalDelta)).Error; err != nil {
+				return err
+			}
+		}
+		if err := p.refreshCompletedStatus(ctx, tx); err != nil {
+			return err
+		}
+		return tx.Create(revocation).Error
+	}); err != nil {
+		if stockItemID > 0 {
+			db.Redis.LPush(ctx, p.ItemsKey(), stockItemID)
+		}
+		return nil, err
+	}
+	// 仅作废时用户的有效领取数量减少,广场需重新计算其领取上限
+	if req.Action != RevokeActionReplace {
+		invalidateReceivedCache(ctx, revocation.ReceiverID)
+	}
+	return revocation, nil
+}
+
+// refreshCompletedStatus 按当前库存与待发放批次重新计算项目完成状态
+func (p *Project) refreshCompletedStatus(ctx context.Context, tx *gorm.DB) error {
+	hasStock, err := p.HasStock(ctx)
+	if err != nil {
+		return err
+	}
+	pending, err := p.HasPendingRelease(tx)
+	if err != nil {
+		return err
+	}
+	completed := !hasStock && !pending
+	if completed == p.IsCompleted {
+		return nil
+	}
+	p.IsCompleted = completed
+	if err := tx.Model(&Project{}).Where("id = ?", p.ID).Update("is_completed", p.IsCompleted).Error; err != nil {
+		return err
+	}
+	invalidateExploreCompletion(ctx, p)
+	return nil
+}
+
+// MarkRevocationRefunded 记录作废对应订单的退款结果
+func (r *ProjectItemRevocation) MarkRevocationRefunded(tx *gorm.DB, outTradeNo string, refunded bool) error {
+	r.OutTradeNo = outTradeNo
+	r.Refunded = refunded
+	return tx.Model(r).Updates(map[string]interface{}{
+		"out_trade_no": outTradeNo,
+		"refunded":     refunded,
+	}).Error
+}
@@ -26,7 +26,12 @@ package project
 
 import (
 	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -56,15 +61,23 @@ type ProjectRequest struct {
 	RiskLevel         int8             `json:"risk_level" binding:"min=0,max=100"`
 	HideFromExplore   bool             `json:"hide_from_explore"`
 	Price             decimal.Decimal  `json:"price"`
+	ProjectQuotaRequest
 }
 type GetProjectResponseData struct {
-	Project             `json:",inline"` // 内嵌所有 Project 字段
-	CreatorUsername     string           `json:"creator_username"`
-	CreatorNickname     string           `json:"creator_nickname"`
-	Tags                []string         `json:"tags"`
-	AvailableItemsCount int64            `json:"available_items_count"`
-	IsReceived          bool             `json:"is_received"`
-	ReceivedContent     string           `json:"received_content"`
+	Project              `json:",inline"` // 内嵌所有 Project 字段
+	CreatorUsername      string           `json:"creator_username"`
+	CreatorNickname      string           `json:"creator_nickname"`
+	Tags                 []string         `json:"tags"`
+	AvailableItemsCount  int64            `json:"available_items_count"`
+	ReleasedItemsCount   int64            `json:"released_items_count"`
+	UnreleasedItemsCount int64            `json:"unreleased_items_count"`
+	Releases             []ProjectRelease `json:"releases"`
+	UserClaimQuota       int64            `json:"user_claim_quota"`
+	IsReceived           bool             `json:"is_received"`
+	ReceivedContent      string           `json:"received_content"`
+	ReceivedContents     []string         `json:"received_contents"`
+	IsFavorited          bool             `json:"is_favorited"`
+	IsFollowingCreator   bool             `json:"is_following_creator"`
 }
 
 // GetProject
@@ -89,6 +102,13 @@ func GetProject(c *gin.Context) {
 		}
 		return
 	}
+	if visible, err := project.IsVisibleTo(db.DB(c.Request.Context()), currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	} else if !visible {
+		c.JSON(http.StatusNotFound, ProjectResponse{ErrorMsg: NotFound})
+		return
+	}
 	if err := project.ValidateRequirement(currentUser); err != nil {
 		c.JSON(http.StatusForbidden, ProjectResponse{ErrorMsg: err.Error()})
 		return
@@ -114,16 +134,45 @@ func GetProject(c *gin.Context) {
 	}
 	availableItemsCount := stock
 
-	isReceived := false
+	// compute staged release counts
+	releases, err := project.GetReleases(db.DB(c.Request.Context()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	var unreleasedItemsCount int64
+	for _, release := range releases {
+		if release.ReleasedAt == nil {
+			unreleasedItemsCount += release.ItemCount
+		}
+	}
+
+	items, err := project.GetReceivedItems(c.Request.Context(), currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
 	receivedContent := ""
-	item, err := project.GetReceivedItem(c.Request.Context(), oauth.GetUserIDFromContext(c))
+	receivedContents := make([]string, len(items))
+	for i, item := range items {
+		if receivedContents[i], err = item.PlainContent(); err != nil {
+			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+			return
+		}
+	}
+	if len(items) > 0 {
+		receivedContent = receivedContents[0]
+	}
+
+	isFavorited, err := project.IsFavoritedBy(db.DB(c.Request.Context()), currentUser.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
-	if item != nil {
-		isReceived = true
-		receivedContent = item.Content
+	isFollowing, err := IsFollowing(db.DB(c.Request.Context()), currentUser.ID, project.CreatorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
 	}
 
 	creatorNickname := user.Nickname
@@ -132,13 +181,20 @@ func GetProject(c *gin.Context) {
 	}
 
 	responseData := GetProjectResponseData{
-		Project:             project,
-		CreatorUsername:     user.Username,
-		CreatorNickname:     creatorNickname,
-		Tags:                tags,
-		AvailableItemsCount: availableItemsCount,
-		IsReceived:          isReceived,
-		ReceivedContent:     receivedContent,
+		Project:              project,
+		CreatorUsername:      user.Username,
+		CreatorNickname:      creatorNickname,
+		Tags:                 tags,
+		AvailableItemsCount:  availableItemsCount,
+		ReleasedItemsCount:   project.TotalItems - unreleasedItemsCount,
+		UnreleasedItemsCount: unreleasedItemsCount,
+		Releases:             releases,
+		UserClaimQuota:       project.ClaimQuotaFor(currentUser.TrustLevel),
+		IsReceived:           len(items) > 0,
+		ReceivedContent:      receivedContent,
+		ReceivedContents:     receivedContents,
+		IsFavorited:          isFavorited,
+		IsFollowingCreator:   isFollowing,
 	}
 
 	c.JSON(http.StatusOK, ProjectResponse{Data: responseData})
@@ -146,9 +202,11 @@ func GetProject(c *gin.Context) {
 
 type CreateProjectRequestBody struct {
 	ProjectRequest
-	DistributionType DistributionType `json:"distribution_type" binding:"oneof=0 1"`
+	ProjectInviteRequest
+	LotteryRequest
+	ProjectReleaseRequest
+	DistributionType DistributionType `json:"distribution_type" binding:"oneof=0 1 2"`
 	ProjectItems     []string         `json:"project_items" binding:"required,min=1,dive,min=1,max=1024"`
-	TopicId          uint64           `json:"topic_id" binding:"omitempty,gt=0"`
 }
 
 // CreateProject
@@ -170,7 +228,19 @@ func CreateProject(c *gin.Context) {
 	currentUser, _ := oauth.GetUserFromContext(c)
 
 	// validate price
-	if err := validateProjectPrice(c.Request.Context(), req.Price, req.DistributionType, currentUser.ID); err != nil {
+	if err := validateProjectPrice(c.Request.Context(), req.Price, currentUser.ID); err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	// validate release schedule
+	if err := req.ProjectReleaseRequest.Validate(req.DistributionType, req.EndTime, len(req.ProjectItems)); err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	// validate claim quota
+	if err := req.ProjectQuotaRequest.Validate(req.DistributionType); err != nil {
 		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
@@ -192,6 +262,42 @@ func CreateProject(c *gin.Context) {
 		HideFromExplore:   req.HideFromExplore,
 		Price:             req.Price,
 	}
+	req.ProjectQuotaRequest.ApplyTo(&project)
+
+	// init winner source
+	var winnerSource WinnerSource
+	if project.DistributionType == DistributionTypeLottery {
+		source, err := req.NewWinnerSource()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+			return
+		}
+		winnerSource = source
+		project.WinnerSource = req.WinnerSource
+		switch project.WinnerSource {
+		case WinnerSourceDraw:
+			// 创建时即开奖,种子随结果一并公开
+			now := time.Now()
+			project.DrawSeed = source.(*DrawWinnerSource).Seed
+			project.DrawSeedHash = DrawSeedHash(project.DrawSeed)
+			project.DrawnAt = &now
+			project.DrawItemCount = int64(len(req.ProjectItems))
+		case WinnerSourceScheduledDraw:
+			// 报名抽奖创建时仅公开种子哈希,开奖后公开种子
+			if req.ClaimEndTime == nil || !req.ClaimEndTime.After(req.EndTime) {
+				c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: ClaimEndTimeRequired})
+				return
+			}
+			seed, err := GenerateDrawSeed()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+				return
+			}
+			project.DrawSeed = seed
+			project.DrawSeedHash = DrawSeedHash(seed)
+			project.ClaimEndTime = req.ClaimEndTime
+		}
+	}
 
 	// create project
 	if err := db.DB(c.Request.Context()).Transaction(
@@ -205,16 +311,36 @@ func CreateProject(c *gin.Context) {
 				return err
 			}
 			// create content
-			if err := project.CreateItems(c.Request.Context(), tx, req.ProjectItems, req.TopicId); err != nil {
+			if project.DistributionType == DistributionTypeInvite {
+				invitees, err := req.ResolveUsernames(tx)
+				if err != nil {
+					return err
+				}
+				return project.CreateInviteItems(c.Request.Context(), tx, req.ProjectItems, invitees)
+			}
+			if len(req.ReleaseSchedule) > 0 {
+				return project.CreateItemsWithRelease(c.Request.Context(), tx, req.ProjectItems, req.ReleaseSchedule)
+			}
+			if err := project.CreateItems(c.Request.Context(), tx, req.ProjectItems, winnerSource); err != nil {
 				return err
 			}
+			// save draw candidates for verification
+			if project.WinnerSource == WinnerSourceDraw {
+				return project.SaveLotteryEntries(tx, req.LotteryCandidates)
+			}
+			// schedule draw at end time
+			if project.WinnerSource == WinnerSourceScheduledDraw {
+				return project.EnqueueDraw(c.Request.Context())
+			}
 			return nil
 		},
 	); err != nil {
-		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		c.JSON(inviteErrorStatus(err), ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
 
+	InvalidateExploreCacheFor(c.Request.Context(), &project)
+
 	// response
 	c.JSON(http.StatusOK, ProjectResponse{
 		Data: map[string]interface{}{"projectId": project.ID},
@@ -223,8 +349,12 @@ func CreateProject(c *gin.Context) {
 
 type UpdateProjectRequestBody struct {
 	ProjectRequest
+	ProjectInviteRequest
+	ProjectReleaseRequest
 	ProjectItems []string `json:"project_items" binding:"dive,min=1,max=1024"`
 	EnableFilter bool     `json:"enable_filter"`
+	// ClaimEndTime 报名抽奖的领取截止时间,为空时保留原值
+	ClaimEndTime *time.Time `json:"claim_end_time"`
 }
 
 // UpdateProject
@@ -245,14 +375,29 @@ func UpdateProject(c *gin.Context) {
 
 	// load project
 	project, _ := GetProjectFromContext(c)
+	// 修改前后任一状态在广场展示时,保存后使广场缓存失效
+	before := *project
 
 	// validate price (复用创建者 ID + 原分发类型)
-	if err := validateProjectPrice(c.Request.Context(), req.Price, project.DistributionType, project.CreatorID); err != nil {
+	if err := validateProjectPrice(c.Request.Context(), req.Price, project.CreatorID); err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	// validate release schedule
+	if err := req.ProjectReleaseRequest.Validate(project.DistributionType, req.EndTime, len(req.ProjectItems)); err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	// validate claim quota
+	if err := req.ProjectQuotaRequest.Validate(project.DistributionType); err != nil {
 		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
 
 	// init project
+	endTimeChanged := !project.EndTime.Equal(req.EndTime)
 	project.Name = req.Name
 	project.Description = req.Description
 	project.StartTime = req.StartTime
@@ -262,17 +407,85 @@ func UpdateProject(c *gin.Context) {
 	project.RiskLevel = req.RiskLevel
 	project.HideFromExplore = req.HideFromExplore
 	project.Price = req.Price
+	req.ProjectQuotaRequest.ApplyTo(project)
 
 	if project.DistributionType == DistributionTypeLottery {
+		// 领取窗口为 EndTime 至 ClaimEndTime,调整任一时间都需重新校验
+		if project.WinnerSource == WinnerSourceScheduledDraw {
+			if req.ClaimEndTime != nil {
+				project.ClaimEndTime = req.ClaimEndTime
+			}
+			if project.ClaimEndTime == nil || !project.ClaimEndTime.After(project.EndTime) {
+				c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: ClaimEndTimeRequired})
+				return
+			}
+		}
 		// save project
 		if err := db.DB(c.Request.Context()).Save(&project).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
-		} else {
-			c.JSON(http.StatusOK, ProjectResponse{})
+			return
+		}
+		InvalidateExploreCacheFor(c.Request.Context(), &before, project)
+		// reschedule draw
+		if project.WinnerSource == WinnerSourceScheduledDraw && project.DrawnAt == nil && endTimeChanged {
+			if err := project.EnqueueDraw(c.Request.Context()); err != nil {
+				c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+				return
+			}
 		}
+		c.JSON(http.StatusOK, ProjectResponse{})
 		return
 	}
 
+	if project.DistributionType == DistributionTypeInvite {
+		// 追加的物品需绑定新增的受邀用户,缺少名单时不能静默丢弃
+		if !req.IsProvided() && len(req.ProjectItems) > 0 {
+			c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: InviteItemsRequireInvitees})
+			return
+		}
+		if err := db.DB(c.Request.Context()).Transaction(
+			func(tx *gorm.DB) error {
+				// refresh invitees, items are bound to newly invited users
+				if req.IsProvided() {
+					invitees, err := req.ResolveUsernames(tx)
+					if err != nil {
+						return err
+					}
+					if err := project.RefreshInvitees(c.Request.Context(), tx, invitees, req.ProjectItems); err != nil {
+						return err
+					}
+				}
+				// save project
+				if err := tx.Save(project).Error; err != nil {
+					return err
+				}
+				// save tags
+				return project.RefreshTags(tx, req.ProjectTags)
+			},
+		); err != nil {
+			c.JSON(inviteErrorStatus(err), ProjectResponse{ErrorMsg: err.Error()})
+			return
+		}
+		InvalidateExploreCacheFor(c.Request.Context(), &before, project)
+		c.JSON(http.StatusOK, ProjectResponse{})
+		return
+	}
+
+	// pending releases must stay within the project window
+	if endTimeChanged {
+		releases, err := project.GetReleases(db.DB(c.Request.Context()))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+			return
+		}
+		for _, release := range releases {
+			if release.ReleasedAt == nil && !release.ReleaseAt.Before(project.EndTime) {
+				c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: ReleaseAfterEndTime})
+				return
+			}
+		}
+	}
+
 	// save to db
 	if err := db.DB(c.Request.Context()).Transaction(
 		func(tx *gorm.DB) error {
@@ -297,7 +510,7 @@ func UpdateProject(c *gin.Context) {
 				return err
 			}
 			// add items with filter
-			if err := project.CreateItemsWithFilter(c.Request.Context(), tx, req.ProjectItems, req.EnableFilter); err != nil {
+			if err := project.CreateItemsWithFilter(c.Request.Context(), tx, req.ProjectItems, req.EnableFilter, req.ReleaseSchedule); err != nil {
 				return err
 			}
 			return nil
@@ -306,6 +519,13 @@ func UpdateProject(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
+	InvalidateExploreCacheFor(c.Request.Context(), &before, project)
+
+	// reserve new items for waitlist
+	if err := project.RefillFromWaitlist(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
 
 	// response
 	c.JSON(http.StatusOK, ProjectResponse{})
@@ -346,6 +566,22 @@ func DeleteProject(c *gin.Context) {
 			if err := tx.Where("project_id = ?", project.ID).Delete(&ProjectItem{}).Error; err != nil {
 				return err
 			}
+			// delete project invitees
+			if err := tx.Where("project_id = ?", project.ID).Delete(&ProjectInvitee{}).Error; err != nil {
+				return err
+			}
+			// delete lottery entries
+			if err := tx.Where("project_id = ?", project.ID).Delete(&ProjectLotteryEntry{}).Error; err != nil {
+				return err
+			}
+			// delete release batches
+			if err := tx.Where("project_id = ?", project.ID).Delete(&ProjectRelease{}).Error; err != nil {
+				return err
+			}
+			// delete favorites
+			if err := tx.Where("project_id = ?", project.ID).Delete(&ProjectFavorite{}).Error; err != nil {
+				return err
+			}
 			// delete project
 			if err := tx.Where("id = ?", project.ID).Delete(&Project{}).Error; err != nil {
 				return err
@@ -354,7 +590,8 @@ func DeleteProject(c *gin.Context) {
 			if err := db.Redis.Del(c.Request.Context(), project.ItemsKey()).Err(); err != nil {
 				return err
 			}
-			return nil
+			// delete waitlist cache
+			return project.ClearWaitlist(c.Request.Context())
 		},
 	); err != nil {
 		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
@@ -362,13 +599,33 @@ func DeleteProject(c *gin.Context) {
 	}
 
 	// response
+	InvalidateExploreCacheFor(c.Request.Context(), project)
+
 	c.JSON(http.StatusOK, ProjectResponse{})
 }
 
 type ListProjectReceiversRequest struct {
-	Current int    `json:"current" form:"current" binding:"min=1"`
+	Current int    `json:"current" form:"current" binding:"omitempty,min=1"`
 	Size    int    `json:"size" form:"size" binding:"min=1,max=100"`
 	Search  string `json:"search" form:"search" binding:"max=1024"`
+	utils.CursorPagination
+}
+
+type ListProjectReceiversResult struct {
+	ItemID     uint64     `json:"item_id"`
+	Username   string     `json:"username"`
+	Nickname   string     `json:"nickname"`
+	Content    string     `json:"content"`
+	Encrypted  bool       `json:"-"`
+	KeyVersion int8       `json:"-"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// ListProjectReceiversResponseData 游标模式的响应;OFFSET 模式保持直接返回数组
+type ListProjectReceiversResponseData struct {
+	Total      int64                        `json:"total"`
+	Results    []ListProjectReceiversResult `json:"results"`
+	NextCursor string                       `json:"next_cursor,omitempty"`
 }
 
 // ListProjectReceivers
@@ -389,43 +646,86 @@ func ListProjectReceivers(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
 	offset := (req.Current - 1) * req.Size
 
 	// build optimized query with proper indexing strategy
 	query := db.DB(c.Request.Context()).
 		Model(&ProjectItem{}).
-		Select("users.username, users.nickname, project_items.content").
+		Select("project_items.id AS item_id, users.username, users.nickname, project_items.content, project_items.encrypted, project_items.key_version, project_items.revoked_at").
 		Joins("JOIN users ON users.id = project_items.receiver_id").
 		Where("project_items.project_id = ?", project.ID)
 
 	if req.Search != "" {
 		searchPattern := strings.TrimSpace(req.Search) + "%"
+		contentClause, contentArgs := itemContentSearchClause(strings.TrimSpace(req.Search), "%"+searchPattern)
 		if len(searchPattern) > 21 {
 			query = query.Where(
-				"users.nickname LIKE ? OR project_items.content LIKE ?",
-				"%"+searchPattern, "%"+searchPattern)
+				"users.nickname LIKE ? OR "+contentClause,
+				append([]interface{}{"%" + searchPattern}, contentArgs...)...)
 		} else {
 			query = query.Where(
-				"users.username LIKE ? OR users.nickname LIKE ? OR project_items.content LIKE ?",
-				searchPattern, "%"+searchPattern, "%"+searchPattern)
+				"users.username LIKE ? OR users.nickname LIKE ? OR "+contentClause,
+				append([]interface{}{searchPattern, "%" + searchPattern}, contentArgs...)...)
 		}
 	}
 
-	// query db with optimizations
-	var receivers []struct {
-		Username string `json:"username"`
-		Nickname string `json:"nickname"`
-		Content  string `json:"content"`
+	// count only when cursor mode asks for it; offset mode never returned a total
+	total := utils.UncountedTotal
+	if req.CursorMode() && req.WithCount {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+			return
+		}
+	}
+
+	// keyset on item id
+	var cursorItemID uint64
+	if ok, err := req.DecodeCursor(&cursorItemID); err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	} else if ok {
+		query = query.Where("project_items.id > ?", cursorItemID)
 	}
+
+	// query db with optimizations
+	queryLimit, queryOffset := req.Window(offset, req.Size)
+	var receivers []ListProjectReceiversResult
 	if err := query.
-		Offset(offset).
-		Limit(req.Size).
+		Order("project_items.id ASC").
+		Offset(queryOffset).
+		Limit(queryLimit).
 		Scan(&receivers).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
 		return
 	}
+	receivers, nextCursor, err := utils.TrimCursorPage(&req.CursorPagination, receivers, req.Size,
+		func(r *ListProjectReceiversResult) []interface{} { return []interface{}{r.ItemID} })
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	for i := range receivers {
+		content, err := DecryptItemContent(receivers[i].Content, receivers[i].Encrypted, receivers[i].KeyVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+			return
+		}
+		receivers[i].Content = content
+	}
 
 	// response
+	if req.CursorMode() {
+		c.JSON(http.StatusOK, ProjectResponse{Data: ListProjectReceiversResponseData{
+			Total:      total,
+			Results:    receivers,
+			NextCursor: nextCursor,
+		}})
+		return
+	}
 	c.JSON(http.StatusOK, ProjectResponse{Data: receivers})
 }
 
@@ -459,6 +759,7 @@ func ReportProject(c *gin.Context) {
 	// init session
 	userID := oauth.GetUserIDFromContext(c)
 
+	hidden := false
 	if err := db.DB(c.Request.Context()).Transaction(
 		func(tx *gorm.DB) error {
 			// if report count reaches threshold, mark project as hidden
@@ -485,6 +786,15 @@ func ReportProject(c *gin.Context) {
 				}
 				return err
 			}
+			// 本次举报触发隐藏时通知创建者
+			var status ProjectStatus
+			if err := tx.Model(&Project{}).Select("status").Where("id = ?", project.ID).Scan(&status).Error; err != nil {
+				return err
+			}
+			if status == ProjectStatusHidden {
+				hidden = true
+				project.NotifyReviewed(c.Request.Context(), tx, status, "举报数量达到阈值,等待管理员审核")
+			}
 			return nil
 		},
 	); err != nil {
@@ -492,13 +802,19 @@ func ReportProject(c *gin.Context) {
 		return
 	}
 
+	// 仅本次举报触发隐藏时广场内容才会变化
+	if hidden {
+		InvalidateExploreCacheFor(c.Request.Context(), project)
+	}
+
 	c.JSON(http.StatusOK, ProjectResponse{})
 }
 
 type ListReceiveHistoryRequest struct {
-	Current int    `json:"current" form:"current" binding:"min=1"`
+	Current int    `json:"current" form:"current" binding:"omitempty,min=1"`
 	Size    int    `json:"size" form:"size" binding:"min=1,max=100"`
 	Search  string `json:"search" form:"search" binding:"max=255"`
+	utils.CursorPagination
 }
 
 type ListReceiveHistoryResponseDataResult struct {
@@ -507,12 +823,16 @@ type ListReceiveHistoryResponseDataResult struct {
 	ProjectCreator         string     `json:"project_creator"`
 	ProjectCreatorNickname string     `json:"project_creator_nickname"`
 	Content                string     `json:"content"`
+	Encrypted              bool       `json:"-"`
+	KeyVersion             int8       `json:"-"`
 	ReceivedAt             *time.Time `json:"received_at"`
+	ItemID                 uint64     `json:"-"`
 }
 
 type ListReceiveHistoryResponseData struct {
-	Total   int64                                  `json:"total"`
-	Results []ListReceiveHistoryResponseDataResult `json:"results"`
+	Total      int64                                  `json:"total"`
+	Results    []ListReceiveHistoryResponseDataResult `json:"results"`
+	NextCursor string                                 `json:"next_cursor,omitempty"`
 }
 
 type ListReceiveHistoryResponse struct {
@@ -536,6 +856,10 @@ func ListReceiveHistory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
 		return
 	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
+		return
+	}
 	offset := (req.Current - 1) * req.Size
 
 	// build base query
@@ -543,7 +867,7 @@ func ListReceiveHistory(c *gin.Context) {
 		Table("project_items").
 		Joins("INNER JOIN projects ON projects.id = project_items.project_id").
 		Joins("INNER JOIN users ON users.id = projects.creator_id").
-		Where("project_items.receiver_id = ?", userID)
+		Where("project_items.receiver_id = ? AND project_items.revoked_at IS NULL", userID)
 
 	// apply search filter
 	if req.Search != "" {
@@ -562,13 +886,31 @@ func ListReceiveHistory(c *gin.Context) {
 	}
 
 	// query total count
-	var total int64
-	if err := baseQuery.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
+	total := utils.UncountedTotal
+	if req.NeedCount() {
+		if err := baseQuery.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
+			return
+		}
+	}
+
+	// keyset on (received_at, id) descending
+	var (
+		cursorReceivedAt time.Time
+		cursorItemID     uint64
+	)
+	if ok, err := req.DecodeCursor(&cursorReceivedAt, &cursorItemID); err != nil {
+		c.JSON(http.StatusBadRequest, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
 		return
+	} else if ok {
+		baseQuery = baseQuery.Where(
+			"(project_items.received_at < ? OR (project_items.received_at = ? AND project_items.id < ?))",
+			cursorReceivedAt, cursorReceivedAt, cursorItemID,
+		)
 	}
 
 	// build data query with COALESCE for nickname default
+	queryLimit, queryOffset := req.Window(offset, req.Size)
 	var results []ListReceiveHistoryResponseDataResult
 	if err := baseQuery.
 		Select(`
@@ -577,20 +919,39 @@ func ListReceiveHistory(c *gin.Context) {
             users.username as project_creator,
             COALESCE(NULLIF(users.nickname, ''), users.username) as project_creator_nickname,
             project_items.content,
-            project_items.received_at
+            project_items.encrypted,
+            project_items.key_version,
+            project_items.received_at,
+            project_items.id as item_id
         `).
 		Order("project_items.received_at DESC, project_items.id DESC").
-		Offset(offset).
-		Limit(req.Size).
+		Offset(queryOffset).
+		Limit(queryLimit).
 		Scan(&results).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
 		return
 	}
+	results, nextCursor, err := utils.TrimCursorPage(&req.CursorPagination, results, req.Size,
+		func(r *ListReceiveHistoryResponseDataResult) []interface{} {
+			return []interface{}{r.ReceivedAt, r.ItemID}
+		})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
+		return
+	}
+	for i := range results {
+		content, err := DecryptItemContent(results[i].Content, results[i].Encrypted, results[i].KeyVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ListReceiveHistoryResponse{ErrorMsg: err.Error()})
+			return
+		}
+		results[i].Content = content
+	}
 
 	c.JSON(
 		http.StatusOK,
 		ListReceiveHistoryResponse{
-			Data: ListReceiveHistoryResponseData{Total: total, Results: results},
+			Data: ListReceiveHistoryResponseData{Total: total, Results: results, NextCursor: nextCursor},
 		},
 	)
 }
@@ -624,9 +985,10 @@ func ListTags(c *gin.Context) {
 }
 
 type ListProjectsRequest struct {
-	Current int      `json:"current" form:"current" binding:"min=1"`
+	Current int      `json:"current" form:"current" binding:"omitempty,min=1"`
 	Size    int      `json:"size" form:"size" binding:"min=1,max=100"`
 	Tags    []string `json:"tags" form:"tags" binding:"dive,min=1,max=16"`
+	utils.CursorPagination
 }
 
 type ListProjectsResponseDataResult struct {
@@ -647,8 +1009,9 @@ type ListProjectsResponseDataResult struct {
 }
 
 type ListProjectsResponseData struct {
-	Total   int64                             `json:"total"`
-	Results *[]ListProjectsResponseDataResult `json:"results"`
+	Total      int64                             `json:"total"`
+	Results    *[]ListProjectsResponseDataResult `json:"results"`
+	NextCursor string                            `json:"next_cursor,omitempty"`
 }
 
 type ListProjectsResponse struct {
@@ -656,6 +1019,23 @@ type ListProjectsResponse struct {
 	Data     *ListProjectsResponseData `json:"data"`
 }
 
+// cursorErrorStatus 非法游标属于请求错误,其余为服务端错误
+func cursorErrorStatus(err error) int {
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// inviteErrorStatus 邀请名单输入错误返回 400,其余返回 500
+func inviteErrorStatus(err error) int {
+	var inviteErr inviteError
+	if errors.As(err, &inviteErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // ListProjects
 // @Tags project
 // @Param request query ListProjectsRequest true "request query"
@@ -668,11 +1048,59 @@ func ListProjects(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
 		return
 	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
 	offset := (req.Current - 1) * req.Size
 
 	currentUser, _ := oauth.GetUserFromContext(c)
 
-	pagedData, err := ListProjectsWithTags(c.Request.Context(), offset, req.Size, req.Tags, currentUser)
+	pagedData, err := ListExploreProjects(c.Request.Context(), offset, req.Size, req.Tags, currentUser, &req.CursorPagination)
+	if err != nil {
+		c.JSON(cursorErrorStatus(err), ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListProjectsResponse{
+		Data: pagedData,
+	})
+}
+
+type SearchProjectsRequest struct {
+	Current  int      `json:"current" form:"current" binding:"min=1"`
+	Size     int      `json:"size" form:"size" binding:"min=1,max=100"`
+	Keyword  string   `json:"keyword" form:"keyword" binding:"max=64"`
+	Tags     []string `json:"tags" form:"tags" binding:"dive,min=1,max=16"`
+	Sort     string   `json:"sort" form:"sort" binding:"omitempty,oneof=relevance newest ending stock price_asc price_desc popular"`
+	Pricing  string   `json:"pricing" form:"pricing" binding:"omitempty,oneof=free paid"`
+	MinPrice string   `json:"min_price" form:"min_price" binding:"omitempty,max=16"`
+	MaxPrice string   `json:"max_price" form:"max_price" binding:"omitempty,max=16"`
+
+	minPrice *decimal.Decimal
+	maxPrice *decimal.Decimal
+}
+
+// SearchProjects
+// @Tags project
+// @Param request query SearchProjectsRequest true "request query"
+// @Produce json
+// @Success 200 {object} ListProjectsResponse
+// @Router /api/v1/projects/search [get]
+func SearchProjects(c *gin.Context) {
+	req := &SearchProjectsRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+	if err := req.parsePriceRange(); err != nil {
+		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	currentUser, _ := oauth.GetUserFromContext(c)
+
+	pagedData, err := SearchProjectsWithTags(c.Request.Context(), req, currentUser)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ListProjectsResponse{ErrorMsg: err.Error()})
 		return
@@ -697,11 +1125,15 @@ func ListMyProjects(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
 		return
 	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
 	offset := (req.Current - 1) * req.Size
 
-	pagedData, err := ListMyProjectsWithTags(c.Request.Context(), userID, offset, req.Size, req.Tags)
+	pagedData, err := ListMyProjectsWithTags(c.Request.Context(), userID, offset, req.Size, req.Tags, &req.CursorPagination)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ListProjectsResponse{ErrorMsg: err.Error()})
+		c.JSON(cursorErrorStatus(err), ListProjectsResponse{ErrorMsg: err.Error()})
 		return
 	}
 
@@ -780,3 +1212,516 @@ func ListReceiveHistoryChart(c *gin.Context) {
 
 	c.JSON(http.StatusOK, ListReceiveHistoryChartResponse{Data: results})
 }
+
+// RegisterLottery
+// @Tags project
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} ProjectResponse
+// @Router /api/v1/projects/{id}/lottery/entries [post]
+func RegisterLottery(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+
+	// load project
+	project := &Project{}
+	if err := project.Exact(db.DB(c.Request.Context()), c.Param("id"), true); err != nil {
+		c.JSON(http.StatusNotFound, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	if project.WinnerSource != WinnerSourceScheduledDraw {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: NotScheduledDraw})
+		return
+	}
+
+	// check time
+	now := time.Now()
+	if now.Before(project.StartTime) || !now.Before(project.EndTime) || project.DrawnAt != nil {
+		c.JSON(http.StatusForbidden, ProjectResponse{ErrorMsg: RegistrationClosed})
+		return
+	}
+	// check requirements
+	if err := project.ValidateRequirement(currentUser); err != nil {
+		c.JSON(http.StatusForbidden, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	// create entry, 时间与开奖状态在 SQL 中再次校验
+	if err := project.RegisterEntry(db.DB(c.Request.Context()), currentUser.Username, now); err != nil {
+		switch err.Error() {
+		case AlreadyRegistered:
+			c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		case RegistrationClosed:
+			c.JSON(http.StatusForbidden, ProjectResponse{ErrorMsg: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ProjectResponse{})
+}
+
+type GetLotteryResponseData struct {
+	WinnerSource WinnerSourceType `json:"winner_source"`
+	DrawSeedHash string           `json:"draw_seed_hash"`
+	DrawSeed     string           `json:"draw_seed"`
+	DrawnAt      *time.Time       `json:"drawn_at"`
+	ItemCount    int64            `json:"item_count"`
+	Entrants     []string         `json:"entrants"`
+	Winners      []string         `json:"winners"`
+	IsRegistered bool             `json:"is_registered"`
+}
+
+type GetLotteryResponse struct {
+	ErrorMsg string                  `json:"error_msg"`
+	Data     *GetLotteryResponseData `json:"data"`
+}
+
+// GetLottery
+// @Tags project
+// @Summary 获取内置抽奖的公开数据 (Get verifiable draw data)
+// @Description 开奖后公开种子、报名名单与中奖者，任何人可通过种子复现抽奖结果
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} GetLotteryResponse
+// @Router /api/v1/projects/{id}/lottery [get]
+func GetLottery(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+
+	// load project
+	project := &Project{}
+	if err := project.Exact(db.DB(c.Request.Context()), c.Param("id"), true); err != nil {
+		c.JSON(http.StatusNotFound, GetLotteryResponse{ErrorMsg: err.Error()})
+		return
+	}
+	if project.WinnerSource != WinnerSourceDraw && project.WinnerSource != WinnerSourceScheduledDraw {
+		c.JSON(http.StatusBadRequest, GetLotteryResponse{ErrorMsg: NotScheduledDraw})
+		return
+	}
+
+	var entrants []string
+	if err := db.DB(c.Request.Context()).
+		Model(&ProjectLotteryEntry{}).
+		Where("project_id = ?", project.ID).
+		Order("username ASC").
+		Pluck("username", &entrants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, GetLotteryResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	data := &GetLotteryResponseData{
+		WinnerSource: project.WinnerSource,
+		DrawSeedHash: project.DrawSeedHash,
+		DrawnAt:      project.DrawnAt,
+		ItemCount:    project.TotalItems,
+		Entrants:     entrants,
+		IsRegistered: slices.Contains(entrants, currentUser.Username),
+	}
+	// 开奖后才公开种子,奖品数与开奖时一致
+	if project.DrawnAt != nil {
+		winners, err := project.DrawnWinners(db.DB(c.Request.Context()), entrants)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, GetLotteryResponse{ErrorMsg: err.Error()})
+			return
+		}
+		data.DrawSeed = project.DrawSeed
+		data.ItemCount = project.DrawnItemCount()
+		data.Winners = winners
+	}
+
+	c.JSON(http.StatusOK, GetLotteryResponse{Data: data})
+}
+
+type GetWaitlistResponseData struct {
+	Position             int64      `json:"position"`
+	Length               int64      `json:"length"`
+	ReservationExpireAt  *time.Time `json:"reservation_expire_at"`
+	ReservationAvailable bool       `json:"reservation_available"`
+}
+
+type GetWaitlistResponse struct {
+	ErrorMsg string                   `json:"error_msg"`
+	Data     *GetWaitlistResponseData `json:"data"`
+}
+
+// loadWaitlistProject 加载支持等候补货的项目
+func loadWaitlistProject(c *gin.Context) (*Project, bool) {
+	project := &Project{}
+	if err := project.Exact(db.DB(c.Request.Context()), c.Param("id"), true); err != nil {
+		c.JSON(http.StatusNotFound, ProjectResponse{ErrorMsg: err.Error()})
+		return nil, false
+	}
+	if !project.SupportsWaitlist() {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: WaitlistNotSupported})
+		return nil, false
+	}
+	return project, true
+}
+
+// GetWaitlist
+// @Tags project
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} GetWaitlistResponse
+// @Router /api/v1/projects/{id}/waitlist [get]
+func GetWaitlist(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+	project, ok := loadWaitlistProject(c)
+	if !ok {
+		return
+	}
+
+	position, err := project.WaitlistPosition(c.Request.Context(), currentUser.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, GetWaitlistResponse{ErrorMsg: err.Error()})
+		return
+	}
+	length, err := db.Redis.ZCard(c.Request.Context(), project.WaitlistKey()).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, GetWaitlistResponse{ErrorMsg: err.Error()})
+		return
+	}
+	expireAt, err := project.ReservationExpireAt(c.Request.Context(), currentUser.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, GetWaitlistResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetWaitlistResponse{Data: &GetWaitlistResponseData{
+		Position:             position,
+		Length:               length,
+		ReservationExpireAt:  expireAt,
+		ReservationAvailable: expireAt != nil,
+	}})
+}
+
+// JoinWaitlist
+// @Tags project
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} ProjectResponse
+// @Router /api/v1/projects/{id}/waitlist [post]
+func JoinWaitlist(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+	project, ok := loadWaitlistProject(c)
+	if !ok {
+		return
+	}
+
+	// check time
+	now := time.Now()
+	if now.Before(project.StartTime) {
+		c.JSON(http.StatusForbidden, ProjectResponse{ErrorMsg: TimeTooEarly})
+		return
+	} else if project.EndTime.Before(now) {
+		c.JSON(http.StatusForbidden, ProjectResponse{ErrorMsg: TimeTooLate})
+		return
+	}
+	// check requirements
+	if err := project.ValidateRequirement(currentUser); err != nil {
+		c.JSON(http.StatusForbidden, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	// check quota
+	if err := project.CheckClaimQuota(c.Request.Context(), currentUser); err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	// check stock
+	if hasStock, err := project.HasStock(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	} else if hasStock {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: StockAvailable})
+		return
+	}
+
+	if err := project.JoinWaitlist(c.Request.Context(), currentUser.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectResponse{})
+}
+
+// LeaveWaitlist
+// @Tags project
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} ProjectResponse
+// @Router /api/v1/projects/{id}/waitlist [delete]
+func LeaveWaitlist(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+	project, ok := loadWaitlistProject(c)
+	if !ok {
+		return
+	}
+
+	if err := project.LeaveWaitlist(c.Request.Context(), currentUser.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectResponse{})
+}
+
+// FavoriteProject
+// @Tags project
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} ProjectResponse
+// @Router /api/v1/projects/{id}/favorite [post]
+func FavoriteProject(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+	project := &Project{}
+	if err := project.Exact(db.DB(c.Request.Context()), c.Param("id"), true); err != nil {
+		c.JSON(http.StatusNotFound, ProjectResponse{ErrorMsg: NotFound})
+		return
+	}
+	if visible, err := project.IsVisibleTo(db.DB(c.Request.Context()), currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	} else if !visible {
+		c.JSON(http.StatusNotFound, ProjectResponse{ErrorMsg: NotFound})
+		return
+	}
+
+	if err := project.Favorite(db.DB(c.Request.Context()), currentUser.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectResponse{})
+}
+
+// UnfavoriteProject
+// @Tags project
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} ProjectResponse
+// @Router /api/v1/projects/{id}/favorite [delete]
+func UnfavoriteProject(c *gin.Context) {
+	userID := oauth.GetUserIDFromContext(c)
+	// 不校验项目状态,被隐藏的项目也可以取消收藏
+	project := &Project{ID: c.Param("id")}
+	if err := project.Unfavorite(db.DB(c.Request.Context()), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectResponse{})
+}
+
+type ListFavoriteProjectsResponse struct {
+	ErrorMsg string                            `json:"error_msg"`
+	Data     *ListFavoriteProjectsResponseData `json:"data"`
+}
+
+// ListFavoriteProjects
+// @Tags project
+// @Param request query ListProjectsRequest true "request query"
+// @Produce json
+// @Success 200 {object} ListFavoriteProjectsResponse
+// @Router /api/v1/projects/favorites [get]
+func ListFavoriteProjects(c *gin.Context) {
+	userID := oauth.GetUserIDFromContext(c)
+
+	req := &ListProjectsRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, ListFavoriteProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, ListFavoriteProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+	offset := (req.Current - 1) * req.Size
+
+	pagedData, err := ListFavoriteProjectsWithTags(c.Request.Context(), userID, offset, req.Size, req.Tags, &req.CursorPagination)
+	if err != nil {
+		c.JSON(cursorErrorStatus(err), ListFavoriteProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListFavoriteProjectsResponse{
+		Data: pagedData,
+	})
+}
+
+// ListFollowingProjects
+// @Tags project
+// @Param request query ListProjectsRequest true "request query"
+// @Produce json
+// @Success 200 {object} ListProjectsResponse
+// @Router /api/v1/projects/following [get]
+func ListFollowingProjects(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+
+	req := &ListProjectsRequest{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+	if err := req.ValidatePage(req.Current); err != nil {
+		c.JSON(http.StatusBadRequest, ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+	offset := (req.Current - 1) * req.Size
+
+	pagedData, err := ListFollowingProjectsWithTags(c.Request.Context(), offset, req.Size, req.Tags, currentUser, &req.CursorPagination)
+	if err != nil {
+		c.JSON(cursorErrorStatus(err), ListProjectsResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListProjectsResponse{
+		Data: pagedData,
+	})
+}
+
+// parseCreatorID 解析路径中的创建者 ID
+func parseCreatorID(c *gin.Context) (uint64, bool) {
+	creatorID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || creatorID == 0 {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: CreatorNotFound})
+		return 0, false
+	}
+	return creatorID, true
+}
+
+// FollowCreator
+// @Tags project
+// @Produce json
+// @Param id path int true "创建者用户ID"
+// @Success 200 {object} ProjectResponse
+// @Router /api/v1/users/{id}/follow [post]
+func FollowCreator(c *gin.Context) {
+	currentUser, _ := oauth.GetUserFromContext(c)
+	creatorID, ok := parseCreatorID(c)
+	if !ok {
+		return
+	}
+
+	if err := AddCreatorFollow(db.DB(c.Request.Context()), currentUser, creatorID); err != nil {
+		switch err.Error() {
+		case CannotFollowSelf:
+			c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		case CreatorNotFound:
+			c.JSON(http.StatusNotFound, ProjectResponse{ErrorMsg: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, ProjectResponse{})
+}
+
+// UnfollowCreator
+// @Tags project
+// @Produce json
+// @Param id path int true "创建者用户ID"
+// @Success 200 {object} ProjectResponse
+// @Router /api/v1/users/{id}/follow [delete]
+func UnfollowCreator(c *gin.Context) {
+	userID := oauth.GetUserIDFromContext(c)
+	creatorID, ok := parseCreatorID(c)
+	if !ok {
+		return
+	}
+
+	if err := RemoveCreatorFollow(db.DB(c.Request.Context()), userID, creatorID); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectResponse{})
+}
+
+// ImportProjectItems
+// @Tags project
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param file formData file true "物品文件 (.txt 每行一个, .csv 取第一列)"
+// @Success 200 {object} ProjectResponse{data=ImportItemsSummary}
+// @Router /api/v1/projects/{id}/items/import [post]
+func ImportProjectItems(c *gin.Context) {
+	// load project
+	project, _ := GetProjectFromContext(c)
+	if project.DistributionType != DistributionTypeOneForEach {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: ImportOnlyOneForEach})
+		return
+	}
+
+	// stream multipart body
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+	multipartReader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	var part *multipart.Part
+	for {
+		part, err = multipartReader.NextPart()
+		if errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: ImportFileRequired})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: err.Error()})
+			return
+		}
+		if part.FormName() == "file" {
+			break
+		}
+	}
+	defer part.Close()
+
+	// init line reader
+	var reader ItemLineReader
+	switch strings.ToLower(filepath.Ext(part.FileName())) {
+	case ".csv":
+		reader = NewCSVLineReader(part)
+	case ".txt", "":
+		reader = NewTXTLineReader(part)
+	default:
+		c.JSON(http.StatusBadRequest, ProjectResponse{ErrorMsg: ImportFileTypeInvalid})
+		return
+	}
+
+	// do import
+	summary, err := project.ImportItems(c.Request.Context(), reader)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error(), Data: summary})
+		return
+	}
+
+	// reserve new items for waitlist
+	if err := project.RefillFromWaitlist(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error(), Data: summary})
+		return
+	}
+
+	invalidateExploreCompletion(c.Request.Context(), project)
+
+	c.JSON(http.StatusOK, ProjectResponse{Data: summary})
+}
+
+type ExportProjectItemsRequest struct {
+	Format ExportFormat `json:"format" form:"format" binding:"omitempty,oneof=csv xlsx"`
+}
+
+// ListItemRevocations
+// @Tags project
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} ProjectResponse{data=[]ProjectItemRevocation}
+// @Router /api/v1/projects/{id}/items/revocations [get]
+func ListItemRevocations(c *gin.Context) {
+	// load project
+	project, _ := GetProjectFromContext(c)
+
+	var revocations []ProjectItemRevocation
+	if err := db.DB(c.Request.Context()).
+		Where("project_id = ?", project.ID).
+		Order("id DESC").
+		Find(&revocations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ProjectResponse{ErrorMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectResponse{Data: revocations})
+}
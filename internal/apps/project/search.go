@@ -0,0 +1,179 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/shopspring/decimal"
+)
+
+// 项目搜索排序方式
+const (
+	SearchSortRelevance = "relevance"  // 关键词相关度,仅在有关键词时生效
+	SearchSortNewest    = "newest"     // 最新创建
+	SearchSortEnding    = "ending"     // 即将结束
+	SearchSortStock     = "stock"      // 剩余库存最多
+	SearchSortPriceAsc  = "price_asc"  // 价格从低到高
+	SearchSortPriceDesc = "price_desc" // 价格从高到低
+	SearchSortPopular   = "popular"    // 已领取数量最多
+)
+
+// 项目搜索价格筛选
+const (
+	SearchPricingFree = "free"
+	SearchPricingPaid = "paid"
+)
+
+// exploreEligibilityClause 广场可见项目的资格条件:未结束、正常状态、信任与风险等级满足、
+// 未隐藏、当前用户未达到领取上限,邀请制项目仅对被邀请人可见。参数见 exploreEligibilityArgs
+const exploreEligibilityClause = `p.end_time > ? AND p.is_completed = false AND p.status = ? AND p.minimum_trust_level <= ? AND p.risk_level >= ? AND p.hide_from_explore = false
+				AND ( SELECT COUNT(*) FROM project_items pi WHERE pi.project_id = p.id AND pi.receiver_id = ? AND pi.revoked_at IS NULL) < ` + claimQuotaSql + `
+				AND (p.distribution_type != ? OR EXISTS ( SELECT 1 FROM project_invitees pv WHERE pv.project_id = p.id AND pv.username = ?))`
+
+// exploreEligibilityArgs 返回 exploreEligibilityClause 的参数
+func exploreEligibilityArgs(now time.Time, currentUser *oauth.User) []interface{} {
+	return []interface{}{now, ProjectStatusNormal, currentUser.TrustLevel, currentUser.RiskLevel(), currentUser.ID, trustLevelQuotaPath(currentUser.TrustLevel), DistributionTypeInvite, currentUser.Username}
+}
+
+// projectKeywordMatch 名称与描述的全文匹配,依赖 idx_projects_fulltext(ngram 分词)
+const projectKeywordMatch = `MATCH(p.name, p.description) AGAINST (? IN NATURAL LANGUAGE MODE)`
+
+// searchSortOrders 各排序方式对应的 ORDER BY,库存与热度按 idx_project_item_receiver 计数
+var searchSortOrders = map[string]string{
+	SearchSortNewest:    `p.created_at DESC`,
+	SearchSortEnding:    `p.end_time ASC`,
+	SearchSortStock:     `(SELECT COUNT(*) FROM project_items si WHERE si.project_id = p.id AND si.receiver_id IS NULL AND si.revoked_at IS NULL) DESC`,
+	SearchSortPriceAsc:  `p.price ASC`,
+	SearchSortPriceDesc: `p.price DESC`,
+	SearchSortPopular:   `(SELECT COUNT(*) FROM project_items ri WHERE ri.project_id = p.id AND ri.receiver_id IS NOT NULL) DESC`,
+}
+
+// SearchProjectsWithTags 按关键词、标签与价格搜索广场项目,保留 ListProjectsWithTags 的全部资格过滤
+func SearchProjectsWithTags(ctx context.Context, req *SearchProjectsRequest, currentUser *oauth.User) (*ListProjectsResponseData, error) {
+	where := exploreEligibilityClause
+	parameters := exploreEligibilityArgs(time.Now(), currentUser)
+
+	keyword := strings.TrimSpace(req.Keyword)
+	if keyword != "" {
+		where += ` AND ` + projectKeywordMatch
+		parameters = append(parameters, keyword)
+	}
+	if len(req.Tags) > 0 {
+		where += ` AND EXISTS ( SELECT 1 FROM project_tags ptf WHERE ptf.project_id = p.id AND ptf.tag IN (?))`
+		parameters = append(parameters, req.Tags)
+	}
+	switch req.Pricing {
+	case SearchPricingFree:
+		where += ` AND p.price = 0`
+	case SearchPricingPaid:
+		where += ` AND p.price > 0`
+	}
+	if req.minPrice != nil {
+		where += ` AND p.price >= ?`
+		parameters = append(parameters, *req.minPrice)
+	}
+	if req.maxPrice != nil {
+		where += ` AND p.price <= ?`
+		parameters = append(parameters, *req.maxPrice)
+	}
+
+	// 查询总数
+	var total int64
+	if err := db.DB(ctx).
+		Raw(`SELECT COUNT(*) FROM projects p WHERE `+where, parameters...).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return &ListProjectsResponseData{
+			Total:   0,
+			Results: nil,
+		}, nil
+	}
+
+	// 无关键词时相关度排序退化为即将结束
+	sort := req.Sort
+	if sort == "" || (sort == SearchSortRelevance && keyword == "") {
+		if keyword != "" {
+			sort = SearchSortRelevance
+		} else {
+			sort = SearchSortEnding
+		}
+	}
+	orderBy := searchSortOrders[sort]
+	if sort == SearchSortRelevance {
+		orderBy = projectKeywordMatch + ` DESC`
+		parameters = append(parameters, keyword)
+	}
+
+	searchSql := `SELECT
+    			p.id,p.name,p.description,p.distribution_type,p.total_items,
+       			p.start_time,p.end_time,p.minimum_trust_level,p.allow_same_ip,p.risk_level,p.price,p.created_at,
+				IF(COUNT(pt.tag) = 0, NULL, JSON_ARRAYAGG(pt.tag)) AS tags
+			FROM projects p
+			LEFT JOIN project_tags pt ON p.id = pt.project_id
+			WHERE ` + where + `
+			GROUP BY p.id ORDER BY ` + orderBy + `, p.id ASC LIMIT ? OFFSET ?`
+	parameters = append(parameters, req.Size, (req.Current-1)*req.Size)
+
+	var results []ListProjectsResponseDataResult
+	if err := db.DB(ctx).Raw(searchSql, parameters...).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return &ListProjectsResponseData{
+		Total:   total,
+		Results: &results,
+	}, nil
+}
+
+// parsePriceRange 解析价格区间,下限不得高于上限
+func (r *SearchProjectsRequest) parsePriceRange() error {
+	parse := func(s string) (*decimal.Decimal, error) {
+		if s == "" {
+			return nil, nil
+		}
+		d, err := decimal.NewFromString(s)
+		if err != nil || d.IsNegative() {
+			return nil, errors.New(InvalidPriceRange)
+		}
+		return &d, nil
+	}
+	var err error
+	if r.minPrice, err = parse(r.MinPrice); err != nil {
+		return err
+	}
+	if r.maxPrice, err = parse(r.MaxPrice); err != nil {
+		return err
+	}
+	if r.minPrice != nil && r.maxPrice != nil && r.minPrice.GreaterThan(*r.maxPrice) {
+		return errors.New(InvalidPriceRange)
+	}
+	return nil
+}
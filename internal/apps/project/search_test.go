@@ -0,0 +1,154 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/linux-do/cdk/internal/apps/oauth"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+	"github.com/shopspring/decimal"
+)
+
+// setupSearch 准备三个可搜索项目:
+// free 免费、最新创建、库存 2;cheap 1.00、最早结束、库存 1 且已被领取 2 个;pricey 5.00、库存 3、带标签 t
+func setupSearch(t *testing.T) *oauth.User {
+	t.Helper()
+	dbtest.Setup(t, &oauth.User{}, &Project{}, &ProjectItem{}, &ProjectTag{}, &ProjectInvitee{})
+	tx := db.DB(context.Background())
+
+	user := &oauth.User{ID: 1, Username: "alice", TrustLevel: oauth.TrustLevelNewUser + 1, Score: oauth.BaseUserScore}
+	if err := tx.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	projects := []Project{
+		{ID: "free", Name: "free", EndTime: now.Add(3 * time.Hour), CreatedAt: now},
+		{ID: "cheap", Name: "cheap", EndTime: now.Add(time.Hour), Price: decimal.RequireFromString("1.00"), CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "pricey", Name: "pricey", EndTime: now.Add(2 * time.Hour), Price: decimal.RequireFromString("5.00"), CreatedAt: now.Add(-time.Hour)},
+	}
+	if err := tx.Create(&projects).Error; err != nil {
+		t.Fatal(err)
+	}
+	var items []ProjectItem
+	for id, stock := range map[string]int{"free": 2, "cheap": 1, "pricey": 3} {
+		for range stock {
+			items = append(items, ProjectItem{ProjectID: id, Content: "code"})
+		}
+	}
+	for _, receiverID := range []uint64{2, 3} {
+		items = append(items, ProjectItem{ProjectID: "cheap", Content: "code", ReceiverID: &receiverID})
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&ProjectTag{ProjectID: "pricey", Tag: "t"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestSearchProjectsSortAndFilter(t *testing.T) {
+	user := setupSearch(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		req  SearchProjectsRequest
+		want []string
+	}{
+		{"default ending", SearchProjectsRequest{}, []string{"cheap", "pricey", "free"}},
+		{"relevance without keyword", SearchProjectsRequest{Sort: SearchSortRelevance}, []string{"cheap", "pricey", "free"}},
+		{"newest", SearchProjectsRequest{Sort: SearchSortNewest}, []string{"free", "pricey", "cheap"}},
+		{"stock", SearchProjectsRequest{Sort: SearchSortStock}, []string{"pricey", "free", "cheap"}},
+		{"price asc", SearchProjectsRequest{Sort: SearchSortPriceAsc}, []string{"free", "cheap", "pricey"}},
+		{"price desc", SearchProjectsRequest{Sort: SearchSortPriceDesc}, []string{"pricey", "cheap", "free"}},
+		{"popular", SearchProjectsRequest{Sort: SearchSortPopular}, []string{"cheap", "free", "pricey"}},
+		{"free only", SearchProjectsRequest{Pricing: SearchPricingFree}, []string{"free"}},
+		{"paid only", SearchProjectsRequest{Pricing: SearchPricingPaid}, []string{"cheap", "pricey"}},
+		{"min price", SearchProjectsRequest{MinPrice: "1"}, []string{"cheap", "pricey"}},
+		{"max price", SearchProjectsRequest{MaxPrice: "1.00"}, []string{"cheap", "free"}},
+		{"price range", SearchProjectsRequest{MinPrice: "0.5", MaxPrice: "4.99"}, []string{"cheap"}},
+		{"tag", SearchProjectsRequest{Tags: []string{"t"}}, []string{"pricey"}},
+		{"no match", SearchProjectsRequest{Pricing: SearchPricingFree, MinPrice: "1"}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			req.Current, req.Size = 1, 10
+			if err := req.parsePriceRange(); err != nil {
+				t.Fatal(err)
+			}
+			data, err := SearchProjectsWithTags(ctx, &req, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultIDs(data); !slices.Equal(got, tc.want) || data.Total != int64(len(tc.want)) {
+				t.Fatalf("got %v (total %d), want %v", got, data.Total, tc.want)
+			}
+		})
+	}
+
+	// 分页保持排序与总数
+	req := SearchProjectsRequest{Current: 2, Size: 2, Sort: SearchSortPriceAsc}
+	data, err := SearchProjectsWithTags(ctx, &req, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(data); !slices.Equal(got, []string{"pricey"}) || data.Total != 3 {
+		t.Fatalf("page 2: got %v (total %d)", got, data.Total)
+	}
+}
+
+func TestSearchProjectsParsePriceRange(t *testing.T) {
+	cases := []struct {
+		name     string
+		min, max string
+		err      bool
+	}{
+		{"empty", "", "", false},
+		{"range", "0", "9.99", false},
+		{"equal bounds", "1.5", "1.50", false},
+		{"negative", "-1", "", true},
+		{"not a number", "", "abc", true},
+		{"min above max", "5", "1", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := SearchProjectsRequest{MinPrice: tc.min, MaxPrice: tc.max}
+			err := req.parsePriceRange()
+			if (err != nil) != tc.err {
+				t.Fatalf("error = %v, want error %v", err, tc.err)
+			}
+			if err != nil && err.Error() != InvalidPriceRange {
+				t.Fatalf("error = %q, want %q", err, InvalidPriceRange)
+			}
+		})
+	}
+}
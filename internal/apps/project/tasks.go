@@ -0,0 +1,152 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// HandleDrawLottery 处理报名抽奖的开奖任务
+func HandleDrawLottery(ctx context.Context, t *asynq.Task) error {
+	// 解析任务参数
+	var payload struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析任务参数失败: %w", err)
+	}
+
+	var p Project
+	if err := db.DB(ctx).Where("id = ?", payload.ProjectID).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.InfoF(ctx, "项目[%s]不存在，跳过开奖", payload.ProjectID)
+			return nil
+		}
+		return fmt.Errorf("查询项目[%s]失败: %w", payload.ProjectID, err)
+	}
+	if p.WinnerSource != WinnerSourceScheduledDraw {
+		return nil
+	}
+
+	// 已开奖时补写此前发布失败的预留
+	now := time.Now()
+	if p.DrawnAt != nil {
+		return p.RestoreDrawReservations(ctx, now)
+	}
+
+	// EndTime 被延后时重新下发任务
+	if now.Before(p.EndTime) {
+		logger.InfoF(ctx, "项目[%s]尚未到开奖时间，重新下发开奖任务", p.ID)
+		return p.EnqueueDraw(ctx)
+	}
+
+	var result *DrawResult
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = p.Draw(tx, now)
+		return err
+	}); err != nil {
+		logger.ErrorF(ctx, "项目[%s]开奖失败: %v", p.ID, err)
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	// 事务提交后才写入预留与通知中奖者,失败时任务重试并补写
+	if err := p.PublishDraw(ctx, result); err != nil {
+		logger.ErrorF(ctx, "项目[%s]发布开奖结果失败: %v", p.ID, err)
+		return err
+	}
+	logger.InfoF(ctx, "项目[%s]开奖成功", p.ID)
+	return nil
+}
+
+// HandleExpireWaitlistReservations 归还超时未领取的等候预留,并顺延给下一位等候用户
+func HandleExpireWaitlistReservations(ctx context.Context, _ *asynq.Task) error {
+	members, err := db.Redis.ZRangeByScore(ctx, waitlistReservationExpireKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: 200,
+	}).Result()
+	if err != nil {
+		logger.ErrorF(ctx, "查询过期等候预留失败: %v", err)
+		return err
+	}
+	logger.InfoF(ctx, "发现 %d 个过期等候预留", len(members))
+
+	for _, member := range members {
+		if err := expireReservation(ctx, member); err != nil {
+			logger.ErrorF(ctx, "处理过期等候预留[%s]失败: %v", member, err)
+		}
+	}
+	return nil
+}
+
+// HandleNotifyFavoritesStarted 通知收藏者项目已开始
+func HandleNotifyFavoritesStarted(ctx context.Context, _ *asynq.Task) error {
+	var notified int
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		notified, err = notifyFavoritesStarted(ctx, tx, time.Now(), 500)
+		return err
+	}); err != nil {
+		logger.ErrorF(ctx, "[Notification] notify started favorites failed: %v", err)
+		return err
+	}
+	logger.InfoF(ctx, "[Notification] sent %d started favorite notifications", notified)
+	return nil
+}
+
+// HandleReleaseDueItems 将到达发放时间的批次推入库存
+func HandleReleaseDueItems(ctx context.Context, _ *asynq.Task) error {
+	var releases []ProjectRelease
+	if err := db.DB(ctx).
+		Where("released_at IS NULL AND release_at <= ?", time.Now()).
+		Order("release_at ASC").
+		Limit(200).
+		Find(&releases).Error; err != nil {
+		logger.ErrorF(ctx, "查询待发放批次失败: %v", err)
+		return err
+	}
+	logger.InfoF(ctx, "发现 %d 个待发放批次", len(releases))
+
+	for i := range releases {
+		if err := releases[i].Release(ctx); err != nil {
+			logger.ErrorF(ctx, "发放批次[%d]失败: %v", releases[i].ID, err)
+		}
+	}
+	return nil
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/config"
 	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/utils"
 	"github.com/shopspring/decimal"
 
 	"time"
@@ -65,13 +66,14 @@ type ProjectsPage struct {
 }
 
 // ListProjectsWithTags 查询未结束的项目列表及其标签
-func ListProjectsWithTags(ctx context.Context, offset, limit int, tags []string, currentUser *oauth.User) (*ListProjectsResponseData, error) {
+// 键集按 (end_time, id) 升序定位。
+func ListProjectsWithTags(ctx context.Context, offset, limit int, tags []string, currentUser *oauth.User, page *utils.CursorPagination) (*ListProjectsResponseData, error) {
 	now := time.Now()
 
 	getTotalCountSql := `SELECT COUNT(DISTINCT p.id) as total
 			FROM projects p
 			LEFT JOIN project_tags pt ON p.id = pt.project_id
-			WHERE p.end_time > ? AND p.is_completed = false AND p.status = ? AND p.minimum_trust_level <= ? AND p.risk_level >= ? AND p.hide_from_explore = false AND NOT EXISTS ( SELECT 1 FROM project_items pi WHERE pi.project_id = p.id AND pi.receiver_id = ?)`
+			WHERE ` + exploreEligibilityClause
 
 	getProjectWithTagsSql := `SELECT
     			p.id,p.name,p.description,p.distribution_type,p.total_items,
@@ -79,46 +81,68 @@ func ListProjectsWithTags(ctx context.Context, offset, limit int, tags []string,
 				IF(COUNT(pt.tag) = 0, NULL, JSON_ARRAYAGG(pt.tag)) AS tags
 			FROM projects p
 			LEFT JOIN project_tags pt ON p.id = pt.project_id
-			WHERE p.end_time > ? AND p.is_completed = false AND p.status = ? AND p.minimum_trust_level <= ? AND p.risk_level >= ? AND p.hide_from_explore = false AND NOT EXISTS ( SELECT 1 FROM project_items pi WHERE pi.project_id = p.id AND pi.receiver_id = ?)`
+			WHERE ` + exploreEligibilityClause
 
-	var parameters = []interface{}{now, ProjectStatusNormal, currentUser.TrustLevel, currentUser.RiskLevel(), currentUser.ID}
+	var parameters = exploreEligibilityArgs(now, currentUser)
 	if len(tags) > 0 {
 		getTotalCountSql += ` AND pt.tag IN (?)`
 		getProjectWithTagsSql += ` AND pt.tag IN (?)`
 		parameters = append(parameters, tags)
 	}
 	// 查询总数
-	var total int64
-	if err := db.DB(ctx).
-		Raw(getTotalCountSql, parameters...).Count(&total).Error; err != nil {
+	total := utils.UncountedTotal
+	if page.NeedCount() {
+		if err := db.DB(ctx).
+			Raw(getTotalCountSql, parameters...).Count(&total).Error; err != nil {
+			return nil, err
+		}
+
+		// 如果没有符合条件的项目，返回空结果
+		if total == 0 {
+			return &ListProjectsResponseData{
+				Total:   0,
+				Results: nil,
+			}, nil
+		}
+	}
+
+	// 游标定位
+	var (
+		cursorEndTime time.Time
+		cursorID      string
+	)
+	if ok, err := page.DecodeCursor(&cursorEndTime, &cursorID); err != nil {
 		return nil, err
+	} else if ok {
+		getProjectWithTagsSql += ` AND (p.end_time > ? OR (p.end_time = ? AND p.id > ?))`
+		parameters = append(parameters, cursorEndTime, cursorEndTime, cursorID)
 	}
 
-	// 如果没有符合条件的项目，返回空结果
-	if total == 0 {
-		return &ListProjectsResponseData{
-			Total:   0,
-			Results: nil,
-		}, nil
-	}
 	// 查询项目列表及其标签
-	getProjectWithTagsSql += ` GROUP BY p.id ORDER BY p.end_time ASC LIMIT ? OFFSET ?`
-	parameters = append(parameters, limit, offset)
+	queryLimit, queryOffset := page.Window(offset, limit)
+	getProjectWithTagsSql += ` GROUP BY p.id ORDER BY p.end_time ASC, p.id ASC LIMIT ? OFFSET ?`
+	parameters = append(parameters, queryLimit, queryOffset)
 	var listProjectsResponseDataResult []ListProjectsResponseDataResult
 	if err := db.DB(ctx).
 		Raw(getProjectWithTagsSql, parameters...).
 		Scan(&listProjectsResponseDataResult).Error; err != nil {
 		return nil, err
 	}
+	listProjectsResponseDataResult, nextCursor, err := utils.TrimCursorPage(page, listProjectsResponseDataResult, limit,
+		func(r *ListProjectsResponseDataResult) []interface{} { return []interface{}{r.EndTime, r.ID} })
+	if err != nil {
+		return nil, err
+	}
 
 	return &ListProjectsResponseData{
-		Total:   total,
-		Results: &listProjectsResponseDataResult,
+		Total:      total,
+		Results:    &listProjectsResponseDataResult,
+		NextCursor: nextCursor,
 	}, nil
 }
 
-// ListMyProjectsWithTags 查询我创建的项目列表及其标签
-func ListMyProjectsWithTags(ctx context.Context, creatorID uint64, offset, limit int, tags []string) (*ListProjectsResponseData, error) {
+// ListMyProjectsWithTags 查询我创建的项目列表及其标签,键集按 (created_at, id) 降序定位
+func ListMyProjectsWithTags(ctx context.Context, creatorID uint64, offset, limit int, tags []string, page *utils.CursorPagination) (*ListProjectsResponseData, error) {
 	getTotalCountSql := `SELECT COUNT(DISTINCT p.id) as total
 			FROM projects p
 			LEFT JOIN project_tags pt ON p.id = pt.project_id
@@ -141,31 +165,52 @@ func ListMyProjectsWithTags(ctx context.Context, creatorID uint64, offset, limit
 	}
 
 	// 查询总数
-	var total int64
-	if err := db.DB(ctx).Raw(getTotalCountSql, parameters...).Count(&total).Error; err != nil {
+	total := utils.UncountedTotal
+	if page.NeedCount() {
+		if err := db.DB(ctx).Raw(getTotalCountSql, parameters...).Count(&total).Error; err != nil {
+			return nil, err
+		}
+
+		// 如果没有符合条件的项目，返回空结果
+		if total == 0 {
+			return &ListProjectsResponseData{
+				Total:   0,
+				Results: nil,
+			}, nil
+		}
+	}
+
+	// 游标定位
+	var (
+		cursorCreatedAt time.Time
+		cursorID        string
+	)
+	if ok, err := page.DecodeCursor(&cursorCreatedAt, &cursorID); err != nil {
 		return nil, err
-	}
-
-	// 如果没有符合条件的项目，返回空结果
-	if total == 0 {
-		return &ListProjectsResponseData{
-			Total:   0,
-			Results: nil,
-		}, nil
+	} else if ok {
+		getMyProjectWithTagsSql += ` AND (p.created_at < ? OR (p.created_at = ? AND p.id < ?))`
+		parameters = append(parameters, cursorCreatedAt, cursorCreatedAt, cursorID)
 	}
 
 	// 查询项目列表及其标签
-	getMyProjectWithTagsSql += ` GROUP BY p.id ORDER BY p.created_at DESC LIMIT ? OFFSET ?`
-	parameters = append(parameters, limit, offset)
+	queryLimit, queryOffset := page.Window(offset, limit)
+	getMyProjectWithTagsSql += ` GROUP BY p.id ORDER BY p.created_at DESC, p.id DESC LIMIT ? OFFSET ?`
+	parameters = append(parameters, queryLimit, queryOffset)
 	var listProjectsResponseDataResult []ListProjectsResponseDataResult
 	if err := db.DB(ctx).
 		Raw(getMyProjectWithTagsSql, parameters...).Scan(&listProjectsResponseDataResult).Error; err != nil {
 		return nil, err
 	}
+	listProjectsResponseDataResult, nextCursor, err := utils.TrimCursorPage(page, listProjectsResponseDataResult, limit,
+		func(r *ListProjectsResponseDataResult) []interface{} { return []interface{}{r.CreatedAt, r.ID} })
+	if err != nil {
+		return nil, err
+	}
 
 	return &ListProjectsResponseData{
-		Total:   total,
-		Results: &listProjectsResponseDataResult,
+		Total:      total,
+		Results:    &listProjectsResponseDataResult,
+		NextCursor: nextCursor,
 	}, nil
 }
 
@@ -175,9 +220,8 @@ var maxProjectPrice = decimal.RequireFromString("99999999.99")
 // validateProjectPrice 校验 Price 字段合法性。
 // 规则:
 //   - Price 必须非负,最多 2 位小数,不超过上限
-//   - Price > 0 仅允许 DistributionTypeOneForEach
 //   - Price > 0 时必须确认全局支付功能已启用且创建者已配置 clientID/clientSecret
-func validateProjectPrice(ctx context.Context, price decimal.Decimal, dt DistributionType, creatorID uint64) error {
+func validateProjectPrice(ctx context.Context, price decimal.Decimal, creatorID uint64) error {
 	if price.IsNegative() {
 		return errors.New(InvalidPrice)
 	}
@@ -190,9 +234,6 @@ func validateProjectPrice(ctx context.Context, price decimal.Decimal, dt Distrib
 	if price.IsZero() {
 		return nil
 	}
-	if dt != DistributionTypeOneForEach {
-		return errors.New(PriceOnlyOneForEach)
-	}
 	if !config.Config.Payment.Enabled {
 		return errors.New(PaymentDisabled)
 	}
@@ -0,0 +1,257 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linux-do/cdk/internal/config"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// waitlistReservationExpireKey 全局的预留过期索引,member 为 projectID:username,score 为过期时间戳
+	waitlistReservationExpireKey = "project:waitlist:reservation_expire"
+	// defaultWaitlistReservationMinutes 预留未领取的默认保留时长
+	defaultWaitlistReservationMinutes = 10
+)
+
+// WaitlistKey 等候队列,member 为 username,score 为加入时间,按 score 先进先出
+func (p *Project) WaitlistKey() string {
+	return fmt.Sprintf("project:%s:waitlist", p.ID)
+}
+
+// ReservationsKey 已为等候用户预留的 item,field 为 username,value 为 itemID
+func (p *Project) ReservationsKey() string {
+	return fmt.Sprintf("project:%s:reservations", p.ID)
+}
+
+func waitlistReservationMember(projectID, username string) string {
+	return projectID + ":" + username
+}
+
+func waitlistReservationTTL() time.Duration {
+	minutes := config.Config.ProjectApp.WaitlistReservationMinutes
+	if minutes <= 0 {
+		minutes = defaultWaitlistReservationMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// SupportsWaitlist 仅一码一用的队列库存支持等候补货
+func (p *Project) SupportsWaitlist() bool {
+	return p.DistributionType == DistributionTypeOneForEach
+}
+
+// JoinWaitlist 加入等候队列,重复加入不会改变排队位置
+func (p *Project) JoinWaitlist(ctx context.Context, username string) error {
+	return db.Redis.ZAddNX(ctx, p.WaitlistKey(), redis.Z{
+		Score:  float64(time.Now().UnixNano()),
+		Member: username,
+	}).Err()
+}
+
+// LeaveWaitlist 退出等候队列
+func (p *Project) LeaveWaitlist(ctx context.Context, username string) error {
+	return db.Redis.ZRem(ctx, p.WaitlistKey(), username).Err()
+}
+
+// WaitlistPosition 返回用户在等候队列中的位置(从 1 开始),不在队列中返回 0
+func (p *Project) WaitlistPosition(ctx context.Context, username string) (int64, error) {
+	rank, err := db.Redis.ZRank(ctx, p.WaitlistKey(), username).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return rank + 1, nil
+}
+
+// ReservationExpireAt 返回用户预留的过期时间,无预留返回 nil
+func (p *Project) ReservationExpireAt(ctx context.Context, username string) (*time.Time, error) {
+	score, err := db.Redis.ZScore(ctx, waitlistReservationExpireKey, waitlistReservationMember(p.ID, username)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	expireAt := time.Unix(int64(score), 0)
+	return &expireAt, nil
+}
+
+// HasReservation 用户是否持有预留的 item
+func (p *Project) HasReservation(ctx context.Context, username string) (bool, error) {
+	if !p.SupportsWaitlist() {
+		return false, nil
+	}
+	return db.Redis.HExists(ctx, p.ReservationsKey(), username).Result()
+}
+
+// takeReservation 取出用户的预留 item;HDel 成功者才视为取得,避免与过期任务并发重复归还
+func (p *Project) takeReservation(ctx context.Context, username string) (uint64, bool, error) {
+	val, err := db.Redis.HGet(ctx, p.ReservationsKey(), username).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	deleted, err := db.Redis.HDel(ctx, p.ReservationsKey(), username).Result()
+	if err != nil || deleted == 0 {
+		return 0, false, err
+	}
+	if err := db.Redis.ZRem(ctx, waitlistReservationExpireKey, waitlistReservationMember(p.ID, username)).Err(); err != nil {
+		return 0, false, err
+	}
+	itemID, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return itemID, true, nil
+}
+
+// reserveForWaitlistScript 为队首的等候用户原子地取出一个 item 并写入预留与过期索引。
+// 已持有预留的用户直接出队;无库存时用户留在队列中。返回 {username, itemID},无可预留时返回 nil。
+var reserveForWaitlistScript = redis.NewScript(`
+while true do
+	local waiting = redis.call('ZRANGE', KEYS[1], 0, 0)
+	if #waiting == 0 then
+		return false
+	end
+	local username = waiting[1]
+	if redis.call('HEXISTS', KEYS[3], username) == 1 then
+		redis.call('ZREM', KEYS[1], username)
+	else
+		local itemID = redis.call('LPOP', KEYS[2])
+		if not itemID then
+			return false
+		end
+		redis.call('ZREM', KEYS[1], username)
+		redis.call('HSET', KEYS[3], username, itemID)
+		redis.call('ZADD', KEYS[4], ARGV[1], ARGV[2] .. username)
+		return {username, itemID}
+	end
+end`)
+
+// RefillFromWaitlist 库存补充后按先进先出为等候用户预留 item,直到库存或队列耗尽,
+// 预留后无剩余库存时标记项目已完成。需在归还库存的事务提交后调用。
+// 预留在 waitlistReservationTTL 后过期,由 HandleExpireWaitlistReservations 归还库存。
+func (p *Project) RefillFromWaitlist(ctx context.Context) error {
+	if !p.SupportsWaitlist() {
+		return nil
+	}
+	reserved := false
+	for {
+		expireAt := time.Now().Add(waitlistReservationTTL())
+		result, err := reserveForWaitlistScript.Run(ctx, db.Redis,
+			[]string{p.WaitlistKey(), p.ItemsKey(), p.ReservationsKey(), waitlistReservationExpireKey},
+			expireAt.Unix(), waitlistReservationMember(p.ID, "")).StringSlice()
+		if errors.Is(err, redis.Nil) {
+			break
+		} else if err != nil {
+			return err
+		}
+		reserved = true
+		logger.InfoF(ctx, "项目[%s]为等候用户[%s]预留 item %s", p.ID, result[0], result[1])
+	}
+	if !reserved {
+		return nil
+	}
+	return p.markCompletedIfNoStock(ctx)
+}
+
+// markCompletedIfNoStock 库存全部被预留后标记项目已完成,预留过期归还时由 ResetCompletedStatusIfHasStock 恢复
+func (p *Project) markCompletedIfNoStock(ctx context.Context) error {
+	hasStock, err := p.HasStock(ctx)
+	if err != nil || hasStock {
+		return err
+	}
+	result := db.DB(ctx).Model(&Project{}).
+		Where("id = ? AND is_completed = ?", p.ID, false).
+		Update("is_completed", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		p.IsCompleted = true
+		invalidateExploreCompletion(ctx, p)
+	}
+	return nil
+}
+
+// expireReservation 归还过期预留的 item 并继续为下一位等候用户预留
+func expireReservation(ctx context.Context, member string) error {
+	projectID, username, ok := strings.Cut(member, ":")
+	if !ok {
+		return db.Redis.ZRem(ctx, waitlistReservationExpireKey, member).Err()
+	}
+
+	var p Project
+	if err := db.DB(ctx).Where("id = ?", projectID).First(&p).Error; err != nil {
+		return err
+	}
+
+	itemID, taken, err := p.takeReservation(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !taken {
+		// 已被领取或已处理
+		return db.Redis.ZRem(ctx, waitlistReservationExpireKey, member).Err()
+	}
+
+	if err := db.Redis.RPush(ctx, p.ItemsKey(), itemID).Err(); err != nil {
+		return err
+	}
+	if err := p.ResetCompletedStatusIfHasStock(ctx, db.DB(ctx)); err != nil {
+		return err
+	}
+	logger.InfoF(ctx, "项目[%s]等候用户[%s]的预留已过期，归还 item %d", p.ID, username, itemID)
+	return p.RefillFromWaitlist(ctx)
+}
+
+// ClearWaitlist 删除项目的等候队列与预留
+func (p *Project) ClearWaitlist(ctx context.Context) error {
+	usernames, err := db.Redis.HKeys(ctx, p.ReservationsKey()).Result()
+	if err != nil {
+		return err
+	}
+	if len(usernames) > 0 {
+		members := make([]interface{}, len(usernames))
+		for i, username := range usernames {
+			members[i] = waitlistReservationMember(p.ID, username)
+		}
+		if err := db.Redis.ZRem(ctx, waitlistReservationExpireKey, members...).Err(); err != nil {
+			return err
+		}
+	}
+	return db.Redis.Del(ctx, p.WaitlistKey(), p.ReservationsKey()).Err()
+}
@@ -0,0 +1,172 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package project
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/linux-do/cdk/internal/db/dbtest"
+)
+
+// setupWaitlist 准备一码一用项目,items 为库存中的 item,waiting 按顺序加入等候队列
+func setupWaitlist(t *testing.T, items int, waiting ...string) (*miniredis.Miniredis, *Project) {
+	t.Helper()
+	mr := dbtest.Setup(t, &Project{}, &ProjectItem{})
+	ctx := context.Background()
+	p := &Project{ID: "waitlist-project", Name: "waitlist", DistributionType: DistributionTypeOneForEach, EndTime: time.Now().Add(time.Hour)}
+	if err := db.DB(ctx).Create(p).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := range items {
+		if err := db.Redis.RPush(ctx, p.ItemsKey(), 100+i).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, username := range waiting {
+		if err := p.JoinWaitlist(ctx, username); err != nil {
+			t.Fatal(err)
+		}
+		// 保证加入时间不同
+		time.Sleep(time.Millisecond)
+	}
+	return mr, p
+}
+
+func TestWaitlistPosition(t *testing.T) {
+	_, p := setupWaitlist(t, 0, "alice", "bob")
+	ctx := context.Background()
+	if err := p.JoinWaitlist(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for username, want := range map[string]int64{"alice": 1, "bob": 2, "carol": 0} {
+		if got, err := p.WaitlistPosition(ctx, username); err != nil || got != want {
+			t.Errorf("position of %s = %d (%v), want %d", username, got, err, want)
+		}
+	}
+	if err := p.LeaveWaitlist(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := p.WaitlistPosition(ctx, "bob"); got != 1 {
+		t.Errorf("bob should move up, got %d", got)
+	}
+}
+
+func TestRefillFromWaitlist(t *testing.T) {
+	cases := []struct {
+		name      string
+		items     int
+		waiting   []string
+		held      string // 已持有预留的用户
+		reserved  map[string]string
+		queue     []string
+		stock     int64
+		completed bool
+	}{
+		{"reserves in join order", 2, []string{"alice", "bob", "carol"}, "", map[string]string{"alice": "100", "bob": "101"}, []string{"carol"}, 0, true},
+		{"leftover stock", 3, []string{"alice"}, "", map[string]string{"alice": "100"}, nil, 2, false},
+		{"no stock keeps queue", 0, []string{"alice"}, "", map[string]string{}, []string{"alice"}, 0, false},
+		{"holder dequeued without new item", 1, []string{"alice", "bob"}, "alice", map[string]string{"alice": "99", "bob": "100"}, nil, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mr, p := setupWaitlist(t, tc.items, tc.waiting...)
+			ctx := context.Background()
+			if tc.held != "" {
+				mr.HSet(p.ReservationsKey(), tc.held, "99")
+			}
+			if err := p.RefillFromWaitlist(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			reservations, err := db.Redis.HGetAll(ctx, p.ReservationsKey()).Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(reservations) != fmt.Sprint(tc.reserved) {
+				t.Errorf("reservations %v, want %v", reservations, tc.reserved)
+			}
+			queue, _ := db.Redis.ZRange(ctx, p.WaitlistKey(), 0, -1).Result()
+			if !slices.Equal(queue, tc.queue) && len(queue)+len(tc.queue) > 0 {
+				t.Errorf("queue %v, want %v", queue, tc.queue)
+			}
+			if stock, _ := p.Stock(ctx); stock != tc.stock {
+				t.Errorf("stock %d, want %d", stock, tc.stock)
+			}
+			for username := range tc.reserved {
+				if username == tc.held {
+					continue
+				}
+				if expireAt, err := p.ReservationExpireAt(ctx, username); err != nil || expireAt == nil {
+					t.Errorf("reservation of %s has no expiry: %v", username, err)
+				}
+			}
+			var stored Project
+			if err := db.DB(ctx).First(&stored, "id = ?", p.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.IsCompleted != tc.completed {
+				t.Errorf("completed = %v, want %v", stored.IsCompleted, tc.completed)
+			}
+		})
+	}
+}
+
+func TestExpireWaitlistReservations(t *testing.T) {
+	mr, p := setupWaitlist(t, 1, "alice", "bob")
+	ctx := context.Background()
+	if err := p.RefillFromWaitlist(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// alice 的预留过期后归还库存并顺延给 bob
+	mr.ZAdd(waitlistReservationExpireKey, float64(time.Now().Add(-time.Minute).Unix()), waitlistReservationMember(p.ID, "alice"))
+	if err := HandleExpireWaitlistReservations(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := p.HasReservation(ctx, "alice"); ok {
+		t.Error("expired reservation of alice kept")
+	}
+	if got := mr.HGet(p.ReservationsKey(), "bob"); got != "100" {
+		t.Errorf("bob reservation = %q, want 100", got)
+	}
+	if expireAt, _ := p.ReservationExpireAt(ctx, "alice"); expireAt != nil {
+		t.Error("expire index of alice not removed")
+	}
+
+	// 领取时取走预留,不再被过期任务归还
+	itemID, taken, err := p.takeReservation(ctx, "bob")
+	if err != nil || !taken || itemID != 100 {
+		t.Fatalf("take reservation: %d %v %v", itemID, taken, err)
+	}
+	if _, taken, _ := p.takeReservation(ctx, "bob"); taken {
+		t.Error("reservation taken twice")
+	}
+}
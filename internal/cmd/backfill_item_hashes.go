@@ -0,0 +1,47 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+
+	"github.com/spf13/cobra"
+)
+
+var backfillItemHashesCmd = &cobra.Command{
+	Use:   "backfill-item-hashes",
+	Short: "回填历史明文 item 的内容哈希,升级后执行一次",
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Println("[BackfillItemHashes] start backfilling item content hashes")
+		count, err := project.BackfillItemContentHashes(context.Background())
+		if err != nil {
+			log.Fatalf("[BackfillItemHashes] failed: %v", err)
+		}
+		log.Printf("[BackfillItemHashes] done, %d items backfilled\n", count)
+	},
+}
@@ -0,0 +1,47 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/linux-do/cdk/internal/apps/project"
+
+	"github.com/spf13/cobra"
+)
+
+var reencryptItemsCmd = &cobra.Command{
+	Use:   "reencrypt-items",
+	Short: "使用当前密钥重新加密旧版密钥加密的 item 并重建盲索引",
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Println("[ReencryptItems] start re-encrypting legacy items")
+		count, err := project.ReencryptLegacyItems(context.Background())
+		if err != nil {
+			log.Fatalf("[ReencryptItems] failed after %d items: %v", count, err)
+		}
+		log.Printf("[ReencryptItems] done, %d items re-encrypted\n", count)
+	},
+}
@@ -25,6 +25,9 @@
 package cmd
 
 import (
+	"github.com/linux-do/cdk/internal/apps/payment"
+	"github.com/linux-do/cdk/internal/apps/project"
+	"github.com/linux-do/cdk/internal/config"
 	"github.com/linux-do/cdk/internal/db/migrator"
 	"github.com/spf13/cobra"
 	"log"
@@ -33,6 +36,7 @@ import (
 var rootCmd = &cobra.Command{
 	Use: "linux-do-cdk",
 	PreRun: func(cmd *cobra.Command, args []string) {
+		validateEncryptionKeys()
 		migrator.Migrate()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -47,12 +51,37 @@ var rootCmd = &cobra.Command{
 			schedulerCmd.Run(schedulerCmd, args)
 		case "worker":
 			workerCmd.Run(workerCmd, args)
+		case "backfill-item-hashes":
+			backfillItemHashesCmd.Run(backfillItemHashesCmd, args)
+		case "backfill-order-amounts":
+			backfillOrderAmountsCmd.Run(backfillOrderAmountsCmd, args)
+		case "encrypt-items":
+			encryptItemsCmd.Run(encryptItemsCmd, args)
+		case "reencrypt-items":
+			reencryptItemsCmd.Run(reencryptItemsCmd, args)
+		case "reencrypt-payment-secrets":
+			reencryptPaymentSecretsCmd.Run(reencryptPaymentSecretsCmd, args)
 		default:
 			log.Fatal("[CMD] unknown app mode\n")
 		}
 	},
 }
 
+// validateEncryptionKeys 校验加密密钥强度,生产环境拒绝启动,其他环境仅告警
+func validateEncryptionKeys() {
+	for name, validate := range map[string]func() error{
+		"payment": payment.ValidateEncryptionKeys,
+		"item":    project.ValidateItemEncryptionKey,
+	} {
+		if err := validate(); err != nil {
+			if config.Config.App.Env == "production" {
+				log.Fatalf("[CMD] invalid %s encryption key: %v\n", name, err)
+			}
+			log.Printf("[CMD] weak %s encryption key: %v\n", name, err)
+		}
+	}
+}
+
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 }
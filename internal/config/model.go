@@ -64,6 +64,10 @@ type projectAppConfig struct {
 		IntervalSeconds int `mapstructure:"interval_seconds"`
 		MaxCount        int `mapstructure:"max_count"`
 	} `mapstructure:"create_project_rate_limit"`
+	WaitlistReservationMinutes int    `mapstructure:"waitlist_reservation_minutes"`
+	ItemEncryptionKey          string `mapstructure:"item_encryption_key"`
+	// ExploreCacheSeconds 广场候选项目缓存时长(秒),默认 60
+	ExploreCacheSeconds int `mapstructure:"explore_cache_seconds"`
 }
 
 // OAuth2Config OAuth2认证配置
@@ -139,6 +143,12 @@ type scheduleConfig struct {
 	UpdateUserBadgeScoresTaskCron         string `mapstructure:"update_user_badges_scores_task_cron"`
 	UpdateAllBadgesTaskCron               string `mapstructure:"update_all_badges_task_cron"`
 	ExpireStalePaymentOrdersCron          string `mapstructure:"expire_stale_payment_orders_cron"`
+	PaymentReconcileReportCron            string `mapstructure:"payment_reconcile_report_cron"`
+	RetryPaymentRefundsCron               string `mapstructure:"retry_payment_refunds_cron"`
+	ExpireWaitlistReservationsCron        string `mapstructure:"expire_waitlist_reservations_cron"`
+	ReleaseDueItemsCron                   string `mapstructure:"release_due_items_cron"`
+	NotifyFavoritesStartedCron            string `mapstructure:"notify_favorites_started_cron"`
+	DeliverNotificationPMCron             string `mapstructure:"deliver_notification_pm_cron"`
 }
 
 // workerConfig 工作配置
@@ -149,6 +159,8 @@ type workerConfig struct {
 
 // linuxDoConfig
 type linuxDoConfig struct {
+	// BaseURL Discourse 站点地址(不含路径),为空时使用 https://linux.do
+	BaseURL     string `mapstructure:"base_url"`
 	ApiKey      string `mapstructure:"api_key"`
 	ApiUsername string `mapstructure:"api_username"`
 }
@@ -181,9 +193,24 @@ type PaymentConfig struct {
 	// RedirectBaseURL 本项目对外可访问的基址(不含路径),例如 https://cdk.linux.do
 	// 用于拼接 redirect_url 并提示用户在 LDC 商户后台填写
 	RedirectBaseURL string `mapstructure:"redirect_base_url"`
-	// ConfigEncryptionKey 用于加密用户 clientSecret 的密钥,必须是 32 字节长度
-	// 建议直接填 32 字符 ASCII 字符串或 base64 解码得 32 字节
+	// ConfigEncryptionKey 用于加密用户 clientSecret 的密钥,至少 32 字节,经 HKDF-SHA256 派生
+	// 建议填写至少 32 字符的随机字符串或 base64 编码的 32 字节随机数;生产环境过短会拒绝启动
 	ConfigEncryptionKey string `mapstructure:"config_encryption_key"`
+	// ConfigEncryptionKeys 按密钥 ID 配置的多把密钥,用于轮换;ID 需为小写且不含冒号。
+	// config_encryption_key 未在此声明时以 ID "default" 参与解密
+	ConfigEncryptionKeys map[string]string `mapstructure:"config_encryption_keys"`
+	// ActiveEncryptionKeyID 加密新凭据使用的密钥 ID,留空时使用 "default"
+	ActiveEncryptionKeyID string `mapstructure:"active_encryption_key_id"`
 	// OrderExpireMinutes 订单 PENDING 状态的最长保留时间(分钟),默认 10
 	OrderExpireMinutes int `mapstructure:"order_expire_minutes"`
+	// RefundMaxAttempts REFUNDING 订单自动重试退款的最大次数,超过后升级给管理员,默认 8
+	RefundMaxAttempts int `mapstructure:"refund_max_attempts"`
+	// RefundRetryBaseSeconds 退款重试的首次退避间隔(秒),之后按 2 的幂递增,默认 60
+	RefundRetryBaseSeconds int `mapstructure:"refund_retry_base_seconds"`
+	// DisputeWindowDays 付款后买家可发起退款争议的天数,默认 7
+	DisputeWindowDays int `mapstructure:"dispute_window_days"`
+	// CouponQuoteRateLimit 每个用户每分钟携带优惠码预览价格的次数上限,默认 10
+	CouponQuoteRateLimit int `mapstructure:"coupon_quote_rate_limit"`
+	// EnableFakeProvider 注册不访问网络的 fake 模拟渠道,仅供本地联调,生产环境忽略该配置
+	EnableFakeProvider bool `mapstructure:"enable_fake_provider"`
 }
@@ -0,0 +1,103 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package dbtest 为单元测试提供基于 sqlite 与 miniredis 的内存依赖,
+// 仅应被 _test.go 文件引用,避免生产二进制链接 sqlite。
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/linux-do/cdk/internal/db"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dialector 在 sqlite 上跳过 MySQL 专有的 FULLTEXT 索引,连接使用注册了 MySQL 兼容函数的驱动
+type dialector struct {
+	*sqlite.Dialector
+}
+
+func (d dialector) Migrator(tx *gorm.DB) gorm.Migrator {
+	return migrator{d.Dialector.Migrator(tx).(sqlite.Migrator)}
+}
+
+type migrator struct {
+	sqlite.Migrator
+}
+
+func (m migrator) CreateIndex(value interface{}, name string) error {
+	skip := false
+	if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema != nil {
+			if idx := stmt.Schema.LookIndex(name); idx != nil && idx.Class == "FULLTEXT" {
+				skip = true
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if skip {
+		return nil
+	}
+	return m.Migrator.CreateIndex(value, name)
+}
+
+// Setup 以临时 sqlite 文件与 miniredis 替换全局 db.DB 与 db.Redis 并迁移给定模型,测试结束后自动恢复
+func Setup(t testing.TB, models ...interface{}) *miniredis.Miniredis {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_journal_mode=WAL&_busy_timeout=5000"
+	gormDB, err := gorm.Open(
+		dialector{&sqlite.Dialector{DriverName: driverName, DSN: dsn}},
+		&gorm.Config{
+			DisableForeignKeyConstraintWhenMigrating: true,
+			Logger:                                   logger.Discard,
+		},
+	)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := gormDB.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	restore := db.SetForTest(gormDB, redisClient)
+	t.Cleanup(func() {
+		restore()
+		_ = redisClient.Close()
+		if sqlDB, err := gormDB.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return mr
+}
@@ -0,0 +1,73 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package dbtest
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName 注册了 MySQL 兼容函数的 sqlite 驱动
+const driverName = "sqlite3_mysql_compat"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("if", mysqlIf, true); err != nil {
+				return err
+			}
+			return conn.RegisterAggregator("json_arrayagg", func() *jsonArrayAgg { return &jsonArrayAgg{} }, true)
+		},
+	})
+}
+
+// mysqlIf 对应 MySQL 的 IF(cond, a, b)
+func mysqlIf(cond bool, a, b any) any {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// jsonArrayAgg 对应 MySQL 的 JSON_ARRAYAGG,以 BLOB 返回便于扫描到 JSON 类型字段
+type jsonArrayAgg struct {
+	values []any
+}
+
+func (a *jsonArrayAgg) Step(v any) {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	a.values = append(a.values, v)
+}
+
+func (a *jsonArrayAgg) Done() ([]byte, error) {
+	if a.values == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(a.values)
+}
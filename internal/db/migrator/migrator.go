@@ -31,6 +31,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/linux-do/cdk/internal/apps/notification"
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/apps/payment"
 	"github.com/linux-do/cdk/internal/apps/project"
@@ -42,14 +43,38 @@ func Migrate() {
 		return
 	}
 
+	// 领取记录索引由唯一索引改为普通索引以支持每用户领取多个
+	if m := db.DB(context.Background()).Migrator(); m.HasIndex(&project.ProjectItem{}, "idx_project_receiver") {
+		if err := m.DropIndex(&project.ProjectItem{}, "idx_project_receiver"); err != nil {
+			log.Fatalf("[MySQL] drop index idx_project_receiver failed: %v\n", err)
+		}
+	}
+
+	// 收款方单列索引已被 idx_payee_status_created 覆盖
+	if m := db.DB(context.Background()).Migrator(); m.HasIndex(&payment.PaymentOrder{}, "idx_payment_orders_payee_id") {
+		if err := m.DropIndex(&payment.PaymentOrder{}, "idx_payment_orders_payee_id"); err != nil {
+			log.Fatalf("[MySQL] drop index idx_payment_orders_payee_id failed: %v\n", err)
+		}
+	}
+
 	if err := db.DB(context.Background()).AutoMigrate(
 		&oauth.User{},
 		&project.Project{},
 		&project.ProjectItem{},
 		&project.ProjectTag{},
 		&project.ProjectReport{},
+		&project.ProjectInvitee{},
+		&project.ProjectLotteryEntry{},
+		&project.ProjectRelease{},
+		&project.ProjectItemRevocation{},
+		&project.ProjectFavorite{},
+		&project.CreatorFollow{},
 		&payment.UserPaymentConfig{},
 		&payment.PaymentOrder{},
+		&payment.PaymentReconcileMismatch{},
+		&payment.PaymentDispute{},
+		&payment.PaymentPriceRule{},
+		&notification.Notification{},
 	); err != nil {
 		log.Fatalf("[MySQL] auto migrate failed: %v\n", err)
 	}
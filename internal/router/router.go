@@ -37,6 +37,7 @@ import (
 	"github.com/linux-do/cdk/internal/apps/admin"
 	"github.com/linux-do/cdk/internal/apps/dashboard"
 	"github.com/linux-do/cdk/internal/apps/health"
+	"github.com/linux-do/cdk/internal/apps/notification"
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/apps/payment"
 	"github.com/linux-do/cdk/internal/apps/project"
@@ -111,13 +112,31 @@ func Serve() {
 			projectRouter.Use(oauth.LoginRequired())
 			{
 				projectRouter.GET("/mine", project.ListMyProjects)
+				projectRouter.GET("/favorites", project.ListFavoriteProjects)
+				projectRouter.GET("/following", project.ListFollowingProjects)
 				projectRouter.GET("", project.ListProjects)
+				projectRouter.GET("/search", project.SearchProjects)
 				projectRouter.POST("", project.ProjectCreateRateLimitMiddleware(), project.CreateProject)
 				projectRouter.PUT("/:id", project.ProjectCreatorPermMiddleware(), project.UpdateProject)
 				projectRouter.DELETE("/:id", project.ProjectCreatorPermMiddleware(), project.DeleteProject)
 				projectRouter.GET("/:id/receivers", project.ProjectCreatorPermMiddleware(), project.ListProjectReceivers)
+				projectRouter.POST("/:id/items/import", project.ProjectCreatorPermMiddleware(), project.ImportProjectItems)
+				projectRouter.GET("/:id/items/export", project.ProjectCreatorPermMiddleware(), payment.ExportProjectItems)
+				projectRouter.GET("/:id/items/revocations", project.ProjectCreatorPermMiddleware(), project.ListItemRevocations)
+				projectRouter.POST("/:id/items/:item_id/revoke", project.ProjectCreatorPermMiddleware(), payment.RevokeItem)
 				projectRouter.POST("/:id/receive", project.ReceiveProjectMiddleware(), payment.DispatchReceive)
+				projectRouter.GET("/:id/price", payment.QuotePriceHTTP)
+				projectRouter.GET("/:id/price-rules", project.ProjectCreatorPermMiddleware(), payment.ListPriceRulesHTTP)
+				projectRouter.POST("/:id/price-rules", project.ProjectCreatorPermMiddleware(), payment.CreatePriceRuleHTTP)
+				projectRouter.DELETE("/:id/price-rules/:rule_id", project.ProjectCreatorPermMiddleware(), payment.DeletePriceRuleHTTP)
 				projectRouter.POST("/:id/report", project.ReportProject)
+				projectRouter.GET("/:id/lottery", project.GetLottery)
+				projectRouter.POST("/:id/lottery/entries", project.RegisterLottery)
+				projectRouter.GET("/:id/waitlist", project.GetWaitlist)
+				projectRouter.POST("/:id/waitlist", project.JoinWaitlist)
+				projectRouter.DELETE("/:id/waitlist", project.LeaveWaitlist)
+				projectRouter.POST("/:id/favorite", project.FavoriteProject)
+				projectRouter.DELETE("/:id/favorite", project.UnfavoriteProject)
 				projectRouter.GET("/received/chart", project.ListReceiveHistoryChart)
 				projectRouter.GET("/received", project.ListReceiveHistory)
 				projectRouter.GET("/:id", project.GetProject)
@@ -130,6 +149,8 @@ func Serve() {
 				userRouter.GET("/payment-config", payment.GetPaymentConfig)
 				userRouter.PUT("/payment-config", payment.UpsertPaymentConfig)
 				userRouter.DELETE("/payment-config", payment.DeletePaymentConfig)
+				userRouter.POST("/:id/follow", project.FollowCreator)
+				userRouter.DELETE("/:id/follow", project.UnfollowCreator)
 			}
 
 			// Payment 回调(易支付 GET 请求,无 session)
@@ -138,6 +159,35 @@ func Serve() {
 				paymentRouter.GET("/notify", payment.HandleNotifyHTTP)
 			}
 
+			// Payment 争议
+			disputeRouter := apiV1Router.Group("/payment")
+			disputeRouter.Use(oauth.LoginRequired())
+			{
+				disputeRouter.POST("/orders/:out_trade_no/dispute", payment.OpenDisputeHTTP)
+				disputeRouter.GET("/disputes", payment.ListDisputesHTTP)
+				disputeRouter.POST("/disputes/:id/accept", payment.AcceptDisputeHTTP)
+				disputeRouter.POST("/disputes/:id/reject", payment.RejectDisputeHTTP)
+				disputeRouter.POST("/disputes/:id/appeal", payment.AppealDisputeHTTP)
+			}
+
+			// Payment 订单与销售
+			orderRouter := apiV1Router.Group("/payment")
+			orderRouter.Use(oauth.LoginRequired())
+			{
+				orderRouter.GET("/orders", payment.ListOrdersHTTP)
+				orderRouter.GET("/orders/:out_trade_no", payment.GetOrderHTTP)
+				orderRouter.GET("/sales", payment.ListSalesHTTP)
+			}
+
+			// Notification
+			notificationRouter := apiV1Router.Group("/notifications")
+			notificationRouter.Use(oauth.LoginRequired())
+			{
+				notificationRouter.GET("", notification.ListNotificationsHTTP)
+				notificationRouter.GET("/unread-count", notification.GetUnreadCountHTTP)
+				notificationRouter.POST("/read", notification.MarkReadHTTP)
+			}
+
 			// Tag
 			tagRouter := apiV1Router.Group("/tags")
 			tagRouter.Use(oauth.LoginRequired())
@@ -168,6 +218,21 @@ func Serve() {
 				{
 					userAdminRouter.GET("", admin.ListUsers)
 				}
+
+				// Explore
+				exploreAdminRouter := adminRouter.Group("/explore")
+				{
+					exploreAdminRouter.GET("/cache-stats", admin.GetExploreCacheStats)
+				}
+
+				// Payment
+				paymentAdminRouter := adminRouter.Group("/payment")
+				{
+					paymentAdminRouter.GET("/refunds/escalated", payment.ListEscalatedRefundsHTTP)
+					paymentAdminRouter.POST("/orders/:out_trade_no/refund/retry", payment.RetryRefundHTTP)
+					paymentAdminRouter.GET("/disputes", payment.AdminListDisputesHTTP)
+					paymentAdminRouter.POST("/disputes/:id/arbitrate", payment.ArbitrateDisputeHTTP)
+				}
 			}
 		}
 	}
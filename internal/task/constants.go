@@ -30,4 +30,13 @@ const (
 	UpdateSingleUserBadgeScoreTask = "user:badge:update_single_score_task"
 
 	ExpireStalePaymentOrdersTask = "payment:expire_stale_orders"
+	PaymentReconcileReportTask   = "payment:reconcile_report"
+	RetryPaymentRefundsTask      = "payment:retry_refunds"
+
+	DrawLotteryTask                = "project:lottery:draw"
+	ExpireWaitlistReservationsTask = "project:waitlist:expire_reservations"
+	ReleaseDueItemsTask            = "project:release:release_due_items"
+	NotifyFavoritesStartedTask     = "project:favorite:notify_started"
+
+	DeliverNotificationPMTask = "notification:deliver_pm"
 )
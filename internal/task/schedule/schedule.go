@@ -74,6 +74,36 @@ func StartScheduler() error {
 			return
 		}
 
+		// 每分钟重试一次到期的退款
+		if _, err = scheduler.Register(config.Config.Schedule.RetryPaymentRefundsCron, asynq.NewTask(task.RetryPaymentRefundsTask, nil)); err != nil {
+			return
+		}
+
+		// 每日生成一次支付对账报告
+		if _, err = scheduler.Register(config.Config.Schedule.PaymentReconcileReportCron, asynq.NewTask(task.PaymentReconcileReportTask, nil)); err != nil {
+			return
+		}
+
+		// 每分钟归还一次超时未领取的等候预留
+		if _, err = scheduler.Register(config.Config.Schedule.ExpireWaitlistReservationsCron, asynq.NewTask(task.ExpireWaitlistReservationsTask, nil)); err != nil {
+			return
+		}
+
+		// 每分钟发放一次到期的分批库存
+		if _, err = scheduler.Register(config.Config.Schedule.ReleaseDueItemsCron, asynq.NewTask(task.ReleaseDueItemsTask, nil)); err != nil {
+			return
+		}
+
+		// 每分钟通知一次收藏的项目已开始
+		if _, err = scheduler.Register(config.Config.Schedule.NotifyFavoritesStartedCron, asynq.NewTask(task.NotifyFavoritesStartedTask, nil)); err != nil {
+			return
+		}
+
+		// 每分钟投递一次待发送的站内信
+		if _, err = scheduler.Register(config.Config.Schedule.DeliverNotificationPMCron, asynq.NewTask(task.DeliverNotificationPMTask, nil)); err != nil {
+			return
+		}
+
 		// 启动调度器
 		err = scheduler.Run()
 	})
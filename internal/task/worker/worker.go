@@ -27,8 +27,10 @@ package worker
 import (
 	"context"
 	"github.com/hibiken/asynq"
+	"github.com/linux-do/cdk/internal/apps/notification"
 	"github.com/linux-do/cdk/internal/apps/oauth"
 	"github.com/linux-do/cdk/internal/apps/payment"
+	"github.com/linux-do/cdk/internal/apps/project"
 	"github.com/linux-do/cdk/internal/config"
 	"github.com/linux-do/cdk/internal/db"
 	"github.com/linux-do/cdk/internal/task"
@@ -71,6 +73,13 @@ func StartWorker() error {
 	mux.HandleFunc(task.UpdateUserBadgeScoresTask, oauth.HandleUpdateUserBadgeScores)
 	mux.HandleFunc(task.UpdateSingleUserBadgeScoreTask, oauth.HandleUpdateSingleUserBadgeScore)
 	mux.HandleFunc(task.ExpireStalePaymentOrdersTask, payment.HandleExpireStaleOrders)
+	mux.HandleFunc(task.PaymentReconcileReportTask, payment.HandleDailyReconcileReport)
+	mux.HandleFunc(task.RetryPaymentRefundsTask, payment.HandleRetryRefunds)
+	mux.HandleFunc(task.DrawLotteryTask, project.HandleDrawLottery)
+	mux.HandleFunc(task.ExpireWaitlistReservationsTask, project.HandleExpireWaitlistReservations)
+	mux.HandleFunc(task.ReleaseDueItemsTask, project.HandleReleaseDueItems)
+	mux.HandleFunc(task.NotifyFavoritesStartedTask, project.HandleNotifyFavoritesStarted)
+	mux.HandleFunc(task.DeliverNotificationPMTask, notification.HandleDeliverPM)
 	// 启动服务器
 	return asynqServer.Run(mux)
 }
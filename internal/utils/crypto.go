@@ -0,0 +1,145 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MinEncryptionKeyLength 加密密钥材料的最小字节数
+const MinEncryptionKeyLength = 32
+
+var (
+	ErrEmptyEncryptionKey = errors.New("encryption key is empty")
+	ErrWeakEncryptionKey  = fmt.Errorf("encryption key must be at least %d bytes", MinEncryptionKeyLength)
+)
+
+// EncryptionKeyMaterial 解析配置的 key:可 base64 解码出至少 32 字节时取解码结果,否则取原字节。
+func EncryptionKeyMaterial(raw string) ([]byte, error) {
+	if raw == "" {
+		return nil, ErrEmptyEncryptionKey
+	}
+	if b, err := base64.StdEncoding.DecodeString(raw); err == nil && len(b) >= MinEncryptionKeyLength {
+		return b, nil
+	}
+	return []byte(raw), nil
+}
+
+// ValidateEncryptionKey 校验配置的 key 是否满足最小长度
+func ValidateEncryptionKey(raw string) error {
+	material, err := EncryptionKeyMaterial(raw)
+	if err != nil {
+		return err
+	}
+	if len(material) < MinEncryptionKeyLength {
+		return ErrWeakEncryptionKey
+	}
+	return nil
+}
+
+// DeriveHKDFKey 使用 HKDF-SHA256 从配置的 key 派生 32 字节 AES-256 密钥,info 区分用途。
+func DeriveHKDFKey(raw string, salt []byte, info string) ([]byte, error) {
+	material, err := EncryptionKeyMaterial(raw)
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Key(sha256.New, material, salt, info, 32)
+}
+
+// DeriveLegacyAESKey 旧版密钥派生,仅用于解密历史密文。
+// 优先尝试 base64 解码,失败则取原字节;不足 32 字节以 MD5 填充至稳定 32 字节。
+func DeriveLegacyAESKey(raw string) ([]byte, error) {
+	if raw == "" {
+		return nil, ErrEmptyEncryptionKey
+	}
+	if b, err := base64.StdEncoding.DecodeString(raw); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	if len(raw) == 32 {
+		return []byte(raw), nil
+	}
+	sum := md5.Sum([]byte(raw))
+	key := make([]byte, 32)
+	copy(key[:16], sum[:])
+	sum2 := md5.Sum(sum[:])
+	copy(key[16:], sum2[:])
+	return key, nil
+}
+
+// EncryptAESGCM 使用 AES-256-GCM 加密明文,输出 base64(nonce|ciphertext|tag)。
+func EncryptAESGCM(key []byte, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	ct := gcm.Seal(nil, nonce, []byte(plaintext), nil)
+	out := make([]byte, 0, len(nonce)+len(ct))
+	out = append(out, nonce...)
+	out = append(out, ct...)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// DecryptAESGCM 解密 EncryptAESGCM 的输出。
+func DecryptAESGCM(key []byte, encoded string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decode secret: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	ns := gcm.NonceSize()
+	if len(raw) < ns+gcm.Overhead() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, ct := raw[:ns], raw[ns:]
+	pt, err := gcm.Open(nil, nonce, ct, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(pt), nil
+}
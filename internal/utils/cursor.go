@@ -0,0 +1,120 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrPageRequired  = errors.New("current is required when cursor is not provided")
+)
+
+// UncountedTotal 游标模式下未请求计数时返回的 total
+const UncountedTotal int64 = -1
+
+// CursorPagination 游标分页参数,嵌入列表请求与 current/size 并存。
+// 携带 cursor 参数(首页传空值)即进入游标模式:按排序列做键集定位,不再执行 OFFSET,
+// 响应中的 next_cursor 为空表示没有更多数据;游标模式下仅当 with_count=true 时统计总数,
+// 否则 total 为 UncountedTotal。
+type CursorPagination struct {
+	Cursor    *string `json:"cursor" form:"cursor" binding:"omitempty,max=512"`
+	WithCount bool    `json:"with_count" form:"with_count"`
+}
+
+// CursorMode 是否为游标模式
+func (p *CursorPagination) CursorMode() bool {
+	return p.Cursor != nil
+}
+
+// NeedCount 是否需要统计总数,OFFSET 模式始终统计以保持兼容
+func (p *CursorPagination) NeedCount() bool {
+	return !p.CursorMode() || p.WithCount
+}
+
+// ValidatePage OFFSET 模式下 current 必填
+func (p *CursorPagination) ValidatePage(current int) error {
+	if !p.CursorMode() && current < 1 {
+		return ErrPageRequired
+	}
+	return nil
+}
+
+// DecodeCursor 把游标依次解码到 dest,返回 false 表示首页(游标为空)
+func (p *CursorPagination) DecodeCursor(dest ...interface{}) (bool, error) {
+	if p.Cursor == nil || *p.Cursor == "" {
+		return false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(*p.Cursor)
+	if err != nil {
+		return false, ErrInvalidCursor
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return false, ErrInvalidCursor
+	}
+	if len(values) != len(dest) {
+		return false, ErrInvalidCursor
+	}
+	for i, v := range values {
+		if err := json.Unmarshal(v, dest[i]); err != nil {
+			return false, ErrInvalidCursor
+		}
+	}
+	return true, nil
+}
+
+// EncodeCursor 把最后一行的排序列值编码为不透明游标
+func EncodeCursor(values ...interface{}) (string, error) {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Window 返回实际查询的 limit 与 offset,游标模式多取一行用于判断是否还有下一页
+func (p *CursorPagination) Window(offset, limit int) (int, int) {
+	if p.CursorMode() {
+		return limit + 1, 0
+	}
+	return limit, offset
+}
+
+// TrimCursorPage 游标模式下裁掉多取的一行,并以最后一行的排序列生成 next_cursor
+func TrimCursorPage[T any](p *CursorPagination, rows []T, limit int, key func(*T) []interface{}) ([]T, string, error) {
+	if !p.CursorMode() || len(rows) <= limit {
+		return rows, "", nil
+	}
+	rows = rows[:limit]
+	next, err := EncodeCursor(key(&rows[limit-1])...)
+	if err != nil {
+		return nil, "", err
+	}
+	return rows, next, nil
+}
@@ -0,0 +1,158 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 linux.do
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func cursorPage(cursor string) *CursorPagination {
+	return &CursorPagination{Cursor: &cursor}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 4, 5, 6, 7, 891011121, time.FixedZone("CST", 8*3600))
+	id := uint64(math.MaxUint64 - 1)
+	cursor, err := EncodeCursor(createdAt, id, "project-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotTime time.Time
+	var gotID uint64
+	var gotName string
+	ok, err := cursorPage(cursor).DecodeCursor(&gotTime, &gotID, &gotName)
+	if err != nil || !ok {
+		t.Fatalf("decode: ok=%v err=%v", ok, err)
+	}
+	if !gotTime.Equal(createdAt) || gotID != id || gotName != "project-1" {
+		t.Fatalf("got (%s, %d, %q), want (%s, %d, project-1)", gotTime, gotID, gotName, createdAt, id)
+	}
+}
+
+func TestDecodeCursorFirstPage(t *testing.T) {
+	var id uint64
+	for _, p := range []*CursorPagination{{}, cursorPage("")} {
+		ok, err := p.DecodeCursor(&id)
+		if err != nil || ok {
+			t.Fatalf("empty cursor should be the first page: ok=%v err=%v", ok, err)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsMalformed(t *testing.T) {
+	valid, err := EncodeCursor(time.Now(), uint64(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	cases := map[string]string{
+		"not base64":        "!!!",
+		"padded base64":     base64.URLEncoding.EncodeToString([]byte(`["2025-01-01T00:00:00Z",1]`)),
+		"truncated":         valid[:len(valid)-3],
+		"trailing garbage":  valid + "x",
+		"not json":          encode("not json"),
+		"json object":       encode(`{"id":1}`),
+		"too few values":    encode(`["2025-01-01T00:00:00Z"]`),
+		"too many values":   encode(`["2025-01-01T00:00:00Z",1,2]`),
+		"wrong time type":   encode(`[123,1]`),
+		"wrong id type":     encode(`["2025-01-01T00:00:00Z","1"]`),
+		"negative id":       encode(`["2025-01-01T00:00:00Z",-1]`),
+		"fractional id":     encode(`["2025-01-01T00:00:00Z",1.5]`),
+		"invalid timestamp": encode(`["yesterday",1]`),
+	}
+	for name, cursor := range cases {
+		var createdAt time.Time
+		var id uint64
+		ok, err := cursorPage(cursor).DecodeCursor(&createdAt, &id)
+		if !errors.Is(err, ErrInvalidCursor) || ok {
+			t.Fatalf("%s: got ok=%v err=%v, want ErrInvalidCursor", name, ok, err)
+		}
+	}
+}
+
+func TestTrimCursorPage(t *testing.T) {
+	rows := []uint64{9, 8, 7, 6}
+	key := func(v *uint64) []interface{} { return []interface{}{*v} }
+
+	// 游标模式多取一行,超过 limit 时裁剪并以最后一行生成下一页游标
+	p := cursorPage("")
+	if limit, offset := p.Window(20, 3); limit != 4 || offset != 0 {
+		t.Fatalf("cursor window = (%d, %d), want (4, 0)", limit, offset)
+	}
+	page, next, err := TrimCursorPage(p, rows, 3, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(page, []uint64{9, 8, 7}) || next == "" {
+		t.Fatalf("got page %v next %q", page, next)
+	}
+	var last uint64
+	if ok, err := cursorPage(next).DecodeCursor(&last); err != nil || !ok || last != 7 {
+		t.Fatalf("next cursor decodes to %d (ok=%v err=%v), want 7", last, ok, err)
+	}
+
+	// 最后一页不返回游标
+	if page, next, err = TrimCursorPage(p, rows[:3], 3, key); err != nil || len(page) != 3 || next != "" {
+		t.Fatalf("last page: %v %q %v", page, next, err)
+	}
+
+	// OFFSET 模式不裁剪
+	offsetPage := &CursorPagination{}
+	if limit, offset := offsetPage.Window(20, 3); limit != 3 || offset != 20 {
+		t.Fatalf("offset window = (%d, %d), want (3, 20)", limit, offset)
+	}
+	if page, next, err = TrimCursorPage(offsetPage, rows, 3, key); err != nil || len(page) != 4 || next != "" {
+		t.Fatalf("offset mode: %v %q %v", page, next, err)
+	}
+}
+
+func TestCursorPaginationModes(t *testing.T) {
+	offsetPage := &CursorPagination{}
+	if offsetPage.CursorMode() || !offsetPage.NeedCount() {
+		t.Fatal("offset mode should always count")
+	}
+	if err := offsetPage.ValidatePage(0); !errors.Is(err, ErrPageRequired) {
+		t.Fatalf("offset mode without current: %v", err)
+	}
+
+	p := cursorPage("")
+	if !p.CursorMode() || p.NeedCount() {
+		t.Fatal("cursor mode should skip counting by default")
+	}
+	if err := p.ValidatePage(0); err != nil {
+		t.Fatalf("cursor mode does not need current: %v", err)
+	}
+	p.WithCount = true
+	if !p.NeedCount() {
+		t.Fatal("with_count should enable counting in cursor mode")
+	}
+}